@@ -25,78 +25,37 @@ const (
 )
 
 var (
-	opAddParser = apc.Bind(apc.ExactStr(string(OpAdd)), OpAdd)
-	opSubParser = apc.Bind(apc.ExactStr(string(OpSub)), OpSub)
-	opMulParser = apc.Bind(apc.ExactStr(string(OpMul)), OpMul)
-	opDivParser = apc.Bind(apc.ExactStr(string(OpDiv)), OpDiv)
-	opExpParser = apc.Bind(apc.ExactStr(string(OpExp)), OpExp)
+	opAddParser = binOpParser(OpAdd)
+	opSubParser = binOpParser(OpSub)
+	opMulParser = binOpParser(OpMul)
+	opDivParser = binOpParser(OpDiv)
+	opExpParser = binOpParser(OpExp)
 
 	factorParser    apc.Parser[rune, Executable]
 	factorParserRef = apc.Ref(&factorParser)
 
 	exponentTermParser = apc.Named("exponential term",
-		apc.Map(
-			apc.Seq2(
-				factorParserRef,
-				apc.ZeroOrMore(
-					apc.Seq2(
-						opExpParser,
-						factorParserRef))),
-			func(node *apc.Seq2Node[Executable, []*apc.Seq2Node[Operator, Executable]]) Executable {
-				left := node.Result1
-				for _, seqRes := range node.Result2 {
-					left = BinOpNode{
-						Operator: seqRes.Result1,
-						Left:     left,
-						Right:    seqRes.Result2,
-					}
-				}
-				return left
-			}))
+		apc.ChainRight1(factorParserRef, opExpParser))
 
 	termParser = apc.Named("term",
-		apc.Map(
-			apc.Seq2(
-				exponentTermParser,
-				apc.ZeroOrMore(
-					apc.Seq2(
-						apc.Any(opMulParser, opDivParser),
-						exponentTermParser))),
-			func(node *apc.Seq2Node[Executable, []*apc.Seq2Node[Operator, Executable]]) Executable {
-				left := node.Result1
-				for _, seqRes := range node.Result2 {
-					left = BinOpNode{
-						Operator: seqRes.Result1,
-						Left:     left,
-						Right:    seqRes.Result2,
-					}
-				}
-				return left
-			}))
+		apc.ChainLeft1(exponentTermParser, apc.Any(opMulParser, opDivParser)))
 
 	exprParser = apc.Named("expression",
-		apc.Map(
-			apc.Seq2(
-				termParser,
-				apc.ZeroOrMore(
-					apc.Seq2(
-						apc.Any(opAddParser, opSubParser),
-						termParser))),
-			func(node *apc.Seq2Node[Executable, []*apc.Seq2Node[Operator, Executable]]) Executable {
-				left := node.Result1
-				for _, seqRes := range node.Result2 {
-					left = BinOpNode{
-						Operator: seqRes.Result1,
-						Left:     left,
-						Right:    seqRes.Result2,
-					}
-				}
-				return left
-			}))
+		apc.ChainLeft1(termParser, apc.Any(opAddParser, opSubParser)))
 
 	maybeExprParser = apc.Maybe(exprParser)
 )
 
+func binOpParser(op Operator) apc.Parser[rune, func(Executable, Executable) Executable] {
+	return apc.Bind(apc.ExactStr(string(op)), func(left Executable, right Executable) Executable {
+		return BinOpNode{
+			Operator: op,
+			Left:     left,
+			Right:    right,
+		}
+	})
+}
+
 func initParser() {
 	factorParser = apc.Named("factor",
 		apc.Any(
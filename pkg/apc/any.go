@@ -1,5 +1,7 @@
 package apc
 
+import "fmt"
+
 // Returns a parser that attempts to parse, in order, the provided parsers.
 // Returns the result of the first successful parser.
 func Any[CT, T any](parsers ...Parser[CT, T]) Parser[CT, T] {
@@ -7,13 +9,21 @@ func Any[CT, T any](parsers ...Parser[CT, T]) Parser[CT, T] {
 		panic("must provide at least 1 parser to Any")
 	}
 
+	branches := make([]string, len(parsers))
+	for i := range parsers {
+		branches[i] = fmt.Sprintf("alternative %v", i+1)
+	}
+	site := newCoverageSite("any", branches...)
+
 	return func(ctx Context[CT]) (T, error) {
 		ctx.DebugStart("any")
 		defer ctx.DebugEnd("any")
+		cov := enterCoverage(ctx, site)
 
-		for _, parser := range parsers {
+		for i, parser := range parsers {
 			node, err := parser(ctx)
 			if err == nil {
+				cov.hit(i)
 				return node, nil
 			}
 			if IsMustReturnParseErr(err) {
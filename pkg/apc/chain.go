@@ -0,0 +1,90 @@
+package apc
+
+// Returns a parser that parses one or more operand matches separated by operator
+// matches, folding the results left-associatively. For example, "1 - 2 - 3" is
+// folded as ((1 - 2) - 3).
+//
+// The operator parser returns the function used to combine its left and right operands.
+// If an operator is matched but is not followed by an operand, a ParseErrorConsumed
+// is returned.
+func ChainLeft1[CT, T any](operand Parser[CT, T], operator Parser[CT, func(T, T) T]) Parser[CT, T] {
+	return func(ctx Context[CT]) (T, error) {
+		ctx.DebugStart("chain left")
+		defer ctx.DebugEnd("chain left")
+
+		left, err := operand(ctx)
+		if err != nil {
+			return zeroVal[T](), err
+		}
+		for {
+			opFunc, right, done, err := chainParseOperatorAndOperand(ctx, operand, operator)
+			if err != nil {
+				return zeroVal[T](), err
+			}
+			if done {
+				return left, nil
+			}
+			left = opFunc(left, right)
+		}
+	}
+}
+
+// Returns a parser that parses one or more operand matches separated by operator
+// matches, folding the results right-associatively. For example, "2 ^ 3 ^ 2" is
+// folded as (2 ^ (3 ^ 2)).
+//
+// The operator parser returns the function used to combine its left and right operands.
+// If an operator is matched but is not followed by an operand, a ParseErrorConsumed
+// is returned.
+func ChainRight1[CT, T any](operand Parser[CT, T], operator Parser[CT, func(T, T) T]) Parser[CT, T] {
+	return func(ctx Context[CT]) (T, error) {
+		ctx.DebugStart("chain right")
+		defer ctx.DebugEnd("chain right")
+
+		first, err := operand(ctx)
+		if err != nil {
+			return zeroVal[T](), err
+		}
+		operands := []T{first}
+		opFuncs := make([]func(T, T) T, 0)
+		for {
+			opFunc, right, done, err := chainParseOperatorAndOperand(ctx, operand, operator)
+			if err != nil {
+				return zeroVal[T](), err
+			}
+			if done {
+				break
+			}
+			opFuncs = append(opFuncs, opFunc)
+			operands = append(operands, right)
+		}
+
+		result := operands[len(operands)-1]
+		for i := len(opFuncs) - 1; i >= 0; i-- {
+			result = opFuncs[i](operands[i], result)
+		}
+		return result, nil
+	}
+}
+
+// Internal helper function used with Chain parsers. Parses an operator followed by
+// an operand. Returns done as true if no operator was matched.
+func chainParseOperatorAndOperand[CT, T any](ctx Context[CT], operand Parser[CT, T],
+	operator Parser[CT, func(T, T) T]) (func(T, T) T, T, bool, error) {
+
+	opFunc, err := operator(ctx)
+	if err != nil {
+		if IsMustReturnParseErr(err) {
+			return nil, zeroVal[T](), false, err
+		}
+		return nil, zeroVal[T](), true, nil
+	}
+	right, err := operand(ctx)
+	if err != nil {
+		if IsMustReturnParseErr(err) {
+			return nil, zeroVal[T](), false, err
+		}
+		return nil, zeroVal[T](), false, ParseErrConsumedExpectedButGotNext(ctx, ctx.GetCurParserName(), err)
+	}
+	return opFunc, right, false, nil
+}
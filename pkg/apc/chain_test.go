@@ -0,0 +1,72 @@
+package apc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testChainOperator(op string) Parser[rune, func(string, string) string] {
+	return Bind(ExactStr(op), func(left string, right string) string {
+		return fmt.Sprintf("(%v%v%v)", left, op, right)
+	})
+}
+
+func TestChainLeft1Parser(t *testing.T) {
+	p := ChainLeft1(Regex("[a-z]"), Any(testChainOperator("-"), testChainOperator("+")))
+
+	ctx := NewStringContext(testStringOrigin, "a-b+c-d")
+	node, err := Parse[rune](ctx, p, DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, "(((a-b)+c)-d)", node)
+
+	ctx = NewStringContext(testStringOrigin, "a_")
+	node, err = p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", node)
+
+	ctx = NewStringContext(testStringOrigin, "_")
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	assert.NotErrorIs(t, err, ErrParseErrConsumed)
+
+	ctx = NewStringContext(testStringOrigin, "a-b+")
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+	assert.Equal(t, Origin{Name: testStringOrigin, LineNum: 1, ColNum: 4}, err.(*ParseErrorConsumed).Origin)
+}
+
+func TestChainRight1Parser(t *testing.T) {
+	p := ChainRight1(Regex("[a-z]"), testChainOperator("^"))
+
+	ctx := NewStringContext(testStringOrigin, "a^b^c")
+	node, err := Parse[rune](ctx, p, DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, "(a^(b^c))", node)
+
+	ctx = NewStringContext(testStringOrigin, "a")
+	node, err = p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", node)
+
+	ctx = NewStringContext(testStringOrigin, "a^")
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+}
+
+func TestChainParsersWithIntOperands(t *testing.T) {
+	sub := Bind(ExactStr("-"), func(left int64, right int64) int64 { return left - right })
+	ctx := NewStringContext(testStringOrigin, "10 - 4 - 3")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+
+	node, err := Parse[rune](ctx, ChainLeft1(IntParser, sub), DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), node)
+
+	ctx = NewStringContext(testStringOrigin, "10 - 4 - 3")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	node, err = Parse[rune](ctx, ChainRight1(IntParser, sub), DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), node)
+}
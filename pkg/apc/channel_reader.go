@@ -0,0 +1,115 @@
+package apc
+
+import "context"
+
+// A single result of ReaderWithOrigin.Read sent over a channel.
+type channelReaderItem[T any] struct {
+	val    T
+	origin Origin
+	err    error
+}
+
+// ChannelReader implements ReaderWithOrigin[T] by reading from another
+// ReaderWithOrigin[T] on its own goroutine, such as a ParseReader running a lexer,
+// so that it runs concurrently with the parser reading from the ChannelReader.
+//
+// The goroutine reads ahead up to a bounded number of elements, and stops once
+// the reader returns an error (including ErrEOF), the context.Context is canceled,
+// or Close is called. Close must be called if the ChannelReader is not read until
+// an error is returned, or the goroutine leaks.
+type ChannelReader[T any] struct {
+	goCtx  context.Context
+	cancel context.CancelFunc
+	items  chan channelReaderItem[T]
+	// Closed once the goroutine has returned.
+	done chan struct{}
+	// The error returned by every read once reading fails.
+	err error
+	// The Origin of the last element read, returned along with err.
+	errOrigin Origin
+}
+
+// Returns a *ChannelReader[T] reading from reader on a new goroutine, buffering
+// up to bufferSize elements (at least 1) ahead of the calls to Read. The goroutine
+// stops once goCtx is canceled, and Read then returns goCtx.Err().
+func NewChannelReader[T any](goCtx context.Context, reader ReaderWithOrigin[T], bufferSize int) *ChannelReader[T] {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	goCtx, cancel := context.WithCancel(goCtx)
+	r := &ChannelReader[T]{
+		goCtx:  goCtx,
+		cancel: cancel,
+		items:  make(chan channelReaderItem[T], bufferSize),
+		done:   make(chan struct{}),
+	}
+	go r.run(reader)
+	return r
+}
+
+// Returns a *ChannelReader[T] running lexer over ctx on a new goroutine, as by
+// NewChannelReader with a ParseReader. ctx must not be used by anything else until
+// the ChannelReader is closed or returns an error.
+func NewLexerChannelReader[T any](goCtx context.Context, ctx Context[rune], lexer Parser[rune, T], bufferSize int) *ChannelReader[T] {
+	return NewChannelReader[T](goCtx, NewParseReader(ctx, lexer), bufferSize)
+}
+
+// Reads from reader until it returns an error or the context is canceled.
+func (r *ChannelReader[T]) run(reader ReaderWithOrigin[T]) {
+	defer close(r.done)
+	defer close(r.items)
+	for {
+		if r.goCtx.Err() != nil {
+			return
+		}
+		val, origin, err := reader.Read()
+		select {
+		case r.items <- channelReaderItem[T]{val: val, origin: origin, err: err}:
+		case <-r.goCtx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Returns the next element read by the goroutine, along with its Origin.
+// Once an error occurs, including ErrEOF or the error of the context.Context
+// once canceled, it is returned by every following call.
+func (r *ChannelReader[T]) Read() (T, Origin, error) {
+	if r.err != nil {
+		return zeroVal[T](), r.errOrigin, r.err
+	}
+	// Prefer a canceled context to any elements already buffered.
+	if err := r.goCtx.Err(); err != nil {
+		r.err = err
+		return zeroVal[T](), r.errOrigin, r.err
+	}
+
+	select {
+	case item, ok := <-r.items:
+		if !ok {
+			// The goroutine only stops without sending an error if canceled.
+			r.err = r.goCtx.Err()
+			return zeroVal[T](), r.errOrigin, r.err
+		}
+		if item.err != nil {
+			r.err = item.err
+		}
+		r.errOrigin = item.origin
+		return item.val, item.origin, item.err
+	case <-r.goCtx.Done():
+		r.err = r.goCtx.Err()
+		return zeroVal[T](), r.errOrigin, r.err
+	}
+}
+
+// Stops the goroutine and waits for it to return. Any following call to Read
+// returns context.Canceled, unless reading had already failed.
+// If the goroutine is blocked in a call to Read of its reader, Close waits for
+// that call to return.
+func (r *ChannelReader[T]) Close() {
+	r.cancel()
+	<-r.done
+}
@@ -0,0 +1,102 @@
+package apc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Implements ReaderWithOrigin[int] by returning increasing ints forever,
+// counting the calls to Read.
+type countingReader struct {
+	reads int32
+}
+
+func (r *countingReader) Read() (int, Origin, error) {
+	n := atomic.AddInt32(&r.reads, 1)
+	return int(n), Origin{Name: "count", LineNum: 1, ColNum: int(n)}, nil
+}
+
+// Returns true if the goroutine of r returns within a second.
+func channelReaderStopped[T any](r *ChannelReader[T]) bool {
+	select {
+	case <-r.done:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func newTestChannelTokenContext(goCtx context.Context, input string, bufferSize int) (*ReaderContext[Token], *ChannelReader[Token]) {
+	ctx := NewStringContext(testStringOrigin, input)
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	reader := NewLexerChannelReader(goCtx, ctx, newTestLexer(), bufferSize)
+	return NewReaderContext[Token](reader), reader
+}
+
+func TestChannelReaderParse(t *testing.T) {
+	input := strings.Repeat("abc 12\n", 100)
+	p := OneOrMore(Seq(ExactTokenType(testTokenTypeIdent), ExactTokenType(testTokenTypeInt)))
+
+	ctx, reader := newTestChannelTokenContext(context.Background(), input, 4)
+	defer reader.Close()
+	node, err := Parse[Token](ctx, p, DefaultParseConfig)
+	assert.NoError(t, err)
+	exp, expErr := Parse[Token](newTestTokenContext(input), p, DefaultParseConfig)
+	assert.NoError(t, expErr)
+	assert.Equal(t, exp, node)
+	assert.True(t, channelReaderStopped(reader))
+}
+
+func TestChannelReaderLexError(t *testing.T) {
+	ctx, reader := newTestChannelTokenContext(context.Background(), "abc\n 12 !x", 1)
+	defer reader.Close()
+
+	val, err := ctx.Peek(0, 5)
+	assert.Nil(t, val)
+	var perr *ParseError
+	if assert.True(t, errors.As(err, &perr)) {
+		assert.Equal(t, Origin{Name: testStringOrigin, LineNum: 2, ColNum: 5}, perr.Origin)
+	}
+	// The error is returned again rather than reading on.
+	_, err = ctx.Peek(0, 5)
+	assert.ErrorAs(t, err, &perr)
+	assert.True(t, channelReaderStopped(reader))
+}
+
+func TestChannelReaderBoundedBuffer(t *testing.T) {
+	source := &countingReader{}
+	reader := NewChannelReader[int](context.Background(), source, 2)
+	val, origin, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+	assert.Equal(t, Origin{Name: "count", LineNum: 1, ColNum: 1}, origin)
+
+	time.Sleep(10 * time.Millisecond)
+	// One element read, two buffered, and one waiting to be sent.
+	assert.LessOrEqual(t, atomic.LoadInt32(&source.reads), int32(4))
+
+	// Stopping early does not leak the goroutine.
+	reader.Close()
+	assert.True(t, channelReaderStopped(reader))
+	_, _, err = reader.Read()
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestChannelReaderCancel(t *testing.T) {
+	goCtx, cancel := context.WithCancel(context.Background())
+	ctx, reader := newTestChannelTokenContext(goCtx, strings.Repeat("abc ", 1000), 1)
+	defer reader.Close()
+
+	_, err := ctx.Consume(1)
+	assert.NoError(t, err)
+	cancel()
+	assert.True(t, channelReaderStopped(reader))
+	_, err = Parse[Token](ctx, OneOrMore(ExactTokenType(testTokenTypeIdent)), DefaultParseConfig)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,171 @@
+package apc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Parses a "//" line comment and returns the comment text.
+var CLineCommentParser = Named("line comment", LineComment("//"))
+
+// Parses a "#" line comment and returns the comment text.
+var HashLineCommentParser = Named("line comment", LineComment("#"))
+
+// Parses a non-nesting "/* */" block comment and returns the comment text.
+var CBlockCommentParser = Named("block comment", BlockComment("/*", "*/", false))
+
+// Parses any amount (at least one) of whitespace, "//" line comments and
+// "/* */" block comments. Suitable for use as a skip parser.
+var CStyleWhitespaceAndCommentsParser = WhitespaceAndComments(CLineCommentParser, CBlockCommentParser)
+
+// Returns true if the runes of ctx starting at offset equal value.
+func peekMatches(ctx Context[rune], offset int, value []rune) (bool, error) {
+	vals, err := ctx.Peek(offset, len(value))
+	if err != nil && !errors.Is(err, ErrEOF) {
+		return false, err
+	}
+	if len(vals) != len(value) {
+		return false, nil
+	}
+	for i, rn := range value {
+		if vals[i] != rn {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Returns a parser that parses a line comment beginning with prefix
+// (such as "//", "#", "--" or ";") up to, but excluding, the end of the line.
+// The result is the comment text including the prefix.
+func LineComment(prefix string) Parser[rune, string] {
+	if len(prefix) == 0 {
+		panic("line comment prefix must not be empty")
+	}
+	prefixRunes := []rune(prefix)
+
+	return func(ctx Context[rune]) (string, error) {
+		err := ctx.RunSkipParsers()
+		if err != nil {
+			return "", err
+		}
+
+		ok, err := peekMatches(ctx, 0, prefixRunes)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			ctx.DebugPrint("line comment: %v => got no match", prefix)
+			return "", ParseErrExpectedButGotNext(ctx, ctx.GetCurParserName(), nil)
+		}
+
+		num := len(prefixRunes)
+		for {
+			vals, err := ctx.Peek(num, 1)
+			if err != nil {
+				if errors.Is(err, ErrEOF) {
+					break
+				}
+				return "", err
+			}
+			if vals[0] == '\n' {
+				break
+			}
+			num++
+		}
+
+		vals, err := ctx.Consume(num)
+		if err != nil && !errors.Is(err, ErrEOF) {
+			return "", err
+		}
+		ctx.DebugPrint("line comment: %v => got %v", prefix, string(vals))
+		return string(vals), nil
+	}
+}
+
+// Returns a parser that parses a block comment delimited by open and close
+// (such as "/*" and "*/"). If nested is true, each open delimiter inside the
+// comment must be matched by its own close delimiter.
+// The result is the comment text including the delimiters.
+//
+// If the end of input is reached before the comment is closed, a
+// ParseErrorConsumed is returned with the Origin of the opening delimiter.
+func BlockComment(open string, close string, nested bool) Parser[rune, string] {
+	if len(open) == 0 || len(close) == 0 {
+		panic("block comment delimiters must not be empty")
+	}
+	openRunes := []rune(open)
+	closeRunes := []rune(close)
+
+	return func(ctx Context[rune]) (string, error) {
+		err := ctx.RunSkipParsers()
+		if err != nil {
+			return "", err
+		}
+
+		ok, err := peekMatches(ctx, 0, openRunes)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			ctx.DebugPrint("block comment: %v => got no match", open)
+			return "", ParseErrExpectedButGotNext(ctx, ctx.GetCurParserName(), nil)
+		}
+
+		openOrigin := ctx.GetCurOrigin()
+		depth := 1
+		num := len(openRunes)
+		for depth > 0 {
+			if _, err := ctx.Peek(num, 1); err != nil {
+				if errors.Is(err, ErrEOF) {
+					return "", &ParseErrorConsumed{
+						Message: fmt.Sprintf("unterminated block comment: expected %v to close %v", close, open),
+						Origin:  openOrigin,
+					}
+				}
+				return "", err
+			}
+
+			if ok, err := peekMatches(ctx, num, closeRunes); err != nil {
+				return "", err
+			} else if ok {
+				depth--
+				num += len(closeRunes)
+				continue
+			}
+			if nested {
+				if ok, err := peekMatches(ctx, num, openRunes); err != nil {
+					return "", err
+				} else if ok {
+					depth++
+					num += len(openRunes)
+					continue
+				}
+			}
+			num++
+		}
+
+		vals, err := ctx.Consume(num)
+		if err != nil && !errors.Is(err, ErrEOF) {
+			return "", err
+		}
+		ctx.DebugPrint("block comment: %v => got %v", open, string(vals))
+		return string(vals), nil
+	}
+}
+
+// Returns a parser that parses at least one of whitespace or any of the
+// provided comment parsers, repeatedly, and returns the parsed text.
+// Suitable for use as a skip parser.
+func WhitespaceAndComments(commentParsers ...Parser[rune, string]) Parser[rune, any] {
+	parsers := append([]Parser[rune, string]{WhitespaceParser}, commentParsers...)
+	return Named("whitespace or comment", Map(
+		OneOrMore(Any(parsers...)),
+		func(nodes []string) any {
+			text := ""
+			for _, node := range nodes {
+				text += node
+			}
+			return text
+		}))
+}
@@ -0,0 +1,76 @@
+package apc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineCommentParser(t *testing.T) {
+	for _, prefix := range []string{"//", "#", "--", ";"} {
+		ctx := NewStringContext(testStringOrigin, prefix+" hello\nnext")
+		node, err := LineComment(prefix)(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, prefix+" hello", node)
+
+		r, err := ctx.Peek(0, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []rune{'\n'}, r)
+	}
+
+	ctx := NewStringContext(testStringOrigin, "# at EOF")
+	node, err := HashLineCommentParser(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "# at EOF", node)
+
+	ctx = NewStringContext(testStringOrigin, "/ not a comment")
+	_, err = CLineCommentParser(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+}
+
+func TestBlockCommentParser(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "/* a /* b */ c */")
+	node, err := CBlockCommentParser(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "/* a /* b */", node)
+
+	ctx = NewStringContext(testStringOrigin, "/* a /* b */ c */x")
+	node, err = BlockComment("/*", "*/", true)(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "/* a /* b */ c */", node)
+	r, err := ctx.Peek(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []rune{'x'}, r)
+
+	ctx = NewStringContext(testStringOrigin, "{- a {- b -} -}")
+	node, err = BlockComment("{-", "-}", true)(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "{- a {- b -} -}", node)
+}
+
+func TestBlockCommentParserUnterminated(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "x\n  /* a /* b */")
+	ctx.AddSkipParser(WhitespaceAndComments(BlockComment("/*", "*/", true)))
+	_, err := ExactStr("x")(ctx)
+	assert.NoError(t, err)
+
+	_, err = ExactStr("y")(ctx)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+	assert.Equal(t, Origin{Name: testStringOrigin, LineNum: 2, ColNum: 3}, err.(*ParseErrorConsumed).Origin)
+}
+
+func TestWhitespaceAndCommentsSkipParser(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "a // one\n /* two */ b\n# not skipped")
+	ctx.AddSkipParser(CStyleWhitespaceAndCommentsParser)
+	p := Seq(ExactStr("a"), ExactStr("b"))
+
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, node)
+
+	_, err = Parse[rune](ctx, ExactStr("c"), DefaultParseConfig)
+	assert.ErrorIs(t, err, ErrParseErr)
+	r, err := ctx.Peek(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []rune{'#'}, r)
+}
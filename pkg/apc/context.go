@@ -31,12 +31,30 @@ type Context[CT any] interface {
 	GetCurOrigin() Origin
 	// Adds the parser to the list of parsers that attempt to run when
 	// RunSkipParsers is called. If the parser matches, its result will
-	// be discarded. Duplicate parsers cannot be added.
-	AddSkipParser(parser Parser[CT, any])
-	// Removes the parser from the list of parsers that attempt to run
-	// when RunSkipParsers is called. If the parser has not been added,
+	// be discarded. Returns the handle with which to remove the parser.
+	AddSkipParser(parser Parser[CT, any]) SkipHandle
+	// Removes the parser added with the handle from the list of parsers that
+	// attempt to run when RunSkipParsers is called. If the parser is not in the
+	// list, such as if it was added before the current skip set was pushed,
 	// the function panics.
-	RemoveSkipParser(parser Parser[CT, any])
+	RemoveSkipParser(handle SkipHandle)
+	// Stops the parser added with the handle from running when RunSkipParsers
+	// is called, until EnableSkipParser is called with the handle. The handle
+	// remains valid, and a parser disabled n times is enabled again after n calls
+	// to EnableSkipParser. If the parser is not in the list, the function panics.
+	DisableSkipParser(handle SkipHandle)
+	// Reverts a call to DisableSkipParser with the handle. If the parser is not
+	// in the list or is not disabled, the function panics.
+	EnableSkipParser(handle SkipHandle)
+	// Replaces the list of parsers that attempt to run when RunSkipParsers is
+	// called with the parsers of set, until PopSkipSet is called.
+	PushSkipSet(set SkipSet[CT])
+	// Restores the list of parsers replaced by the last call to PushSkipSet,
+	// returning the name of the popped skip set. If no skip set has been pushed,
+	// the function panics.
+	PopSkipSet() string
+	// Returns the name of the current skip set, or "" if none has been pushed.
+	GetSkipSetName() string
 	// Attempts to run any added skip parsers as long as one of the parsers
 	// successfully matches. The results of any matched parsers is discarded.
 	// Should only return nil or non-ParseError errors.
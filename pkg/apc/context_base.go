@@ -0,0 +1,295 @@
+package apc
+
+import "fmt"
+
+// contextBase[CT] holds the state of a Context[CT] that does not depend on how
+// its input is stored: skip parsers, the look offset, the current parser name,
+// debugging, user data, retained trivia and the input consumed within spans.
+// Contexts embed it, implementing RunSkipParsers, the Debug methods and spans by
+// passing themselves to it.
+type contextBase[CT any] struct {
+	// Whether or not to retain elements consumed by skip parsers as trivia.
+	retainTrivia bool
+	// Retained trivia, ordered by position.
+	trivia []triviaElement[CT]
+	// Input consumed while a span is open, ordered by position, and the number
+	// of open spans. See Span.
+	consumeLog []loggedConsume[CT]
+	openSpans  int
+	// The current skip state. Its slices are never modified in place, so that
+	// they can be saved and restored.
+	skip skipState[CT]
+	// The id of the last added skip parser.
+	lastSkipID uint64
+	// Whether or not RunSkipParsers is currently running.
+	skipping bool
+	// Current parser name.
+	curParserName string
+	// Current look offset value.
+	lookOffset int
+	// Current debug indentation.
+	debugIndentation string
+	// Whether or not to enable debugging.
+	DebugParsers bool
+	// User data storage
+	userData any
+}
+
+func newContextBase[CT any]() contextBase[CT] {
+	return contextBase[CT]{
+		skipping:         false,
+		curParserName:    "<unknown>",
+		lookOffset:       InvalidLookOffset,
+		debugIndentation: "",
+		DebugParsers:     false,
+		userData:         nil,
+	}
+}
+
+// Returns nil, as coverage is only recorded by wrapping a context with WithCoverage.
+func (ctx *contextBase[CT]) coverage() *coverageContext[CT] {
+	return nil
+}
+
+// Returns the look offset, or 0 if not in a Look frame.
+func (ctx *contextBase[CT]) lookOffsetOrZero() int {
+	if ctx.lookOffset == InvalidLookOffset {
+		return 0
+	}
+	return ctx.lookOffset
+}
+
+// Adds the parser to the list of parsers that attempt to run when
+// RunSkipParsers is called. If the parser matches, its result will
+// be discarded. Returns the handle with which to remove the parser.
+func (ctx *contextBase[CT]) AddSkipParser(parser Parser[CT, any]) SkipHandle {
+	ctx.lastSkipID++
+	handle := SkipHandle{id: ctx.lastSkipID}
+	parsers := ctx.skip.parsers
+	ctx.skip.parsers = append(parsers[:len(parsers):len(parsers)], registeredSkipParser[CT]{
+		handle: handle,
+		parser: parser,
+	})
+	return handle
+}
+
+// Removes the parser added with the handle from the list of parsers that
+// attempt to run when RunSkipParsers is called. If the parser is not in the
+// list, such as if it was added before the current skip set was pushed,
+// the function panics.
+func (ctx *contextBase[CT]) RemoveSkipParser(handle SkipHandle) {
+	for i, registered := range ctx.skip.parsers {
+		if registered.handle == handle {
+			parsers := make([]registeredSkipParser[CT], 0, len(ctx.skip.parsers)-1)
+			parsers = append(parsers, ctx.skip.parsers[:i]...)
+			ctx.skip.parsers = append(parsers, ctx.skip.parsers[i+1:]...)
+			return
+		}
+	}
+	panic("cannot remove non-existent skip parser")
+}
+
+// Stops the parser added with the handle from running when RunSkipParsers
+// is called, until EnableSkipParser is called with the handle. The handle
+// remains valid, and a parser disabled n times is enabled again after n calls
+// to EnableSkipParser. If the parser is not in the list, the function panics.
+func (ctx *contextBase[CT]) DisableSkipParser(handle SkipHandle) {
+	ctx.addSkipParserDisabled(handle, 1)
+}
+
+// Reverts a call to DisableSkipParser with the handle. If the parser is not
+// in the list or is not disabled, the function panics.
+func (ctx *contextBase[CT]) EnableSkipParser(handle SkipHandle) {
+	ctx.addSkipParserDisabled(handle, -1)
+}
+
+// Adds delta to the disabled count of the parser added with the handle. The
+// parsers are copied, as saved skip states may share them.
+func (ctx *contextBase[CT]) addSkipParserDisabled(handle SkipHandle, delta int) {
+	for i, registered := range ctx.skip.parsers {
+		if registered.handle == handle {
+			if registered.disabled+delta < 0 {
+				panic("cannot enable a skip parser that is not disabled")
+			}
+			parsers := append([]registeredSkipParser[CT](nil), ctx.skip.parsers...)
+			parsers[i].disabled += delta
+			ctx.skip.parsers = parsers
+			return
+		}
+	}
+	panic("cannot disable or enable non-existent skip parser")
+}
+
+// Replaces the list of parsers that attempt to run when RunSkipParsers is
+// called with the parsers of set, until PopSkipSet is called.
+func (ctx *contextBase[CT]) PushSkipSet(set SkipSet[CT]) {
+	parsers := make([]registeredSkipParser[CT], 0, len(set.Parsers))
+	for _, parser := range set.Parsers {
+		ctx.lastSkipID++
+		parsers = append(parsers, registeredSkipParser[CT]{
+			handle: SkipHandle{id: ctx.lastSkipID},
+			parser: parser,
+		})
+	}
+	saved := ctx.skip
+	ctx.skip = skipState[CT]{
+		parsers: parsers,
+		setName: set.Name,
+		saved:   &saved,
+	}
+}
+
+// Restores the list of parsers replaced by the last call to PushSkipSet,
+// returning the name of the popped skip set. If no skip set has been pushed,
+// the function panics.
+func (ctx *contextBase[CT]) PopSkipSet() string {
+	if ctx.skip.saved == nil {
+		panic("cannot pop a skip set that has not been pushed")
+	}
+	name := ctx.skip.setName
+	ctx.skip = *ctx.skip.saved
+	return name
+}
+
+// Returns the name of the current skip set, or "" if none has been pushed.
+func (ctx *contextBase[CT]) GetSkipSetName() string {
+	return ctx.skip.setName
+}
+
+// Returns the current skip state.
+func (ctx *contextBase[CT]) saveSkipState() skipState[CT] {
+	return ctx.skip
+}
+
+// Restores a skip state returned by saveSkipState.
+func (ctx *contextBase[CT]) restoreSkipState(state skipState[CT]) {
+	ctx.skip = state
+}
+
+// Implements RunSkipParsers, running the skip parsers on outer, the Context
+// embedding the contextBase.
+func (ctx *contextBase[CT]) runSkipParsers(outer Context[CT]) error {
+	if ctx.skipping {
+		return nil
+	}
+
+	ctx.skipping = true
+	// Reset even if a skip parser panics, so skipping is not disabled for good
+	defer func() {
+		ctx.skipping = false
+	}()
+
+	skip := true
+	for skip {
+		skip = false
+		for _, registered := range ctx.skip.parsers {
+			if registered.disabled > 0 {
+				continue
+			}
+			_, err := registered.parser(outer)
+			if err == nil {
+				skip = true
+				break
+			} else if IsMustReturnParseErr(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Sets the look value.
+func (ctx *contextBase[CT]) SetLookOffset(val int) {
+	ctx.lookOffset = val
+}
+
+// Gets the look value.
+func (ctx *contextBase[CT]) GetLookOffset() int {
+	return ctx.lookOffset
+}
+
+// Sets the name of all subsequent parsers.
+func (ctx *contextBase[CT]) SetCurParserName(name string) {
+	ctx.curParserName = name
+}
+
+// Gets the current name of parsers.
+func (ctx *contextBase[CT]) GetCurParserName() string {
+	return ctx.curParserName
+}
+
+// Implements DebugStart for outer, the Context embedding the contextBase.
+func (ctx *contextBase[CT]) debugStart(outer Context[CT], format string, formatArgs ...interface{}) {
+	if !ctx.DebugParsers {
+		return
+	}
+	fmt.Printf("%vSTART: %v (in %v) @ %v\n", ctx.debugIndentation, fmt.Sprintf(format, formatArgs...), ctx.GetCurParserName(), outer.GetCurOrigin())
+	ctx.debugIndentation += "  "
+}
+
+// Implements DebugPrint for outer, the Context embedding the contextBase.
+func (ctx *contextBase[CT]) debugPrint(outer Context[CT], format string, formatArgs ...interface{}) {
+	if !ctx.DebugParsers {
+		return
+	}
+	fmt.Printf("%vDEBUG: %v (in %v) @ %v\n", ctx.debugIndentation, fmt.Sprintf(format, formatArgs...), ctx.GetCurParserName(), outer.GetCurOrigin())
+}
+
+// Implements DebugEnd for outer, the Context embedding the contextBase.
+func (ctx *contextBase[CT]) debugEnd(outer Context[CT], format string, formatArgs ...interface{}) {
+	if !ctx.DebugParsers {
+		return
+	}
+	ctx.debugIndentation = ctx.debugIndentation[:len(ctx.debugIndentation)-2]
+	fmt.Printf("%vEND: %v @ %v\n", ctx.debugIndentation, fmt.Sprintf(format, formatArgs...), outer.GetCurOrigin())
+}
+
+func (ctx *contextBase[CT]) SetUserData(data any) {
+	ctx.userData = data
+}
+
+func (ctx *contextBase[CT]) GetUserData() any {
+	return ctx.userData
+}
+
+// Retains vals as trivia starting at the position pos, discarding any trivia
+// retained at or after pos by parsing that was backtracked.
+func (ctx *contextBase[CT]) appendTrivia(pos int, vals []CT) {
+	for len(ctx.trivia) > 0 && ctx.trivia[len(ctx.trivia)-1].pos >= pos {
+		ctx.trivia = ctx.trivia[:len(ctx.trivia)-1]
+	}
+	for i, val := range vals {
+		ctx.trivia = append(ctx.trivia, triviaElement[CT]{pos: pos + i, val: val})
+	}
+}
+
+// Sets whether or not elements consumed by skip parsers are retained as trivia.
+// Returns the previous setting.
+func (ctx *contextBase[CT]) setRetainTrivia(retain bool) bool {
+	prev := ctx.retainTrivia
+	ctx.retainTrivia = retain
+	if !retain {
+		ctx.trivia = nil
+	}
+	return prev
+}
+
+// Returns the retained trivia in the positions [start, end).
+func (ctx *contextBase[CT]) triviaBetween(start int, end int) []CT {
+	vals := make([]CT, 0)
+	for _, elem := range ctx.trivia {
+		if elem.pos >= start && elem.pos < end {
+			vals = append(vals, elem.val)
+		}
+	}
+	return vals
+}
+
+// Discards the retained trivia before the position pos.
+func (ctx *contextBase[CT]) discardTriviaBefore(pos int) {
+	i := 0
+	for i < len(ctx.trivia) && ctx.trivia[i].pos < pos {
+		i++
+	}
+	ctx.trivia = ctx.trivia[i:]
+}
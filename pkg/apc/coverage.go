@@ -0,0 +1,339 @@
+package apc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Records which branches of the Any, Maybe, Range and Named parsers run on a
+// Context[CT] returned by WithCoverage were taken, across any number of parses.
+//
+// A parser is only known to a Coverage once it has been run at least once, so
+// parsers only reachable through a branch that was never taken are reported
+// as that untaken branch. Skip parsers are not recorded.
+//
+// A Coverage is safe for concurrent use by multiple parses.
+type Coverage struct {
+	lock    sync.Mutex
+	records map[*coverageSite]*coverageRecord
+	order   []*coverageRecord
+}
+
+// Returns an empty Coverage.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		records: make(map[*coverageSite]*coverageRecord),
+	}
+}
+
+// Returns a Context[CT] wrapping ctx that records the coverage of parsers run on it into cov.
+func WithCoverage[CT any](ctx Context[CT], cov *Coverage) Context[CT] {
+	return &coverageContext[CT]{
+		Context: ctx,
+		cov:     cov,
+	}
+}
+
+// Returns a parser that labels the coverage recorded for the Any, Maybe, Range
+// and Named parsers run by parser, such as with the field of a struct they parse.
+// An empty label keeps the label of any enclosing CoverageLabel parser.
+//
+// If branchNames is not nil, it names the branches of the first of those parsers
+// run by parser, which must have the same number of branches. For example, the
+// alternatives of an Any parser. Empty names keep their default name.
+//
+// Has no effect unless parser is run on a Context[CT] returned by WithCoverage.
+func CoverageLabel[CT, T any](label string, branchNames []string, parser Parser[CT, T]) Parser[CT, T] {
+	return func(ctx Context[CT]) (T, error) {
+		cctx := coverageOf(ctx)
+		if cctx == nil {
+			return parser(ctx)
+		}
+		curLabel := label
+		if curLabel == "" && len(cctx.labels) > 0 {
+			curLabel = cctx.labels[len(cctx.labels)-1].label
+		}
+		cctx.labels = append(cctx.labels, &coverageLabel{
+			label:       curLabel,
+			branchNames: branchNames,
+		})
+		defer func() {
+			cctx.labels = cctx.labels[:len(cctx.labels)-1]
+		}()
+		return parser(ctx)
+	}
+}
+
+// A parser whose branches are recorded by a Coverage, created along with the parser.
+type coverageSite struct {
+	kind     string
+	branches []string
+}
+
+func newCoverageSite(kind string, branches ...string) *coverageSite {
+	return &coverageSite{
+		kind:     kind,
+		branches: branches,
+	}
+}
+
+// Returns the coverage site of a Range(min, max, ...) parser, with a branch for
+// each of zero, one or many matches that the range allows.
+func newRangeCoverageSite(min int, max int) *coverageSite {
+	site := newCoverageSite("range")
+	if min == 0 {
+		site.branches = append(site.branches, "zero")
+	}
+	if min <= 1 {
+		site.branches = append(site.branches, "one")
+	}
+	if max == -1 || max >= 2 {
+		site.branches = append(site.branches, "many")
+	}
+	return site
+}
+
+// Returns the branch of a site created by newRangeCoverageSite taken when count
+// matches occurred.
+func (site *coverageSite) rangeBranch(count int) int {
+	name := "many"
+	switch count {
+	case 0:
+		name = "zero"
+	case 1:
+		name = "one"
+	}
+	for i, branch := range site.branches {
+		if branch == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// The coverage of a single coverageSite.
+type coverageRecord struct {
+	index    int
+	kind     string
+	rule     string
+	label    string
+	branches []string
+	hits     []int64
+}
+
+// Records that branch was taken. Does nothing if rec is nil.
+func (rec *coverageRecord) hit(branch int) {
+	if rec == nil || branch < 0 {
+		return
+	}
+	atomic.AddInt64(&rec.hits[branch], 1)
+}
+
+// Returns the record of site in the Coverage of ctx, creating it if this is
+// the first time the site has been run. Returns nil if ctx does not record coverage.
+func enterCoverage[CT any](ctx Context[CT], site *coverageSite) *coverageRecord {
+	cctx := coverageOf(ctx)
+	if cctx == nil {
+		return nil
+	}
+
+	var lbl *coverageLabel
+	if len(cctx.labels) > 0 {
+		lbl = cctx.labels[len(cctx.labels)-1]
+	}
+	// Only the first site run by a CoverageLabel parser may claim its branch names
+	var branchNames []string
+	if lbl != nil && !lbl.claimed {
+		lbl.claimed = true
+		branchNames = lbl.branchNames
+	}
+
+	cov := cctx.cov
+	cov.lock.Lock()
+	defer cov.lock.Unlock()
+	if rec, ok := cov.records[site]; ok {
+		return rec
+	}
+
+	rec := &coverageRecord{
+		index:    len(cov.order),
+		kind:     site.kind,
+		rule:     ctx.GetCurParserName(),
+		branches: append([]string{}, site.branches...),
+		hits:     make([]int64, len(site.branches)),
+	}
+	if lbl != nil {
+		rec.label = lbl.label
+	}
+	if len(branchNames) == len(rec.branches) {
+		for i, name := range branchNames {
+			if name != "" {
+				rec.branches[i] = name
+			}
+		}
+	}
+	cov.records[site] = rec
+	cov.order = append(cov.order, rec)
+	return rec
+}
+
+// Implemented by contexts that may record coverage. Contexts embedding contextBase
+// never do, and context wrappers forward coverage to the context they wrap, so
+// that finding the coverageContext[CT] is cheap when coverage is not recorded.
+type coverageRecorder[CT any] interface {
+	coverage() *coverageContext[CT]
+}
+
+// Returns the coverageContext[CT] recording coverage for ctx, or nil if there is none.
+func coverageOf[CT any](ctx Context[CT]) *coverageContext[CT] {
+	if cctx, ok := ctx.(coverageRecorder[CT]); ok {
+		return cctx.coverage()
+	}
+	return nil
+}
+
+// A label pushed by a CoverageLabel parser.
+type coverageLabel struct {
+	label       string
+	branchNames []string
+	claimed     bool
+}
+
+// Wraps a Context[CT] to record coverage into a Coverage.
+type coverageContext[CT any] struct {
+	Context[CT]
+	cov    *Coverage
+	labels []*coverageLabel
+}
+
+func (ctx *coverageContext[CT]) coverage() *coverageContext[CT] {
+	return ctx
+}
+
+func (ctx *coverageContext[CT]) commitLook(num int) error {
+	return commitLook(ctx.Context, num)
+}
+
+func (ctx *coverageContext[CT]) position() (int, bool) {
+	return contextPosition(ctx.Context)
+}
+
+func (ctx *coverageContext[CT]) endOfInputOrigin() (Origin, bool) {
+	return endOfInputOriginOf(ctx.Context)
+}
+
+func (ctx *coverageContext[CT]) openSpan() (spanStart, bool) {
+	return openSpanOf(ctx.Context)
+}
+
+func (ctx *coverageContext[CT]) closeSpan(start spanStart) OriginRange {
+	return ctx.Context.(spanLogger[CT]).closeSpan(start)
+}
+
+func (ctx *coverageContext[CT]) unwrap() Context[CT] {
+	return ctx.Context
+}
+
+// A report of the branches recorded by a Coverage.
+type CoverageReport struct {
+	// Number of branches taken at least once.
+	Covered int `json:"covered"`
+	// Number of branches of all parsers that were run.
+	Total int `json:"total"`
+	// The parsers that have branches that were never taken, listing only those branches.
+	Uncovered []CoverageSiteReport `json:"uncovered"`
+}
+
+// The branches of a single Any, Maybe, Range or Named parser.
+type CoverageSiteReport struct {
+	// One of "any", "maybe", "range" or "named".
+	Kind string `json:"kind"`
+	// The name of the parser the site was first run within, as given by Named.
+	Rule string `json:"rule"`
+	// The label given by CoverageLabel, if any.
+	Label string `json:"label,omitempty"`
+	// The names of the branches, such as "alternative 2" of an Any parser.
+	Branches []string `json:"branches"`
+}
+
+// Returns a report of the coverage recorded so far, with uncovered parsers
+// sorted by rule and label.
+func (cov *Coverage) Report() *CoverageReport {
+	cov.lock.Lock()
+	records := append([]*coverageRecord{}, cov.order...)
+	cov.lock.Unlock()
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].rule != records[j].rule {
+			return records[i].rule < records[j].rule
+		}
+		if records[i].label != records[j].label {
+			return records[i].label < records[j].label
+		}
+		return records[i].index < records[j].index
+	})
+
+	report := &CoverageReport{
+		Uncovered: []CoverageSiteReport{},
+	}
+	for _, rec := range records {
+		site := CoverageSiteReport{
+			Kind:     rec.kind,
+			Rule:     rec.rule,
+			Label:    rec.label,
+			Branches: []string{},
+		}
+		for i, name := range rec.branches {
+			report.Total++
+			if atomic.LoadInt64(&rec.hits[i]) > 0 {
+				report.Covered++
+				continue
+			}
+			site.Branches = append(site.Branches, name)
+		}
+		if len(site.Branches) > 0 {
+			report.Uncovered = append(report.Uncovered, site)
+		}
+	}
+	return report
+}
+
+// Writes the report as human-readable text to w, grouping uncovered branches by rule.
+func (report *CoverageReport) WriteText(w io.Writer) error {
+	percent := 100.0
+	if report.Total > 0 {
+		percent = 100 * float64(report.Covered) / float64(report.Total)
+	}
+	if _, err := fmt.Fprintf(w, "coverage: %v of %v branches (%.1f%%)\n", report.Covered, report.Total, percent); err != nil {
+		return err
+	}
+
+	for i, site := range report.Uncovered {
+		if i == 0 || report.Uncovered[i-1].Rule != site.Rule {
+			if _, err := fmt.Fprintf(w, "uncovered in rule '%v':\n", site.Rule); err != nil {
+				return err
+			}
+		}
+		kind := site.Kind
+		if site.Label != "" {
+			kind = fmt.Sprintf("%v (%v)", site.Kind, site.Label)
+		}
+		for _, branch := range site.Branches {
+			if _, err := fmt.Fprintf(w, "\t%v: %v\n", kind, branch); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Writes the report as indented JSON to w.
+func (report *CoverageReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(report)
+}
@@ -0,0 +1,114 @@
+package apc
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func coverageTestParser() Parser[rune, any] {
+	number := Named("number", CastToAny(Regex("[0-9]+")))
+	word := Named("word", CastToAny(Regex("[a-z]+")))
+	value := Named("value", Any(number, word))
+	return Named("list", CastToAny(Seq(
+		CastToAny(ExactStr("[")),
+		CastToAny(ZeroOrMore(value)),
+		CastToAny(Maybe(ExactStr("!"))),
+		CastToAny(ExactStr("]")),
+	)))
+}
+
+func TestCoverage(t *testing.T) {
+	parser := coverageTestParser()
+	cov := NewCoverage()
+
+	for _, input := range []string{"[]", "[1]"} {
+		ctx := WithCoverage[rune](NewStringContext(testStringOrigin, input), cov)
+		_, err := Parse[rune](ctx, parser, DefaultParseConfig)
+		assert.NoError(t, err, input)
+	}
+
+	report := cov.Report()
+	assert.Equal(t, &CoverageReport{
+		Covered: 7,
+		Total:   11,
+		Uncovered: []CoverageSiteReport{
+			{Kind: "range", Rule: "list", Branches: []string{"many"}},
+			{Kind: "maybe", Rule: "list", Branches: []string{"matched"}},
+			{Kind: "any", Rule: "value", Branches: []string{"alternative 2"}},
+			{Kind: "named", Rule: "word", Branches: []string{"matched"}},
+		},
+	}, report)
+
+	var text bytes.Buffer
+	assert.NoError(t, report.WriteText(&text))
+	assert.Equal(t, `coverage: 7 of 11 branches (63.6%)
+uncovered in rule 'list':
+	range: many
+	maybe: matched
+uncovered in rule 'value':
+	any: alternative 2
+uncovered in rule 'word':
+	named: matched
+`, text.String())
+
+	// The "word" rule was run, but only failed to match, before this parse
+	ctx := WithCoverage[rune](NewStringContext(testStringOrigin, "[1a!]"), cov)
+	_, err := Parse[rune](ctx, parser, DefaultParseConfig)
+	assert.NoError(t, err)
+	report = cov.Report()
+	assert.Equal(t, 11, report.Covered)
+	assert.Equal(t, 11, report.Total)
+	assert.Empty(t, report.Uncovered)
+}
+
+func TestCoverageJSON(t *testing.T) {
+	cov := NewCoverage()
+	ctx := WithCoverage[rune](NewStringContext(testStringOrigin, "[x]"), cov)
+	_, err := Parse[rune](ctx, coverageTestParser(), DefaultParseConfig)
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.NoError(t, cov.Report().WriteJSON(&out))
+	var decoded CoverageReport
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, cov.Report(), &decoded)
+	assert.Contains(t, out.String(), `"rule": "value"`)
+}
+
+func TestCoverageLabel(t *testing.T) {
+	parser := CoverageLabel("list", nil, Seq(
+		CastToAny(CoverageLabel("", []string{"digits", ""}, Any(Regex("[0-9]+"), Regex("[a-z]+")))),
+		CoverageLabel("suffix", nil, CastToAny(Maybe(ExactStr("!")))),
+	))
+	cov := NewCoverage()
+	ctx := WithCoverage[rune](NewStringContext(testStringOrigin, "ab!"), cov)
+	_, err := Parse[rune](ctx, parser, DefaultParseConfig)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []CoverageSiteReport{
+		{Kind: "any", Rule: "<unknown>", Label: "list", Branches: []string{"digits"}},
+		{Kind: "maybe", Rule: "<unknown>", Label: "suffix", Branches: []string{"not matched"}},
+	}, cov.Report().Uncovered)
+}
+
+func TestCoverageNotRecorded(t *testing.T) {
+	// Parsers run without WithCoverage behave as normal
+	ctx := NewStringContext(testStringOrigin, "[a]")
+	_, err := Parse[rune](ctx, coverageTestParser(), DefaultParseConfig)
+	assert.NoError(t, err)
+}
+
+func TestCoverageOf(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "[a]")
+	assert.Nil(t, coverageOf[rune](ctx))
+	assert.Nil(t, coverageOf[rune](newConsumeRecorder[rune](newCSTRecorder[rune](ctx), false)))
+	assert.Nil(t, coverageOf[rune](unpositionedContext[rune]{ctx}))
+
+	// Coverage is found through context wrappers
+	cctx := WithCoverage[rune](ctx, NewCoverage())
+	assert.Same(t, cctx, coverageOf[rune](cctx))
+	assert.Same(t, cctx, coverageOf[rune](newConsumeRecorder[rune](newCSTRecorder[rune](cctx), false)))
+}
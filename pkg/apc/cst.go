@@ -0,0 +1,325 @@
+package apc
+
+import (
+	"io"
+	"strings"
+)
+
+// CSTChild is a child of a CSTNode: either a *CSTNode or a *CSTToken.
+type CSTChild interface {
+	// Writes the input text of the child, including trivia, to sb.
+	writeText(sb *strings.Builder)
+}
+
+// CSTToken is the input consumed by a single terminal parser, along with the
+// input consumed by skip parsers directly before it (the trivia).
+type CSTToken struct {
+	// The Type of the consumed Token when parsing Tokens, otherwise empty.
+	Kind string
+	// Input consumed by skip parsers directly before this token.
+	Trivia string
+	// The consumed input.
+	Text        string
+	OriginRange OriginRange
+}
+
+func (tok *CSTToken) writeText(sb *strings.Builder) {
+	sb.WriteString(tok.Trivia)
+	sb.WriteString(tok.Text)
+}
+
+// CSTNode is a node of a lossless concrete syntax tree, as built by the CST
+// and MapCST parsers. Printing the root node reproduces the parsed input exactly.
+type CSTNode struct {
+	Kind string
+	// Children of the node, in input order.
+	Children []CSTChild
+	// Input consumed by skip parsers after the last token. Only set on the root node.
+	TrailingTrivia string
+	// The OriginRange of the tokens of the node, excluding trivia.
+	OriginRange OriginRange
+}
+
+func (node *CSTNode) writeText(sb *strings.Builder) {
+	for _, child := range node.Children {
+		child.writeText(sb)
+	}
+	sb.WriteString(node.TrailingTrivia)
+}
+
+// Returns the input text of the node, including trivia.
+func (node *CSTNode) String() string {
+	var sb strings.Builder
+	node.writeText(&sb)
+	return sb.String()
+}
+
+// Writes the input text of the node, including trivia, to w.
+func (node *CSTNode) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, node.String())
+	return int64(n), err
+}
+
+// Returns every token of the node and its descendants, in input order.
+func (node *CSTNode) Tokens() []*CSTToken {
+	toks := make([]*CSTToken, 0)
+	for _, child := range node.Children {
+		switch cchild := child.(type) {
+		case *CSTToken:
+			toks = append(toks, cchild)
+		case *CSTNode:
+			toks = append(toks, cchild.Tokens()...)
+		}
+	}
+	return toks
+}
+
+// MapCSTFunc is a function that maps some type T to some type U
+// with knowledge of the CSTNode built from the matched input.
+type MapCSTFunc[T, U any] func(node T, cst *CSTNode) U
+
+// Returns a parser that builds a *CSTNode of kind from the input matched by parser.
+// Equivalent to MapCST, returning the CSTNode.
+func CST[CT, T any](kind string, parser Parser[CT, T]) Parser[CT, *CSTNode] {
+	return MapCST(kind, parser, func(_ T, cst *CSTNode) *CSTNode {
+		return cst
+	})
+}
+
+// Returns a parser that maps a Parser[CT, T] into a Parser[CT, U] by running the
+// result of parser, along with a *CSTNode of kind built from the matched input,
+// through mapFunc.
+//
+// Every element consumed by a terminal parser becomes part of a CSTToken. While
+// the outermost CST parser runs, input consumed by skip parsers is retained as
+// trivia attached to the next CSTToken; the outermost parser runs the skip parsers
+// once more after parser, and attaches the remaining trivia to its CSTNode.
+// CST parsers nested within parser group their tokens into child CSTNodes.
+//
+// Retaining trivia requires the Context to be a ReaderContext, or to wrap one.
+// When parsing Tokens, trivia skipped while lexing is part of each Token's Raw text
+// only if the lexer includes it.
+func MapCST[CT, T, U any](kind string, parser Parser[CT, T], mapFunc MapCSTFunc[T, U]) Parser[CT, U] {
+	return func(ctx Context[CT]) (U, error) {
+		if outer, ok := findContext[*cstRecorder[CT]](ctx); ok {
+			startPos, _ := contextPosition(ctx)
+			outer.discardAfter(startPos)
+			index := len(outer.children)
+			startOrigin := curOriginOf(ctx)
+
+			node, err := parser(ctx)
+			if err != nil {
+				return zeroVal[U](), err
+			}
+			return mapFunc(node, outer.group(kind, index, startOrigin)), nil
+		}
+
+		recorder := newCSTRecorder(ctx)
+		if recorder.retainer != nil {
+			prev := recorder.retainer.setRetainTrivia(true)
+			defer recorder.retainer.setRetainTrivia(prev)
+		}
+		node, err := parser(recorder)
+		if err != nil {
+			return zeroVal[U](), err
+		}
+		err = ctx.RunSkipParsers()
+		if err != nil {
+			return zeroVal[U](), err
+		}
+		return mapFunc(node, recorder.root(kind)), nil
+	}
+}
+
+// Implemented by contexts that can retain the input consumed by skip parsers.
+type triviaRetainer[CT any] interface {
+	setRetainTrivia(retain bool) bool
+	triviaBetween(start int, end int) []CT
+	discardTriviaBefore(pos int)
+}
+
+// An element of input retained as trivia, with its absolute position.
+type triviaElement[CT any] struct {
+	pos int
+	val CT
+}
+
+// A child recorded by a cstRecorder[CT], with the absolute positions
+// of its first token and just past its last token.
+type cstRecord struct {
+	startPos int
+	endPos   int
+	child    CSTChild
+}
+
+// cstRecorder[CT] wraps a Context[CT] and records a CSTToken for every consumption
+// made through it. Recorded children undone by a Look frame backtracking are discarded.
+type cstRecorder[CT any] struct {
+	Context[CT]
+	// The context retaining trivia, or nil if there is none.
+	retainer triviaRetainer[CT]
+	// Position and Origin of the next unconsumed element when the recorder was created.
+	startPos    int
+	startOrigin Origin
+	// The recorded children, in input order.
+	children []cstRecord
+	// The coverageContext[CT] of the wrapped context, if any.
+	cov *coverageContext[CT]
+}
+
+// Returns a *cstRecorder[CT] wrapping ctx.
+func newCSTRecorder[CT any](ctx Context[CT]) *cstRecorder[CT] {
+	startPos, _ := contextPosition(ctx)
+	retainer, _ := findContext[triviaRetainer[CT]](ctx)
+	return &cstRecorder[CT]{
+		Context:     ctx,
+		retainer:    retainer,
+		startPos:    startPos,
+		startOrigin: curOriginOf(ctx),
+		children:    make([]cstRecord, 0),
+		cov:         coverageOf(ctx),
+	}
+}
+
+// Advances the input stream by num elements, returning the consumed
+// elements, and records them as a CSTToken.
+func (r *cstRecorder[CT]) Consume(num int) ([]CT, error) {
+	startPos, hasPos := contextPosition(r.Context)
+	startOrigin := r.Context.GetCurOrigin()
+	vals, err := r.Context.Consume(num)
+	if len(vals) == 0 {
+		return vals, err
+	}
+
+	if hasPos {
+		r.discardAfter(startPos)
+	}
+	tok := &CSTToken{
+		Text: rawTextOf(vals),
+		OriginRange: tokenSpan(vals, OriginRange{
+			Start: startOrigin,
+			End:   curOriginOf(r.Context),
+		}),
+	}
+	if toks, ok := any(vals).([]Token); ok && len(toks) == 1 {
+		tok.Kind = string(toks[0].Type)
+	}
+	endPos := startPos + len(vals)
+	if r.retainer != nil {
+		tok.Trivia = rawTextOf(r.retainer.triviaBetween(r.lastEnd(), startPos))
+		if r.Context.GetLookOffset() == InvalidLookOffset {
+			r.retainer.discardTriviaBefore(endPos)
+		}
+	}
+	r.children = append(r.children, cstRecord{
+		startPos: startPos,
+		endPos:   endPos,
+		child:    tok,
+	})
+	return vals, err
+}
+
+// Commits the outermost Look frame without recording the committed elements
+// again, as they were recorded when consumed in the Look frame.
+func (r *cstRecorder[CT]) commitLook(num int) error {
+	return commitLook(r.Context, num)
+}
+
+func (r *cstRecorder[CT]) coverage() *coverageContext[CT] {
+	return r.cov
+}
+
+func (r *cstRecorder[CT]) position() (int, bool) {
+	return contextPosition(r.Context)
+}
+
+func (r *cstRecorder[CT]) endOfInputOrigin() (Origin, bool) {
+	return endOfInputOriginOf(r.Context)
+}
+
+func (r *cstRecorder[CT]) openSpan() (spanStart, bool) {
+	return openSpanOf(r.Context)
+}
+
+func (r *cstRecorder[CT]) closeSpan(start spanStart) OriginRange {
+	return r.Context.(spanLogger[CT]).closeSpan(start)
+}
+
+func (r *cstRecorder[CT]) unwrap() Context[CT] {
+	return r.Context
+}
+
+// Discards recorded children that end after the position pos, as they
+// were undone by backtracking.
+func (r *cstRecorder[CT]) discardAfter(pos int) {
+	for len(r.children) > 0 && r.children[len(r.children)-1].endPos > pos {
+		r.children = r.children[:len(r.children)-1]
+	}
+}
+
+// Returns the position just past the last recorded token.
+func (r *cstRecorder[CT]) lastEnd() int {
+	if len(r.children) == 0 {
+		return r.startPos
+	}
+	return r.children[len(r.children)-1].endPos
+}
+
+// Groups the children recorded since index into a new child CSTNode of kind.
+// If no children were recorded, the CSTNode is empty and located at startOrigin.
+func (r *cstRecorder[CT]) group(kind string, index int, startOrigin Origin) *CSTNode {
+	if pos, ok := contextPosition(r.Context); ok {
+		r.discardAfter(pos)
+	}
+	if index > len(r.children) {
+		index = len(r.children)
+	}
+
+	records := r.children[index:]
+	node := newCSTNode(kind, records, startOrigin)
+	rec := cstRecord{
+		startPos: r.lastEnd(),
+		endPos:   r.lastEnd(),
+		child:    node,
+	}
+	if len(records) > 0 {
+		rec.startPos = records[0].startPos
+		rec.endPos = records[len(records)-1].endPos
+	}
+	r.children = append(r.children[:index], rec)
+	return node
+}
+
+// Returns the root CSTNode of kind holding every recorded child, with
+// the trivia after the last token.
+func (r *cstRecorder[CT]) root(kind string) *CSTNode {
+	pos, hasPos := contextPosition(r.Context)
+	if hasPos {
+		r.discardAfter(pos)
+	}
+	node := newCSTNode(kind, r.children, r.startOrigin)
+	if r.retainer != nil && hasPos {
+		node.TrailingTrivia = rawTextOf(r.retainer.triviaBetween(r.lastEnd(), pos))
+	}
+	return node
+}
+
+// Returns a *CSTNode of kind with the children of records.
+func newCSTNode(kind string, records []cstRecord, emptyOrigin Origin) *CSTNode {
+	node := &CSTNode{
+		Kind:        kind,
+		Children:    make([]CSTChild, len(records)),
+		OriginRange: OriginRange{Start: emptyOrigin, End: emptyOrigin},
+	}
+	for i, rec := range records {
+		node.Children[i] = rec.child
+	}
+	toks := node.Tokens()
+	if len(toks) > 0 {
+		node.OriginRange = OriginRange{
+			Start: toks[0].OriginRange.Start,
+			End:   toks[len(toks)-1].OriginRange.End,
+		}
+	}
+	return node
+}
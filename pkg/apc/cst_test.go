@@ -0,0 +1,88 @@
+package apc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCSTKeyValueParser() Parser[rune, *CSTNode] {
+	pair := CST("pair", Seq3(IdentifierParser, ExactStr("="), Any(IdentifierParser, Regex("[0-9]+"))))
+	return CST("file", ZeroOrMore(pair))
+}
+
+func TestCSTParserReproducesInput(t *testing.T) {
+	input := "  # header\na = 1 // trailing\n\n/* block */ b=c   \n# end\n"
+	ctx := NewStringContext(testStringOrigin, input)
+	ctx.AddSkipParser(CastToAny(CStyleWhitespaceAndCommentsParser))
+	ctx.AddSkipParser(CastToAny(HashLineCommentParser))
+
+	node, err := Parse[rune](ctx, newTestCSTKeyValueParser(), DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, input, node.String())
+
+	var buf bytes.Buffer
+	n, err := node.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(input)), n)
+	assert.Equal(t, input, buf.String())
+
+	assert.Equal(t, "file", node.Kind)
+	assert.Len(t, node.Children, 2)
+	pair := node.Children[0].(*CSTNode)
+	assert.Equal(t, "pair", pair.Kind)
+	assert.Equal(t, testOriginRange(2, 1, 2, 6), pair.OriginRange)
+	toks := pair.Tokens()
+	assert.Equal(t, "  # header\n", toks[0].Trivia)
+	assert.Equal(t, "a", toks[0].Text)
+	assert.Equal(t, " ", toks[1].Trivia)
+	assert.Equal(t, "=", toks[1].Text)
+	assert.Equal(t, " // trailing\n\n/* block */ ", node.Children[1].(*CSTNode).Tokens()[0].Trivia)
+	assert.Equal(t, "   \n# end\n", node.TrailingTrivia)
+}
+
+func TestCSTParserBacktracking(t *testing.T) {
+	input := "a b  c"
+	ctx := NewStringContext(testStringOrigin, input)
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	p := CST("root", Seq2(
+		CST("a", ExactStr("a")),
+		Any(
+			Look(CST("bad", Seq2(ExactStr("b"), ExactStr("x")))),
+			CST("good", Seq2(ExactStr("b"), ExactStr("c"))),
+		),
+	))
+
+	node, err := Parse[rune](ctx, p, DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, input, node.String())
+	assert.Len(t, node.Children, 2)
+	assert.Equal(t, "good", node.Children[1].(*CSTNode).Kind)
+	toks := node.Tokens()
+	assert.Len(t, toks, 3)
+	assert.Equal(t, "  ", toks[2].Trivia)
+}
+
+func TestCSTParserEmptyInput(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, " \n ")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+
+	node, err := Parse[rune](ctx, newTestCSTKeyValueParser(), DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Empty(t, node.Children)
+	assert.Equal(t, " \n ", node.String())
+}
+
+func TestContextWrappersForwardPosition(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "ab")
+	wrapped := newConsumeRecorder[rune](newCSTRecorder(WithCoverage[rune](ctx, NewCoverage())), false)
+	_, err := wrapped.Consume(1)
+	assert.NoError(t, err)
+	pos, ok := contextPosition[rune](wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, 1, pos)
+
+	_, _ = wrapped.Consume(1)
+	assert.Equal(t, Origin{Name: testStringOrigin, LineNum: 1, ColNum: 3}, curOriginOf[rune](wrapped))
+}
@@ -0,0 +1,257 @@
+package apc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The character encoding of input decoded to runes.
+type Encoding int
+
+const (
+	EncodingUTF8 Encoding = iota
+	EncodingUTF16LE
+	EncodingUTF16BE
+	// ISO-8859-1: each byte is the rune of the same value.
+	EncodingLatin1
+	// Like Latin-1, but the bytes 0x80 to 0x9F are the punctuation and letters of
+	// Windows code page 1252. The five bytes it leaves undefined are the rune of the same value.
+	EncodingWindows1252
+)
+
+// Returns the name of the Encoding.
+func (encoding Encoding) String() string {
+	switch encoding {
+	case EncodingUTF8:
+		return "UTF-8"
+	case EncodingUTF16LE:
+		return "UTF-16LE"
+	case EncodingUTF16BE:
+		return "UTF-16BE"
+	case EncodingLatin1:
+		return "ISO-8859-1"
+	case EncodingWindows1252:
+		return "windows-1252"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(encoding))
+	}
+}
+
+// DecodeConfig contains settings for decoding input to runes.
+type DecodeConfig struct {
+	// The encoding of the input, unless it begins with a detected byte order mark.
+	Encoding Encoding
+	// If true, a UTF-8 or UTF-16 byte order mark at the start of the input selects
+	// its encoding and is not decoded as a rune.
+	DetectBOM bool
+	// If true, invalid input, such as invalid UTF-8 or an unpaired UTF-16 surrogate,
+	// is a *DecodeError. Otherwise, it is decoded as utf8.RuneError.
+	Strict bool
+}
+
+// A sane default for DecodeConfig, decoding UTF-8 unless a byte order mark says
+// otherwise, and decoding invalid input as utf8.RuneError.
+var DefaultDecodeConfig = DecodeConfig{
+	Encoding:  EncodingUTF8,
+	DetectBOM: true,
+	Strict:    false,
+}
+
+// DecodeError represents input that is invalid in its encoding, when decoding strictly.
+// A RuneReaderWithOrigin returns it wrapped in a ParseErrorConsumed at the Origin
+// of the invalid input, so that parsers cannot backtrack over it.
+type DecodeError struct {
+	Encoding Encoding
+	// The offset of the invalid input in bytes, from the start of the input
+	// (including any byte order mark).
+	Offset int64
+	// The invalid bytes.
+	Bytes []byte
+}
+
+// The error string.
+func (err *DecodeError) Error() string {
+	return fmt.Sprintf("invalid %v at byte offset %v: % x", err.Encoding, err.Offset, err.Bytes)
+}
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// The runes of the bytes 0x80 to 0x9F in Windows code page 1252.
+var windows1252Runes = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// DecodingRuneReader implements io.RuneReader by decoding the bytes of a reader
+// according to a DecodeConfig.
+type DecodingRuneReader struct {
+	reader   *bufio.Reader
+	config   DecodeConfig
+	encoding Encoding
+	// The offset in bytes of the next byte of reader.
+	offset     int64
+	checkedBOM bool
+	// The error returned by every read once reading fails.
+	err error
+}
+
+// Returns a *DecodingRuneReader decoding the bytes of reader according to config.
+func NewDecodingRuneReader(reader io.Reader, config DecodeConfig) *DecodingRuneReader {
+	return &DecodingRuneReader{
+		reader:   bufio.NewReader(reader),
+		config:   config,
+		encoding: config.Encoding,
+	}
+}
+
+// Returns the encoding being decoded, which is known once the first rune has been read.
+func (r *DecodingRuneReader) Encoding() Encoding {
+	return r.encoding
+}
+
+// Returns the next decoded rune and the number of bytes it was decoded from.
+// Once an error occurs, including io.EOF or a *DecodeError, it is returned by every
+// following call.
+func (r *DecodingRuneReader) ReadRune() (rune, int, error) {
+	if r.err != nil {
+		return utf8.RuneError, 0, r.err
+	}
+	if !r.checkedBOM {
+		r.checkedBOM = true
+		if r.config.DetectBOM {
+			r.detectBOM()
+		}
+	}
+
+	rn, size, invalid, err := r.decode()
+	if err == nil && invalid != nil && r.config.Strict {
+		err = &DecodeError{Encoding: r.encoding, Offset: r.offset, Bytes: invalid}
+	}
+	if err != nil {
+		r.err = err
+		return utf8.RuneError, 0, err
+	}
+	r.offset += int64(size)
+	return rn, size, nil
+}
+
+func (r *DecodingRuneReader) detectBOM() {
+	start, _ := r.reader.Peek(3)
+	for _, bom := range []struct {
+		bytes    []byte
+		encoding Encoding
+	}{
+		{bomUTF8, EncodingUTF8},
+		{bomUTF16LE, EncodingUTF16LE},
+		{bomUTF16BE, EncodingUTF16BE},
+	} {
+		if bytes.HasPrefix(start, bom.bytes) {
+			r.encoding = bom.encoding
+			r.reader.Discard(len(bom.bytes))
+			r.offset += int64(len(bom.bytes))
+			return
+		}
+	}
+}
+
+// Decodes the next rune, returning it and the number of bytes it was decoded from.
+// If the bytes are invalid, they are also returned, and the rune is utf8.RuneError.
+func (r *DecodingRuneReader) decode() (rn rune, size int, invalid []byte, err error) {
+	switch r.encoding {
+	case EncodingUTF16LE, EncodingUTF16BE:
+		return r.decodeUTF16()
+	case EncodingLatin1, EncodingWindows1252:
+		b, err := r.reader.ReadByte()
+		if err != nil {
+			return utf8.RuneError, 0, nil, err
+		}
+		if r.encoding == EncodingWindows1252 && b >= 0x80 && b <= 0x9F {
+			return windows1252Runes[b-0x80], 1, nil, nil
+		}
+		return rune(b), 1, nil, nil
+	default:
+		rn, size, err := r.reader.ReadRune()
+		if err != nil {
+			return utf8.RuneError, 0, nil, err
+		}
+		if rn == utf8.RuneError && size == 1 {
+			r.reader.UnreadRune()
+			b, _ := r.reader.ReadByte()
+			return utf8.RuneError, 1, []byte{b}, nil
+		}
+		return rn, size, nil, nil
+	}
+}
+
+// Reads the next UTF-16 code unit, returning a truncated unit as invalid.
+func (r *DecodingRuneReader) readUTF16Unit() (uint16, []byte, error) {
+	buf := make([]byte, 2)
+	n, err := io.ReadFull(r.reader, buf)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, buf[:n], nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if r.encoding == EncodingUTF16LE {
+		return uint16(buf[0]) | uint16(buf[1])<<8, nil, nil
+	}
+	return uint16(buf[0])<<8 | uint16(buf[1]), nil, nil
+}
+
+func (r *DecodingRuneReader) decodeUTF16() (rune, int, []byte, error) {
+	unit, truncated, err := r.readUTF16Unit()
+	if err != nil {
+		return utf8.RuneError, 0, nil, err
+	}
+	if truncated != nil {
+		return utf8.RuneError, len(truncated), truncated, nil
+	}
+	if !utf16.IsSurrogate(rune(unit)) {
+		return rune(unit), 2, nil, nil
+	}
+
+	invalid := []byte{byte(unit >> 8), byte(unit)}
+	if r.encoding == EncodingUTF16LE {
+		invalid[0], invalid[1] = invalid[1], invalid[0]
+	}
+	if unit >= 0xDC00 {
+		// A low surrogate without a high surrogate before it
+		return utf8.RuneError, 2, invalid, nil
+	}
+	next, err := r.reader.Peek(2)
+	if len(next) == 2 {
+		low := uint16(next[0])<<8 | uint16(next[1])
+		if r.encoding == EncodingUTF16LE {
+			low = uint16(next[0]) | uint16(next[1])<<8
+		}
+		if rn := utf16.DecodeRune(rune(unit), rune(low)); rn != utf8.RuneError {
+			r.reader.Discard(2)
+			return rn, 4, nil, nil
+		}
+	} else if err != nil && !errors.Is(err, io.EOF) {
+		return utf8.RuneError, 0, nil, err
+	}
+	// A high surrogate without a low surrogate after it
+	return utf8.RuneError, 2, invalid, nil
+}
+
+// Returns a *ReaderContext[rune] from an io.Reader of bytes decoded according to config.
+func NewDecodingContext(originName string, reader io.Reader, config DecodeConfig) *ReaderContext[rune] {
+	return NewRuneReaderContext(originName, NewDecodingRuneReader(reader, config))
+}
+
+// Returns a *ReaderContext[rune] from a file decoded according to config.
+func NewDecodingFileContext(file *os.File, config DecodeConfig) *ReaderContext[rune] {
+	return NewDecodingContext(file.Name(), file, config)
+}
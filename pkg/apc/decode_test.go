@@ -0,0 +1,113 @@
+package apc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the runes decoded from input, and the error that ended decoding.
+func decodeAll(input []byte, config DecodeConfig) (string, error) {
+	reader := NewDecodingRuneReader(bytes.NewReader(input), config)
+	var sb strings.Builder
+	for {
+		rn, _, err := reader.ReadRune()
+		if err != nil {
+			return sb.String(), err
+		}
+		sb.WriteRune(rn)
+	}
+}
+
+func TestDecodeUTF8(t *testing.T) {
+	text, err := decodeAll([]byte("héllo"), DefaultDecodeConfig)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, "héllo", text)
+
+	text, _ = decodeAll([]byte("\xEF\xBB\xBFhi"), DefaultDecodeConfig)
+	assert.Equal(t, "hi", text)
+	text, _ = decodeAll([]byte("\xEF\xBB\xBFhi"), DecodeConfig{})
+	assert.Equal(t, "\uFEFFhi", text)
+
+	text, err = decodeAll([]byte("a\xffb"), DefaultDecodeConfig)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, "a"+string(utf8.RuneError)+"b", text)
+}
+
+func TestDecodeUTF16(t *testing.T) {
+	// "a😀" with byte order marks
+	text, err := decodeAll([]byte{0xFF, 0xFE, 'a', 0, 0x3D, 0xD8, 0x00, 0xDE}, DefaultDecodeConfig)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, "a😀", text)
+	text, _ = decodeAll([]byte{0xFE, 0xFF, 0, 'a', 0xD8, 0x3D, 0xDE, 0x00}, DefaultDecodeConfig)
+	assert.Equal(t, "a😀", text)
+	// Without a byte order mark
+	text, _ = decodeAll([]byte{0, 'a', 0, 'b'}, DecodeConfig{Encoding: EncodingUTF16BE})
+	assert.Equal(t, "ab", text)
+
+	// Unpaired surrogates and a truncated code unit
+	text, err = decodeAll([]byte{0x3D, 0xD8, 'a', 0, 0x00, 0xDE, 'b'}, DecodeConfig{Encoding: EncodingUTF16LE})
+	assert.ErrorIs(t, err, io.EOF)
+	bad := string(utf8.RuneError)
+	assert.Equal(t, bad+"a"+bad+bad, text)
+}
+
+func TestDecodeLatin1AndWindows1252(t *testing.T) {
+	input := []byte{'c', 0xE9, 0x80, 0x93, 0x81}
+	text, _ := decodeAll(input, DecodeConfig{Encoding: EncodingLatin1})
+	assert.Equal(t, "cé\u0080\u0093\u0081", text)
+	text, _ = decodeAll(input, DecodeConfig{Encoding: EncodingWindows1252})
+	assert.Equal(t, "cé€“\u0081", text)
+	// A byte order mark overrides the configured encoding
+	text, _ = decodeAll([]byte("\xEF\xBB\xBFé"), DecodeConfig{Encoding: EncodingLatin1, DetectBOM: true})
+	assert.Equal(t, "é", text)
+}
+
+func TestDecodeStrict(t *testing.T) {
+	config := DecodeConfig{Encoding: EncodingUTF8, DetectBOM: true, Strict: true}
+	text, err := decodeAll([]byte("\xEF\xBB\xBFab\xC3("), config)
+	assert.Equal(t, "ab", text)
+	var decodeErr *DecodeError
+	if assert.True(t, errors.As(err, &decodeErr)) {
+		assert.Equal(t, &DecodeError{Encoding: EncodingUTF8, Offset: 5, Bytes: []byte{0xC3}}, decodeErr)
+		assert.Equal(t, "invalid UTF-8 at byte offset 5: c3", err.Error())
+	}
+
+	_, err = decodeAll([]byte{'a', 0, 0x00, 0xDC}, DecodeConfig{Encoding: EncodingUTF16LE, Strict: true})
+	assert.EqualError(t, err, "invalid UTF-16LE at byte offset 2: 00 dc")
+}
+
+func TestDecodingContextStrictParseError(t *testing.T) {
+	ctx := NewDecodingContext("test", bytes.NewReader([]byte("ab\ncd\xffe")),
+		DecodeConfig{Encoding: EncodingUTF8, Strict: true})
+	_, err := Parse[rune](ctx, Regex("[a-z\\n]+"), DefaultParseConfig)
+	var perr *ParseErrorConsumed
+	if assert.True(t, errors.As(err, &perr)) {
+		assert.Equal(t, Origin{Name: "test", LineNum: 2, ColNum: 3}, perr.Origin)
+	}
+	var decodeErr *DecodeError
+	if assert.True(t, errors.As(err, &decodeErr)) {
+		assert.Equal(t, int64(5), decodeErr.Offset)
+	}
+
+	// Alternatives are not tried after invalid input
+	ctx = NewDecodingContext("t", bytes.NewReader([]byte("a\xffb")), DecodeConfig{Encoding: EncodingUTF8, Strict: true})
+	_, err = Parse[rune](ctx, Seq(ExactStr("a"), Any(ExactStr("b"), ExactStr("c"))), DefaultParseConfig)
+	assert.EqualError(t, err, "Parse Error (cannot backtrack) at t:1:2: could not decode input\ninvalid UTF-8 at byte offset 1: ff")
+
+	// Without strict mode, the invalid byte is parsed as utf8.RuneError
+	ctx = NewDecodingContext("test", bytes.NewReader([]byte("ab\xff")), DefaultDecodeConfig)
+	node, err := Parse[rune](ctx, Regex("ab."), DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, "ab"+string(utf8.RuneError), node)
+}
+
+func TestEncodingString(t *testing.T) {
+	assert.Equal(t, "windows-1252", EncodingWindows1252.String())
+	assert.Equal(t, "Encoding(9)", Encoding(9).String())
+}
@@ -0,0 +1,170 @@
+package apc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The severity of a Diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+	SeverityHint
+)
+
+// Returns the lowercase name of the severity, such as "error".
+func (severity Severity) String() string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	default:
+		return fmt.Sprintf("severity(%d)", int(severity))
+	}
+}
+
+// A problem found in some input, such as a parse error, in a form suited to
+// reporting by tools.
+type Diagnostic struct {
+	Severity Severity
+	// The message, without the location.
+	Message string
+	// The input the diagnostic applies to. Start and End are the same if only
+	// the Origin is known.
+	OriginRange OriginRange
+	// The set of descriptions of what was expected, such as the names of parsers.
+	Expected []string
+	// The names of the Named parsers the error and those it wraps were returned
+	// through, innermost first.
+	ParserNames []string
+	// Other locations related to the diagnostic, such as the causes of a parse error.
+	Related []DiagnosticRelated
+}
+
+// A location related to a Diagnostic.
+type DiagnosticRelated struct {
+	Message     string
+	OriginRange OriginRange
+}
+
+// Returns the diagnostic in the format "<range>: <severity>: <message>".
+func (diag Diagnostic) String() string {
+	return fmt.Sprintf("%v: %v: %v", diagnosticLocationString(diag.OriginRange), diag.Severity, diag.Message)
+}
+
+func diagnosticLocationString(orgRange OriginRange) string {
+	if orgRange.Start == orgRange.End {
+		return orgRange.Start.String()
+	}
+	return orgRange.String()
+}
+
+// Returns err as a Diagnostic of SeverityError. ParseError and ParseErrorConsumed
+// are converted by their Diagnostic method; other errors have only a message.
+func NewDiagnostic(err error) Diagnostic {
+	// Convert the outermost parse error, if err wraps one
+	for perr := err; perr != nil; perr = errors.Unwrap(perr) {
+		switch perr := perr.(type) {
+		case *ParseError:
+			return perr.Diagnostic()
+		case *ParseErrorConsumed:
+			return perr.Diagnostic()
+		}
+	}
+	return Diagnostic{
+		Severity: SeverityError,
+		Message:  err.Error(),
+	}
+}
+
+// Returns the error as a Diagnostic of SeverityError. Each error wrapped by Err becomes
+// a related location, and those at the same Origin add to the expected set.
+func (err *ParseError) Diagnostic() Diagnostic {
+	return newParseErrorDiagnostic(err.Message, err.Origin, err.OriginRange, err.Expected, err.ParserNames, err.Err)
+}
+
+// Returns the error as a Diagnostic of SeverityError. Each error wrapped by Err becomes
+// a related location, and those at the same Origin add to the expected set.
+func (err *ParseErrorConsumed) Diagnostic() Diagnostic {
+	return newParseErrorDiagnostic(err.Message, err.Origin, err.OriginRange, err.Expected, err.ParserNames, err.Err)
+}
+
+// Returns the OriginRange of a parse error, or the empty range at its Origin if unknown.
+func parseErrorRange(origin Origin, orgRange OriginRange) OriginRange {
+	if orgRange.IsZero() {
+		return OriginRange{Start: origin, End: origin}
+	}
+	return orgRange
+}
+
+func newParseErrorDiagnostic(message string, origin Origin, orgRange OriginRange, expected string,
+	parserNames []string, wrapErr error) Diagnostic {
+	diag := Diagnostic{
+		Severity:    SeverityError,
+		Message:     message,
+		OriginRange: parseErrorRange(origin, orgRange),
+	}
+	// The Named parsers of wrapped errors were returned through before those of the error
+	nameLevels := [][]string{parserNames}
+	addExpected := func(exp string) {
+		if exp == "" {
+			return
+		}
+		for _, other := range diag.Expected {
+			if other == exp {
+				return
+			}
+		}
+		diag.Expected = append(diag.Expected, exp)
+	}
+	addExpected(expected)
+
+	for err := wrapErr; err != nil; {
+		switch perr := err.(type) {
+		case *ParseError:
+			diag.Related = append(diag.Related, DiagnosticRelated{
+				Message:     perr.Message,
+				OriginRange: parseErrorRange(perr.Origin, perr.OriginRange),
+			})
+			if perr.Origin == origin {
+				addExpected(perr.Expected)
+			}
+			nameLevels = append(nameLevels, perr.ParserNames)
+			err = perr.Err
+		case *ParseErrorConsumed:
+			diag.Related = append(diag.Related, DiagnosticRelated{
+				Message:     perr.Message,
+				OriginRange: parseErrorRange(perr.Origin, perr.OriginRange),
+			})
+			if perr.Origin == origin {
+				addExpected(perr.Expected)
+			}
+			nameLevels = append(nameLevels, perr.ParserNames)
+			err = perr.Err
+		default:
+			// The message of other errors includes those they wrap
+			diag.Related = append(diag.Related, DiagnosticRelated{
+				Message: err.Error(),
+			})
+			err = nil
+		}
+	}
+	for _, from := range diag.OriginRange.Start.IncludeChain() {
+		diag.Related = append(diag.Related, DiagnosticRelated{
+			Message:     "included from here",
+			OriginRange: OriginRange{Start: from, End: from},
+		})
+	}
+	for i := len(nameLevels) - 1; i >= 0; i-- {
+		diag.ParserNames = append(diag.ParserNames, nameLevels[i]...)
+	}
+	return diag
+}
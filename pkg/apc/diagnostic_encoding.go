@@ -0,0 +1,224 @@
+package apc
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type jsonDiagnosticRange struct {
+	Name      string `json:"name"`
+	StartLine int    `json:"startLine"`
+	StartCol  int    `json:"startCol"`
+	EndLine   int    `json:"endLine"`
+	EndCol    int    `json:"endCol"`
+}
+
+type jsonDiagnosticRelated struct {
+	Message string               `json:"message"`
+	Range   *jsonDiagnosticRange `json:"range,omitempty"`
+}
+
+type jsonDiagnostic struct {
+	Severity    string                  `json:"severity"`
+	Message     string                  `json:"message"`
+	Range       *jsonDiagnosticRange    `json:"range,omitempty"`
+	Expected    []string                `json:"expected,omitempty"`
+	ParserNames []string                `json:"parserNames,omitempty"`
+	Related     []jsonDiagnosticRelated `json:"related,omitempty"`
+}
+
+// Returns nil if the range is unknown.
+func newJSONDiagnosticRange(orgRange OriginRange) *jsonDiagnosticRange {
+	if orgRange.IsZero() {
+		return nil
+	}
+	return &jsonDiagnosticRange{
+		Name:      orgRange.Start.Name,
+		StartLine: orgRange.Start.LineNum,
+		StartCol:  orgRange.Start.ColNum,
+		EndLine:   orgRange.End.LineNum,
+		EndCol:    orgRange.End.ColNum,
+	}
+}
+
+// Writes each diagnostic to w as a single line of JSON, in the form:
+//
+//	{"severity":"error","message":"...","range":{"name":"a.conf","startLine":1,"startCol":5,"endLine":1,"endCol":5},
+//	 "expected":["..."],"parserNames":["..."],"related":[{"message":"...","range":{...}}]}
+//
+// Unknown ranges and empty lists are omitted.
+func WriteDiagnosticsJSONLines(w io.Writer, diags []Diagnostic) error {
+	enc := json.NewEncoder(w)
+	for _, diag := range diags {
+		jdiag := jsonDiagnostic{
+			Severity:    diag.Severity.String(),
+			Message:     diag.Message,
+			Range:       newJSONDiagnosticRange(diag.OriginRange),
+			Expected:    diag.Expected,
+			ParserNames: diag.ParserNames,
+		}
+		for _, related := range diag.Related {
+			jdiag.Related = append(jdiag.Related, jsonDiagnosticRelated{
+				Message: related.Message,
+				Range:   newJSONDiagnosticRange(related.OriginRange),
+			})
+		}
+		if err := enc.Encode(jdiag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The rule of all results in SARIF logs written by WriteDiagnosticsSARIF.
+const sarifRuleID = "parse-error"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string           `json:"ruleId"`
+	Level            string           `json:"level"`
+	Message          sarifMessage     `json:"message"`
+	Locations        []sarifLocation  `json:"locations,omitempty"`
+	RelatedLocations []sarifLocation  `json:"relatedLocations,omitempty"`
+	Properties       *sarifProperties `json:"properties,omitempty"`
+}
+
+type sarifProperties struct {
+	Expected    []string `json:"expected,omitempty"`
+	ParserNames []string `json:"parserNames,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               *int                   `json:"id,omitempty"`
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	Message          *sarifMessage          `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// Returns the SARIF level of a Severity.
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// Returns nil if the range is unknown.
+func newSARIFPhysicalLocation(orgRange OriginRange) *sarifPhysicalLocation {
+	if orgRange.IsZero() {
+		return nil
+	}
+	loc := &sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: orgRange.Start.Name},
+	}
+	if orgRange.Start.LineNum > 0 {
+		loc.Region = &sarifRegion{
+			StartLine:   orgRange.Start.LineNum,
+			StartColumn: orgRange.Start.ColNum,
+			EndLine:     orgRange.End.LineNum,
+			EndColumn:   orgRange.End.ColNum,
+		}
+	}
+	return loc
+}
+
+// Writes the diagnostics to w as a SARIF 2.1.0 log with a single run of the tool
+// named toolName. The Name of each Origin is used as the URI of its artifact, and
+// columns are counted in Unicode code points.
+func WriteDiagnosticsSARIF(w io.Writer, toolName string, diags []Diagnostic) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name: toolName,
+				Rules: []sarifRule{{
+					ID:               sarifRuleID,
+					ShortDescription: sarifMessage{Text: "The input could not be parsed."},
+				}},
+			},
+		},
+		ColumnKind: "unicodeCodePoints",
+		Results:    make([]sarifResult, 0, len(diags)),
+	}
+
+	for _, diag := range diags {
+		result := sarifResult{
+			RuleID:  sarifRuleID,
+			Level:   sarifLevel(diag.Severity),
+			Message: sarifMessage{Text: diag.Message},
+		}
+		if loc := newSARIFPhysicalLocation(diag.OriginRange); loc != nil {
+			result.Locations = []sarifLocation{{PhysicalLocation: loc}}
+		}
+		for i, related := range diag.Related {
+			id := i
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+				ID:               &id,
+				PhysicalLocation: newSARIFPhysicalLocation(related.OriginRange),
+				Message:          &sarifMessage{Text: related.Message},
+			})
+		}
+		if len(diag.Expected) > 0 || len(diag.ParserNames) > 0 {
+			result.Properties = &sarifProperties{
+				Expected:    diag.Expected,
+				ParserNames: diag.ParserNames,
+			}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,71 @@
+package apc
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func diagnosticEncodingTestDiagnostics() []Diagnostic {
+	start := Origin{Name: "a.conf", LineNum: 1, ColNum: 6}
+	end := Origin{Name: "a.conf", LineNum: 1, ColNum: 8}
+	return []Diagnostic{
+		{
+			Severity:    SeverityError,
+			Message:     "expected pair but got x",
+			OriginRange: OriginRange{Start: start, End: end},
+			Expected:    []string{"pair"},
+			ParserNames: []string{"pair"},
+			Related: []DiagnosticRelated{
+				{Message: "expected value but got x", OriginRange: OriginRange{Start: start, End: start}},
+			},
+		},
+		{
+			Severity: SeverityWarning,
+			Message:  "unknown location",
+		},
+	}
+}
+
+func TestWriteDiagnosticsJSONLines(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteDiagnosticsJSONLines(&out, diagnosticEncodingTestDiagnostics()))
+	assert.Equal(t, `{"severity":"error","message":"expected pair but got x",`+
+		`"range":{"name":"a.conf","startLine":1,"startCol":6,"endLine":1,"endCol":8},"expected":["pair"],"parserNames":["pair"],`+
+		`"related":[{"message":"expected value but got x","range":{"name":"a.conf","startLine":1,"startCol":6,"endLine":1,"endCol":6}}]}
+{"severity":"warning","message":"unknown location"}
+`, out.String())
+}
+
+func TestWriteDiagnosticsSARIF(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteDiagnosticsSARIF(&out, "checker", diagnosticEncodingTestDiagnostics()))
+
+	var log map[string]any
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log["version"])
+	run := log["runs"].([]any)[0].(map[string]any)
+	assert.Equal(t, "checker", run["tool"].(map[string]any)["driver"].(map[string]any)["name"])
+	results := run["results"].([]any)
+	assert.Len(t, results, 2)
+
+	first := results[0].(map[string]any)
+	assert.Equal(t, "error", first["level"])
+	assert.Equal(t, "parse-error", first["ruleId"])
+	assert.Equal(t, map[string]any{
+		"physicalLocation": map[string]any{
+			"artifactLocation": map[string]any{"uri": "a.conf"},
+			"region":           map[string]any{"startLine": 1.0, "startColumn": 6.0, "endLine": 1.0, "endColumn": 8.0},
+		},
+	}, first["locations"].([]any)[0])
+	related := first["relatedLocations"].([]any)[0].(map[string]any)
+	assert.Equal(t, 0.0, related["id"])
+	assert.Equal(t, "expected value but got x", related["message"].(map[string]any)["text"])
+	assert.Equal(t, map[string]any{"expected": []any{"pair"}, "parserNames": []any{"pair"}}, first["properties"])
+
+	second := results[1].(map[string]any)
+	assert.Equal(t, "warning", second["level"])
+	assert.NotContains(t, second, "locations")
+}
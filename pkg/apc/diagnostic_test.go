@@ -0,0 +1,63 @@
+package apc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func diagnosticTestParser() Parser[rune, []any] {
+	return Named("pair", Seq(
+		CastToAny(Named("key", Regex("[a-z]+"))),
+		CastToAny(ExactStr("=")),
+		CastToAny(Named("value", Regex("[0-9]+"))),
+	))
+}
+
+func TestParseErrorDiagnostic(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "ab = x")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	_, err := Parse[rune](ctx, diagnosticTestParser(), DefaultParseConfig)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+
+	at := Origin{Name: testStringOrigin, LineNum: 1, ColNum: 6}
+	diag := NewDiagnostic(err)
+	assert.Equal(t, Diagnostic{
+		Severity:    SeverityError,
+		Message:     "expected pair but got x",
+		OriginRange: OriginRange{Start: at, End: at},
+		Expected:    []string{"pair", "value"},
+		ParserNames: []string{"value", "pair"},
+		Related: []DiagnosticRelated{
+			{Message: "expected value but got x", OriginRange: OriginRange{Start: at, End: at}},
+		},
+	}, diag)
+	assert.Equal(t, "<origin>:1:6: error: expected pair but got x", diag.String())
+
+	// Wrapping the error does not change its diagnostic
+	assert.Equal(t, diag, NewDiagnostic(fmt.Errorf("checking: %w", err)))
+}
+
+func TestParseErrorDiagnosticRange(t *testing.T) {
+	start := Origin{Name: "a.conf", LineNum: 2, ColNum: 3}
+	end := Origin{Name: "a.conf", LineNum: 2, ColNum: 7}
+	err := &ParseError{
+		Message:     "expected value but got token",
+		Origin:      start,
+		OriginRange: OriginRange{Start: start, End: end},
+		Expected:    "value",
+		Err:         fmt.Errorf("bad token"),
+	}
+	diag := err.Diagnostic()
+	assert.Equal(t, OriginRange{Start: start, End: end}, diag.OriginRange)
+	assert.Equal(t, []string{"value"}, diag.Expected)
+	assert.Equal(t, []DiagnosticRelated{{Message: "bad token"}}, diag.Related)
+	assert.Equal(t, "a.conf:2:3-2:7: error: expected value but got token", diag.String())
+}
+
+func TestErrorDiagnostic(t *testing.T) {
+	diag := NewDiagnostic(fmt.Errorf("cannot read input"))
+	assert.Equal(t, Diagnostic{Severity: SeverityError, Message: "cannot read input"}, diag)
+	assert.Equal(t, "warning", SeverityWarning.String())
+}
@@ -22,6 +22,29 @@ func IsMustReturnParseErr(err error) bool {
 	return true
 }
 
+// Returns a copy of err recording that it was returned through the Named parser
+// with the given name, if err is a ParseError or ParseErrorConsumed; otherwise
+// returns err. err itself is not modified, as it may be shared, such as by a
+// parser that returns the same error each time.
+func withParserName(err error, name string) error {
+	switch perr := err.(type) {
+	case *ParseError:
+		named := *perr
+		named.ParserNames = appendParserName(perr.ParserNames, name)
+		return &named
+	case *ParseErrorConsumed:
+		named := *perr
+		named.ParserNames = appendParserName(perr.ParserNames, name)
+		return &named
+	}
+	return err
+}
+
+// Returns a copy of names with name appended, never sharing the array of names.
+func appendParserName(names []string, name string) []string {
+	return append(names[:len(names):len(names)], name)
+}
+
 // EOFError represents that the end of a file or input has been reached.
 type EOFError struct{}
 
@@ -38,6 +61,29 @@ func (err *EOFError) Is(target error) bool {
 	return false
 }
 
+// Returns the OriginRange of got if it is a Token (or a slice of a single Token)
+// with a known OriginRange; otherwise returns the zero OriginRange.
+func originRangeOfGot(got interface{}) OriginRange {
+	switch val := got.(type) {
+	case Token:
+		return val.OriginRange
+	case []Token:
+		if len(val) == 1 {
+			return val[0].OriginRange
+		}
+	}
+	return OriginRange{}
+}
+
+// Returns the location string used in error messages: the OriginRange if
+// it is known, otherwise the Origin.
+func errLocationString(origin Origin, orgRange OriginRange) string {
+	if orgRange.IsZero() {
+		return origin.String()
+	}
+	return orgRange.String()
+}
+
 // ParseError represents a parser that could not match input and has
 // NOT consumed any input.
 type ParseError struct {
@@ -47,14 +93,23 @@ type ParseError struct {
 	Message string
 	// The Origin of the error.
 	Origin Origin
+	// The optional OriginRange of the input that caused the error,
+	// such as the span of an unexpected Token.
+	OriginRange OriginRange
+	// The optional description of what was expected instead, such as the name of a parser.
+	Expected string
+	// The names of the Named parsers the error was returned through, innermost first.
+	ParserNames []string
 }
 
 // Returns a ParseError with an error message in the format of "expected but got".
 func ParseErrExpectedButGot[CT any](ctx Context[CT], expected interface{}, got interface{}, wrapErr error) *ParseError {
 	return &ParseError{
-		Err:     wrapErr,
-		Message: fmt.Sprintf("expected %v but got %v", interfaceToErrString(expected), interfaceToErrString(got)),
-		Origin:  ctx.GetCurOrigin(),
+		Err:         wrapErr,
+		Message:     fmt.Sprintf("expected %v but got %v", interfaceToErrString(expected), interfaceToErrString(got)),
+		Origin:      ctx.GetCurOrigin(),
+		OriginRange: originRangeOfGot(got),
+		Expected:    interfaceToErrString(expected),
 	}
 }
 
@@ -68,9 +123,11 @@ func ParseErrExpectedButGotNext[CT any](ctx Context[CT], expected interface{}, w
 // The error string.
 func (err *ParseError) Error() string {
 	if err.Err == nil {
-		return fmt.Sprintf("Parse Error at %v: %v", err.Origin, err.Message)
+		return fmt.Sprintf("Parse Error at %v: %v%v", errLocationString(err.Origin, err.OriginRange), err.Message,
+			includeStackString(err.Origin))
 	}
-	return fmt.Sprintf("Parse Error at %v: %v\n%v", err.Origin, err.Message, err.Err)
+	return fmt.Sprintf("Parse Error at %v: %v%v\n%v", errLocationString(err.Origin, err.OriginRange), err.Message,
+		includeStackString(err.Origin), err.Err)
 }
 
 // Unwraps this error.
@@ -95,13 +152,22 @@ type ParseErrorConsumed struct {
 	Message string
 	// The Origin of the error.
 	Origin Origin
+	// The optional OriginRange of the input that caused the error,
+	// such as the span of an unexpected Token.
+	OriginRange OriginRange
+	// The optional description of what was expected instead, such as the name of a parser.
+	Expected string
+	// The names of the Named parsers the error was returned through, innermost first.
+	ParserNames []string
 }
 
 func ParseErrConsumedExpectedButGot[CT any](ctx Context[CT], expected interface{}, got interface{}, wrapErr error) *ParseErrorConsumed {
 	return &ParseErrorConsumed{
-		Err:     wrapErr,
-		Message: fmt.Sprintf("expected %v but got %v", interfaceToErrString(expected), interfaceToErrString(got)),
-		Origin:  ctx.GetCurOrigin(),
+		Err:         wrapErr,
+		Message:     fmt.Sprintf("expected %v but got %v", interfaceToErrString(expected), interfaceToErrString(got)),
+		Origin:      ctx.GetCurOrigin(),
+		OriginRange: originRangeOfGot(got),
+		Expected:    interfaceToErrString(expected),
 	}
 }
 
@@ -115,9 +181,11 @@ func ParseErrConsumedExpectedButGotNext[CT any](ctx Context[CT], expected interf
 // The error string.
 func (err *ParseErrorConsumed) Error() string {
 	if err.Err == nil {
-		return fmt.Sprintf("Parse Error (cannot backtrack) at %v: %v", err.Origin, err.Message)
+		return fmt.Sprintf("Parse Error (cannot backtrack) at %v: %v%v", errLocationString(err.Origin, err.OriginRange), err.Message,
+			includeStackString(err.Origin))
 	}
-	return fmt.Sprintf("Parse Error (cannot backtrack) at %v: %v\n%v", err.Origin, err.Message, err.Err)
+	return fmt.Sprintf("Parse Error (cannot backtrack) at %v: %v%v\n%v", errLocationString(err.Origin, err.OriginRange), err.Message,
+		includeStackString(err.Origin), err.Err)
 }
 
 // Unwraps this error.
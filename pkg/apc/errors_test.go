@@ -35,3 +35,24 @@ func TestParseErrorConsumedWrapsTypesIs(t *testing.T) {
 	assert.False(t, IsMustReturnParseErr(pe))
 	assert.True(t, IsMustReturnParseErr(pec))
 }
+
+func TestParserNamesOfSharedErrors(t *testing.T) {
+	shared := &ParseError{Message: "shared", ParserNames: make([]string, 1, 4)}
+	failing := func(ctx Context[rune]) (any, error) {
+		return nil, shared
+	}
+	ctx := NewStringContext(testStringOrigin, "")
+
+	_, first := Named("a", failing)(ctx)
+	_, second := Named("b", Named("c", failing))(ctx)
+	assert.Equal(t, []string{"", "a"}, first.(*ParseError).ParserNames)
+	assert.Equal(t, []string{"", "c", "b"}, second.(*ParseError).ParserNames)
+	assert.Equal(t, []string{""}, shared.ParserNames)
+
+	consumed := &ParseErrorConsumed{Message: "consumed", ParserNames: []string{"a"}}
+	_, err := Look(func(ctx Context[rune]) (any, error) {
+		return nil, consumed
+	})(ctx)
+	err.(*ParseError).ParserNames[0] = "changed"
+	assert.Equal(t, []string{"a"}, consumed.ParserNames)
+}
@@ -0,0 +1,216 @@
+package apc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Wrapped by the error returned when including a source would include itself.
+var ErrIncludeCycle = errors.New("include cycle")
+
+// Resolves the name of a source to include, as written in the source including it,
+// returning the name of the resolved source and a reader of it. from is the name of the
+// including source, or "" for the root source.
+//
+// The same source must always resolve to the same name, as include cycles are
+// detected by comparing names.
+type IncludeResolver func(from string, name string) (string, io.RuneReader, error)
+
+// Returns an IncludeResolver reading files of fsys. Names are slash-separated paths
+// relative to the directory of the including file, or to the root of fsys if they
+// begin with "/".
+func FSIncludeResolver(fsys fs.FS) IncludeResolver {
+	return func(from string, name string) (string, io.RuneReader, error) {
+		resolved := strings.TrimPrefix(name, "/")
+		if !strings.HasPrefix(name, "/") && from != "" {
+			resolved = path.Join(path.Dir(from), name)
+		}
+		resolved = path.Clean(resolved)
+		if !fs.ValidPath(resolved) {
+			return "", nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+		}
+		data, err := fs.ReadFile(fsys, resolved)
+		if err != nil {
+			return "", nil, err
+		}
+		return resolved, bytes.NewReader(data), nil
+	}
+}
+
+// Returns the Origins of the include directives that included origin, starting
+// with the one in the source including origin.
+func (origin Origin) IncludeChain() []Origin {
+	var chain []Origin
+	for from := origin.IncludedFrom; from != nil; from = from.IncludedFrom {
+		chain = append(chain, *from)
+	}
+	return chain
+}
+
+// Returns a line of the form "\n\tincluded from <name>:<line>" for each Origin that
+// included origin, or "" if it was not included.
+func includeStackString(origin Origin) string {
+	var sb strings.Builder
+	for _, from := range origin.IncludeChain() {
+		sb.WriteString(fmt.Sprintf("\n\tincluded from %v:%v", from.Name, from.LineNum))
+	}
+	return sb.String()
+}
+
+// An element read from a source before a source was included ahead of it.
+type includeReplay struct {
+	val    rune
+	origin Origin
+}
+
+// A source being read by an IncludeReader.
+type includeFrame struct {
+	reader ReaderWithOrigin[rune]
+	// The Origin of the include directive, shared by every Origin of the source.
+	// Nil for the root source.
+	includedFrom *Origin
+	// Elements to return before reading from reader.
+	replay []includeReplay
+}
+
+// IncludeReader implements ReaderWithOrigin[rune] by reading a stack of sources:
+// Include pushes a source that is read to its end before returning to the source
+// including it. Origins of included sources carry the chain of Origins they were
+// included from.
+type IncludeReader struct {
+	resolver IncludeResolver
+	frames   []*includeFrame
+}
+
+// Returns an *IncludeReader of the root source with the given name, resolved by resolver.
+func NewIncludeReader(name string, resolver IncludeResolver) (*IncludeReader, error) {
+	resolved, reader, err := resolver("", name)
+	if err != nil {
+		return nil, err
+	}
+	return &IncludeReader{
+		resolver: resolver,
+		frames:   []*includeFrame{{reader: NewRuneReaderWithOrigin(resolved, reader)}},
+	}, nil
+}
+
+// Returns a *ReaderContext[rune] of an IncludeReader of the root source with the given
+// name, resolved by resolver, in which the Include parser can include other sources.
+func NewIncludeContext(name string, resolver IncludeResolver) (*ReaderContext[rune], error) {
+	reader, err := NewIncludeReader(name, resolver)
+	if err != nil {
+		return nil, err
+	}
+	return NewReaderContext[rune](reader), nil
+}
+
+// Returns the next rune of the innermost source not yet fully read, and its Origin.
+// At the end of an included source, reading continues in the source including it.
+func (r *IncludeReader) Read() (rune, Origin, error) {
+	for {
+		frame := r.frames[len(r.frames)-1]
+		if len(frame.replay) > 0 {
+			elem := frame.replay[0]
+			frame.replay = frame.replay[1:]
+			return elem.val, elem.origin, nil
+		}
+
+		val, origin, err := frame.reader.Read()
+		origin.IncludedFrom = frame.includedFrom
+		if errors.Is(err, ErrEOF) && len(r.frames) > 1 {
+			r.frames = r.frames[:len(r.frames)-1]
+			continue
+		}
+		return val, origin, err
+	}
+}
+
+// Pushes the source with the given name, as written in the source of from, to be read
+// before the elements in unread. Returns the name of the resolved source.
+func (r *IncludeReader) include(name string, from Origin, unread []rune, unreadOrigins []Origin) (string, error) {
+	resolved, reader, err := r.resolver(from.Name, name)
+	if err != nil {
+		return "", err
+	}
+	chain := []string{resolved}
+	for org := &from; org != nil; org = org.IncludedFrom {
+		chain = append([]string{org.Name}, chain...)
+		if org.Name == resolved {
+			return "", fmt.Errorf("%w: %v", ErrIncludeCycle, strings.Join(chain, " -> "))
+		}
+	}
+
+	// The elements already read ahead of the include directive are read after the
+	// included source, in their own frame as they may come from several sources
+	if len(unread) > 0 {
+		replay := make([]includeReplay, len(unread))
+		for i, val := range unread {
+			replay[i] = includeReplay{val: val, origin: unreadOrigins[i]}
+		}
+		r.frames = append(r.frames, &includeFrame{reader: eofReader{}, replay: replay})
+	}
+	includedFrom := from
+	r.frames = append(r.frames, &includeFrame{
+		reader:       NewRuneReaderWithOrigin(resolved, reader),
+		includedFrom: &includedFrom,
+	})
+	return resolved, nil
+}
+
+// Implements ReaderWithOrigin[rune] by always returning ErrEOF.
+type eofReader struct{}
+
+func (eofReader) Read() (rune, Origin, error) {
+	return rune(-1), Origin{}, ErrEOF
+}
+
+// Returns a parser that parses the name of a source with nameParser, then includes
+// that source so that parsing continues in it, returning to the input following
+// the name at its end. Returns the name of the resolved source.
+//
+// The Context must be one returned by NewIncludeContext, possibly wrapped.
+// The Origins of the included source are included from the Origin of the name.
+// Including a source cannot be undone, so backtracking past the name does not
+// remove the included source from the input.
+func Include(nameParser Parser[rune, string]) Parser[rune, string] {
+	return func(ctx Context[rune]) (string, error) {
+		ctx.DebugStart("include")
+		defer ctx.DebugEnd("include")
+
+		recorder := newConsumeRecorder(ctx, false)
+		name, err := nameParser(recorder)
+		if err != nil {
+			return "", err
+		}
+		from := recorder.startOrigin()
+
+		rctx, ok := findContext[*ReaderContext[rune]](ctx)
+		var reader *IncludeReader
+		if ok {
+			reader, ok = rctx.reader.(*IncludeReader)
+		}
+		if !ok {
+			return "", &ParseErrorConsumed{
+				Message: fmt.Sprintf("cannot include '%v': context does not support includes", name),
+				Origin:  from,
+			}
+		}
+
+		unread, unreadOrigins := rctx.takeUnread()
+		resolved, err := reader.include(name, from, unread, unreadOrigins)
+		if err != nil {
+			rctx.restoreUnread(unread, unreadOrigins)
+			return "", &ParseErrorConsumed{
+				Err:     err,
+				Message: fmt.Sprintf("cannot include '%v'", name),
+				Origin:  from,
+			}
+		}
+		return resolved, nil
+	}
+}
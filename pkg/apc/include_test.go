@@ -0,0 +1,130 @@
+package apc
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Parses assignments of the form "name = 1" and include directives, returning
+// the Spanned name of each assignment.
+func newTestIncludeParser() Parser[rune, []Spanned[string]] {
+	assignment := Map(
+		Seq3(Span(Regex("[a-z]+")), ExactStr("="), Regex("[0-9]+")),
+		func(node *Seq3Node[Spanned[string], string, string]) []Spanned[string] {
+			return []Spanned[string]{node.Result1}
+		})
+	include := Map(
+		Seq2(ExactStr("include"), Include(DoubleQuotedStringParser)),
+		func(node *Seq2Node[string, string]) []Spanned[string] {
+			return nil
+		})
+	return Map(ZeroOrMore(Any(include, assignment)),
+		func(nodes [][]Spanned[string]) []Spanned[string] {
+			var result []Spanned[string]
+			for _, node := range nodes {
+				result = append(result, node...)
+			}
+			return result
+		})
+}
+
+func parseIncludes(t *testing.T, fsys fs.FS, name string) ([]Spanned[string], error) {
+	ctx, err := NewIncludeContext(name, FSIncludeResolver(fsys))
+	if !assert.NoError(t, err) {
+		return nil, err
+	}
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	return Parse[rune](ctx, newTestIncludeParser(), ParseConfig{MustParseToEOF: true})
+}
+
+func TestIncludeContinuesAfterIncludedSource(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.conf":     {Data: []byte("x = 1\ninclude \"sub/b.conf\"\ny = 2\n")},
+		"sub/b.conf": {Data: []byte("include \"c.conf\" z = 3")},
+		"sub/c.conf": {Data: []byte("w = 4\n")},
+	}
+	entries, err := parseIncludes(t, fsys, "a.conf")
+	assert.NoError(t, err)
+
+	var names []string
+	var origins []string
+	var chains [][]Origin
+	for _, entry := range entries {
+		names = append(names, entry.Value)
+		origins = append(origins, entry.OriginRange.Start.String())
+		chains = append(chains, entry.OriginRange.Start.IncludeChain())
+	}
+	assert.Equal(t, []string{"x", "w", "z", "y"}, names)
+	assert.Equal(t, []string{"a.conf:1:1", "sub/c.conf:1:1", "sub/b.conf:1:18", "a.conf:3:1"}, origins)
+
+	assert.Nil(t, chains[0])
+	assert.Equal(t, []Origin{
+		{Name: "sub/b.conf", LineNum: 1, ColNum: 9, IncludedFrom: &Origin{Name: "a.conf", LineNum: 2, ColNum: 9}},
+		{Name: "a.conf", LineNum: 2, ColNum: 9},
+	}, chains[1])
+	assert.Equal(t, []Origin{{Name: "a.conf", LineNum: 2, ColNum: 9}}, chains[2])
+	assert.Nil(t, chains[3])
+}
+
+func TestIncludeErrorShowsIncludeStack(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.conf": {Data: []byte("x = 1\n\ninclude \"b.conf\"")},
+		"b.conf": {Data: []byte("y = ")},
+	}
+	_, err := parseIncludes(t, fsys, "a.conf")
+	var perr *ParseErrorConsumed
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, "b.conf", perr.Origin.Name)
+		assert.Regexp(t, "^Parse Error \\(cannot backtrack\\) at b.conf:1:4: .*\n\tincluded from a.conf:3\n", err.Error())
+	}
+
+	diag := NewDiagnostic(err)
+	if assert.NotEmpty(t, diag.Related) {
+		related := diag.Related[len(diag.Related)-1]
+		assert.Equal(t, "included from here", related.Message)
+		assert.Equal(t, "a.conf:3:9", related.OriginRange.Start.String())
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.conf": {Data: []byte("include \"b.conf\"")},
+		"b.conf": {Data: []byte("x = 1 include \"/a.conf\"")},
+	}
+	_, err := parseIncludes(t, fsys, "a.conf")
+	assert.ErrorIs(t, err, ErrIncludeCycle)
+	assert.ErrorContains(t, err, "include cycle: a.conf -> b.conf -> a.conf")
+
+	fsys = fstest.MapFS{"self.conf": {Data: []byte("include \"self.conf\"")}}
+	_, err = parseIncludes(t, fsys, "self.conf")
+	assert.ErrorIs(t, err, ErrIncludeCycle)
+}
+
+func TestIncludeMissingSource(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.conf": {Data: []byte("x = 1 include \"missing.conf\" y = 2")},
+	}
+	_, err := parseIncludes(t, fsys, "a.conf")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	var perr *ParseErrorConsumed
+	if assert.True(t, errors.As(err, &perr)) {
+		assert.Equal(t, "cannot include 'missing.conf'", perr.Message)
+		assert.Equal(t, "a.conf:1:15", perr.Origin.String())
+	}
+
+	_, err = NewIncludeContext("missing.conf", FSIncludeResolver(fsys))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = parseIncludes(t, fstest.MapFS{"a.conf": {Data: []byte("include \"../x\"")}}, "a.conf")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestIncludeRequiresIncludeContext(t *testing.T) {
+	ctx := NewStringContext("a.conf", "include \"b.conf\"")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	_, err := Parse[rune](ctx, newTestIncludeParser(), DefaultParseConfig)
+	assert.ErrorContains(t, err, "context does not support includes")
+}
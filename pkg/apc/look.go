@@ -2,7 +2,9 @@ package apc
 
 // Provides backtracking support for the provided parser.
 // If an error occurs, any consumptions made are reverted to the state
-// of the context when this Look parser is called.
+// of the context when this Look parser is called, as are any skip parsers
+// added or removed and skip sets pushed or popped. The look offset and skip
+// state are also reverted if the parser panics.
 // If no error occurs, any consumptions made are committed to the current
 // Look frame.
 func Look[CT, T any](parser Parser[CT, T]) Parser[CT, T] {
@@ -14,6 +16,14 @@ func Look[CT, T any](parser Parser[CT, T]) Parser[CT, T] {
 		if lastLook == InvalidLookOffset {
 			ctx.SetLookOffset(0)
 		}
+		restoreSkipState := saveSkipStateOf(ctx)
+		succeeded := false
+		defer func() {
+			if !succeeded {
+				ctx.SetLookOffset(lastLook)
+				restoreSkipState()
+			}
+		}()
 
 		node, err := parser(ctx)
 		if err != nil {
@@ -22,9 +32,12 @@ func Look[CT, T any](parser Parser[CT, T]) Parser[CT, T] {
 			if pec, ok := err.(*ParseErrorConsumed); ok {
 				// Just convert the ParseErrorConsumed to a ParseError.
 				return zeroVal[T](), &ParseError{
-					Err:     pec.Err,
-					Message: pec.Message,
-					Origin:  pec.Origin,
+					Err:         pec.Err,
+					Message:     pec.Message,
+					Origin:      pec.Origin,
+					OriginRange: pec.OriginRange,
+					Expected:    pec.Expected,
+					ParserNames: append([]string(nil), pec.ParserNames...),
 				}
 			} else if pe, ok := err.(*ParseError); ok {
 				return zeroVal[T](), pe
@@ -38,8 +51,7 @@ func Look[CT, T any](parser Parser[CT, T]) Parser[CT, T] {
 
 		newLook := ctx.GetLookOffset()
 		if lastLook == InvalidLookOffset {
-			ctx.SetLookOffset(InvalidLookOffset)
-			_, err := ctx.Consume(newLook)
+			err := commitLook(ctx, newLook)
 			if err != nil {
 				return zeroVal[T](), err
 			}
@@ -47,6 +59,7 @@ func Look[CT, T any](parser Parser[CT, T]) Parser[CT, T] {
 			ctx.SetLookOffset(newLook)
 		}
 
+		succeeded = true
 		return node, err
 	}
 }
@@ -39,3 +39,18 @@ func TestLook(t *testing.T) {
 	_, err := parser(ctx)
 	assert.Error(t, err)
 }
+
+func TestLookRestoresLookOffsetOnPanic(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "abc")
+	panicking := func(ctx Context[rune]) (string, error) {
+		panic("parser panicked")
+	}
+
+	assert.Panics(t, func() { Look(Seq(ExactStr("a"), panicking))(ctx) })
+	assert.Equal(t, InvalidLookOffset, ctx.GetLookOffset())
+
+	// A nested Look frame reverts to the offset of the enclosing frame.
+	ctx.SetLookOffset(1)
+	assert.Panics(t, func() { Look(Seq(ExactStr("b"), panicking))(ctx) })
+	assert.Equal(t, 1, ctx.GetLookOffset())
+}
@@ -22,18 +22,22 @@ func Map[CT, T, U any](parser Parser[CT, T], mapFunc MapFunc[T, U]) Parser[CT, U
 // Returns a parser that maps a Parser[CT, T] into a Parser[CT, U] by running the
 // result of parser through mapFunc. This version of Map provides the ability to
 // report errors and have knowledge of the Origin.
+//
+// When parsing Tokens with known OriginRanges, the OriginRange spans from the start
+// of the first consumed Token to the end of the last consumed Token.
 func MapDetailed[CT, T, U any](parser Parser[CT, T], mapFunc MapDetailedFunc[T, U]) Parser[CT, U] {
 	return func(ctx Context[CT]) (U, error) {
 		startOrg := ctx.GetCurOrigin()
-		node, err := parser(ctx)
+		recorder := newConsumeRecorder(ctx, false)
+		node, err := parser(recorder)
 		if err != nil {
 			return zeroVal[U](), err
 		}
-		endOrg := ctx.GetCurOrigin()
-		return mapFunc(node, OriginRange{
+		orgRange := tokenSpan(recorder.consumedElements(), OriginRange{
 			Start: startOrg,
-			End:   endOrg,
+			End:   ctx.GetCurOrigin(),
 		})
+		return mapFunc(node, orgRange)
 	}
 }
 
@@ -2,10 +2,18 @@ package apc
 
 // Associates a name with the provided parser for better error messages.
 func Named[CT, T any](name string, parser Parser[CT, T]) Parser[CT, T] {
+	site := newCoverageSite("named", "matched")
+
 	return func(ctx Context[CT]) (T, error) {
 		lastName := ctx.GetCurParserName()
 		ctx.SetCurParserName(name)
+		cov := enterCoverage(ctx, site)
 		node, err := parser(ctx)
+		if err == nil {
+			cov.hit(0)
+		} else {
+			err = withParserName(err, name)
+		}
 		ctx.SetCurParserName(lastName)
 		return node, err
 	}
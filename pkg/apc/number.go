@@ -0,0 +1,400 @@
+package apc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// The precision (in bits) of *big.Float results from numeric literal parsers.
+var BigFloatPrecision uint = 256
+
+// NumberDialect describes which forms of numeric literals are accepted
+// by the numeric literal parsers.
+type NumberDialect struct {
+	// Allows a leading '-' sign.
+	AllowMinusSign bool
+	// Allows a leading '+' sign.
+	AllowPlusSign bool
+	// Allows decimal integer parts with leading zeros (such as "007").
+	// If false, a leading "0" is never followed by more digits.
+	AllowLeadingZeros bool
+	// Allows a fractional part (such as "1.5").
+	AllowFraction bool
+	// Allows a fractional part without an integer part (such as ".5").
+	// Requires AllowFraction.
+	AllowLeadingDot bool
+	// Allows a '.' without a following fractional part (such as "5.").
+	// Requires AllowFraction.
+	AllowTrailingDot bool
+	// Allows an exponent (such as "1e10" or "2.5E-3").
+	AllowExponent bool
+	// Allows hexadecimal integers with a "0x" or "0X" prefix.
+	AllowHex bool
+	// Allows octal integers with a "0o" or "0O" prefix.
+	AllowOctal bool
+	// Allows binary integers with a "0b" or "0B" prefix.
+	AllowBinary bool
+	// Allows '_' between digits (such as "1_000_000").
+	AllowDigitSeparators bool
+}
+
+// NumberDialect accepting exactly the JSON number grammar.
+var JSONNumberDialect = NumberDialect{
+	AllowMinusSign: true,
+	AllowFraction:  true,
+	AllowExponent:  true,
+}
+
+// NumberDialect accepting Go-style integer and decimal floating point literals.
+// As in Go, signs are not part of the literal.
+// Note that a leading "0" does not denote an octal literal; use "0o" instead.
+var GoNumberDialect = NumberDialect{
+	AllowLeadingZeros:    true,
+	AllowFraction:        true,
+	AllowLeadingDot:      true,
+	AllowTrailingDot:     true,
+	AllowExponent:        true,
+	AllowHex:             true,
+	AllowOctal:           true,
+	AllowBinary:          true,
+	AllowDigitSeparators: true,
+}
+
+// NumberDialect used by IntParser, FloatParser and IntOrFloatParser.
+// Same as GoNumberDialect, but allows a leading '+' or '-' and requires
+// digits on both sides of a '.' (so "1..5" is not read as "1." followed by ".5").
+var DefaultNumberDialect = NumberDialect{
+	AllowMinusSign:       true,
+	AllowPlusSign:        true,
+	AllowLeadingZeros:    true,
+	AllowFraction:        true,
+	AllowExponent:        true,
+	AllowHex:             true,
+	AllowOctal:           true,
+	AllowBinary:          true,
+	AllowDigitSeparators: true,
+}
+
+// numberLiteral is the result of scanning a numeric literal.
+type numberLiteral struct {
+	// The raw text of the literal.
+	text string
+	// Either "", "-" or "+".
+	sign string
+	// The digits of an integer literal without sign, prefix or separators.
+	intDigits string
+	// The base of an integer literal.
+	base int
+	// Whether the literal has a fractional part or exponent.
+	isFloat bool
+	// The Origin of the first rune of the literal.
+	origin Origin
+}
+
+// Returns the decimal literal without digit separators, suitable for
+// strconv.ParseFloat and big.ParseFloat.
+func (lit *numberLiteral) floatText() string {
+	return strings.ReplaceAll(lit.text, "_", "")
+}
+
+// Returns a ParseErrorConsumed reporting the literal as out of range.
+func (lit *numberLiteral) rangeErr(kind string, wrapErr error) *ParseErrorConsumed {
+	return &ParseErrorConsumed{
+		Err:     wrapErr,
+		Message: fmt.Sprintf("%v literal %v is out of range", kind, lit.text),
+		Origin:  lit.origin,
+	}
+}
+
+// Scans and consumes a numeric literal matching dialect. If allowFloat is false,
+// no fractional part or exponent is scanned.
+func scanNumberLiteral(ctx Context[rune], dialect NumberDialect, allowFloat bool) (*numberLiteral, error) {
+	err := ctx.RunSkipParsers()
+	if err != nil {
+		return nil, err
+	}
+
+	peekErr := error(nil)
+	peek := func(offset int) rune {
+		vals, err := ctx.Peek(offset, 1)
+		if err != nil {
+			if !errors.Is(err, ErrEOF) && peekErr == nil {
+				peekErr = err
+			}
+			return -1
+		}
+		return vals[0]
+	}
+	// Scans digits matching isDigit, optionally separated by '_', starting at pos.
+	// Returns the position after the last digit.
+	scanDigits := func(pos int, isDigit func(rune) bool) int {
+		for {
+			rn := peek(pos)
+			if isDigit(rn) {
+				pos++
+			} else if rn == '_' && dialect.AllowDigitSeparators && pos > 0 && isDigit(peek(pos-1)) && isDigit(peek(pos+1)) {
+				pos++
+			} else {
+				return pos
+			}
+		}
+	}
+
+	lit := &numberLiteral{base: 10, origin: ctx.GetCurOrigin()}
+	pos := 0
+	switch peek(0) {
+	case '-':
+		if dialect.AllowMinusSign {
+			lit.sign = "-"
+			pos++
+		}
+	case '+':
+		if dialect.AllowPlusSign {
+			lit.sign = "+"
+			pos++
+		}
+	}
+	start := pos
+	allowFraction := allowFloat && dialect.AllowFraction
+
+	// Prefixed integers
+	if peek(pos) == '0' {
+		base := 0
+		var isDigit func(rune) bool
+		switch peek(pos + 1) {
+		case 'x', 'X':
+			if dialect.AllowHex {
+				base, isDigit = 16, isHexDigit
+			}
+		case 'o', 'O':
+			if dialect.AllowOctal {
+				base, isDigit = 8, isOctalDigit
+			}
+		case 'b', 'B':
+			if dialect.AllowBinary {
+				base, isDigit = 2, isBinaryDigit
+			}
+		}
+		if base != 0 {
+			digitStart := pos + 2
+			if dialect.AllowDigitSeparators && peek(digitStart) == '_' && isDigit(peek(digitStart+1)) {
+				digitStart++
+			}
+			if end := scanDigits(digitStart, isDigit); end > digitStart {
+				lit.base = base
+				pos = end
+			}
+		}
+	}
+
+	// Decimal numbers
+	if lit.base == 10 {
+		if peek(pos) == '0' && !dialect.AllowLeadingZeros {
+			pos++
+		} else {
+			pos = scanDigits(pos, isDecimalDigit)
+		}
+		hasIntPart := pos > start
+		if allowFraction && peek(pos) == '.' && (hasIntPart || dialect.AllowLeadingDot) {
+			if end := scanDigits(pos+1, isDecimalDigit); end > pos+1 {
+				pos = end
+				lit.isFloat = true
+			} else if hasIntPart && dialect.AllowTrailingDot {
+				pos++
+				lit.isFloat = true
+			}
+		}
+		if (hasIntPart || lit.isFloat) && allowFloat && dialect.AllowExponent {
+			if rn := peek(pos); rn == 'e' || rn == 'E' {
+				expPos := pos + 1
+				if rn := peek(expPos); rn == '+' || rn == '-' {
+					expPos++
+				}
+				if end := scanDigits(expPos, isDecimalDigit); end > expPos {
+					pos = end
+					lit.isFloat = true
+				}
+			}
+		}
+	}
+	if peekErr != nil {
+		return nil, peekErr
+	}
+
+	if pos == start {
+		ctx.DebugPrint("number literal: got no match")
+		return nil, ParseErrExpectedButGotNext(ctx, ctx.GetCurParserName(), nil)
+	}
+
+	vals, err := ctx.Consume(pos)
+	if err != nil && !errors.Is(err, ErrEOF) {
+		return nil, err
+	}
+	lit.text = string(vals)
+	ctx.DebugPrint("number literal: got %v", lit.text)
+	if !lit.isFloat {
+		digits := lit.text[len(lit.sign):]
+		if lit.base != 10 {
+			digits = digits[2:]
+		}
+		lit.intDigits = strings.ReplaceAll(digits, "_", "")
+	}
+	return lit, nil
+}
+
+func isDecimalDigit(rn rune) bool {
+	return rn >= '0' && rn <= '9'
+}
+
+func isHexDigit(rn rune) bool {
+	return isDecimalDigit(rn) || (rn >= 'a' && rn <= 'f') || (rn >= 'A' && rn <= 'F')
+}
+
+func isOctalDigit(rn rune) bool {
+	return rn >= '0' && rn <= '7'
+}
+
+func isBinaryDigit(rn rune) bool {
+	return rn == '0' || rn == '1'
+}
+
+// Converts an integer literal to an int64.
+func (lit *numberLiteral) toInt64() (int64, error) {
+	val, err := strconv.ParseInt(lit.sign+lit.intDigits, lit.base, 64)
+	if err != nil {
+		return 0, lit.rangeErr("integer", err)
+	}
+	return val, nil
+}
+
+// Converts any literal to a float64.
+func (lit *numberLiteral) toFloat64() (float64, error) {
+	if !lit.isFloat && lit.base != 10 {
+		// strconv.ParseFloat does not accept octal or binary prefixes.
+		val, _ := new(big.Float).SetInt(lit.toBigInt()).Float64()
+		if math.IsInf(val, 0) {
+			return 0, lit.rangeErr("float", nil)
+		}
+		return val, nil
+	}
+	val, err := strconv.ParseFloat(lit.floatText(), 64)
+	if err != nil {
+		return 0, lit.rangeErr("float", err)
+	}
+	return val, nil
+}
+
+// Converts an integer literal to a *big.Int.
+func (lit *numberLiteral) toBigInt() *big.Int {
+	val, _ := new(big.Int).SetString(lit.sign+lit.intDigits, lit.base)
+	return val
+}
+
+// Converts any literal to a *big.Float.
+func (lit *numberLiteral) toBigFloat() (*big.Float, error) {
+	if !lit.isFloat {
+		return new(big.Float).SetInt(lit.toBigInt()), nil
+	}
+	val, _, err := big.ParseFloat(lit.floatText(), 10, BigFloatPrecision, big.ToNearestEven)
+	if err != nil {
+		return nil, lit.rangeErr("float", err)
+	}
+	return val, nil
+}
+
+// Returns a parser that parses a numeric literal accepted by dialect.
+// The result is an int64 for integer literals, or a float64 for literals
+// with a fractional part or exponent.
+//
+// If the literal does not fit the result type, a ParseErrorConsumed is returned
+// with the Origin of the literal.
+func NumberLiteral(dialect NumberDialect) Parser[rune, any] {
+	return func(ctx Context[rune]) (any, error) {
+		lit, err := scanNumberLiteral(ctx, dialect, true)
+		if err != nil {
+			return nil, err
+		}
+		if lit.isFloat {
+			return lit.toFloat64()
+		}
+		return lit.toInt64()
+	}
+}
+
+// Returns a parser that parses a numeric literal accepted by dialect.
+// The result is a *big.Int for integer literals, or a *big.Float for literals
+// with a fractional part or exponent.
+func BigNumberLiteral(dialect NumberDialect) Parser[rune, any] {
+	return func(ctx Context[rune]) (any, error) {
+		lit, err := scanNumberLiteral(ctx, dialect, true)
+		if err != nil {
+			return nil, err
+		}
+		if lit.isFloat {
+			val, err := lit.toBigFloat()
+			if err != nil {
+				return nil, err
+			}
+			return val, nil
+		}
+		return lit.toBigInt(), nil
+	}
+}
+
+// Returns a parser that parses an integer literal accepted by dialect and
+// returns an int64 result. A fractional part or exponent is never consumed.
+//
+// If the literal does not fit an int64, a ParseErrorConsumed is returned
+// with the Origin of the literal.
+func IntLiteral(dialect NumberDialect) Parser[rune, int64] {
+	return func(ctx Context[rune]) (int64, error) {
+		lit, err := scanNumberLiteral(ctx, dialect, false)
+		if err != nil {
+			return 0, err
+		}
+		return lit.toInt64()
+	}
+}
+
+// Returns a parser that parses an integer literal accepted by dialect and
+// returns a *big.Int result. A fractional part or exponent is never consumed.
+func BigIntLiteral(dialect NumberDialect) Parser[rune, *big.Int] {
+	return func(ctx Context[rune]) (*big.Int, error) {
+		lit, err := scanNumberLiteral(ctx, dialect, false)
+		if err != nil {
+			return nil, err
+		}
+		return lit.toBigInt(), nil
+	}
+}
+
+// Returns a parser that parses any numeric literal accepted by dialect and
+// returns a float64 result.
+//
+// If the literal does not fit a float64, a ParseErrorConsumed is returned
+// with the Origin of the literal.
+func FloatLiteral(dialect NumberDialect) Parser[rune, float64] {
+	return func(ctx Context[rune]) (float64, error) {
+		lit, err := scanNumberLiteral(ctx, dialect, true)
+		if err != nil {
+			return 0, err
+		}
+		return lit.toFloat64()
+	}
+}
+
+// Returns a parser that parses any numeric literal accepted by dialect and
+// returns a *big.Float result with BigFloatPrecision bits of precision.
+func BigFloatLiteral(dialect NumberDialect) Parser[rune, *big.Float] {
+	return func(ctx Context[rune]) (*big.Float, error) {
+		lit, err := scanNumberLiteral(ctx, dialect, true)
+		if err != nil {
+			return nil, err
+		}
+		return lit.toBigFloat()
+	}
+}
@@ -0,0 +1,139 @@
+package apc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntOrFloatParserLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"42", int64(42)},
+		{"-42", int64(-42)},
+		{"+7", int64(7)},
+		{"007", int64(7)},
+		{"1_000_000", int64(1000000)},
+		{"0x1F", int64(31)},
+		{"0X_ff", int64(255)},
+		{"0o17", int64(15)},
+		{"0b1010", int64(10)},
+		{"-0b1", int64(-1)},
+		{"1.5", 1.5},
+		{"1e10", 1e10},
+		{"2.5E-3", 2.5e-3},
+		{"1_0.0_1", 10.01},
+	}
+	for _, test := range tests {
+		ctx := NewStringContext(testStringOrigin, test.input)
+		node, err := Parse[rune](ctx, IntOrFloatParser, DefaultParseConfig)
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.expected, node, test.input)
+	}
+}
+
+func TestNumberLiteralPartialMatches(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+		rest     string
+	}{
+		{"1_", int64(1), "_"},
+		{"1__2", int64(1), "__2"},
+		{"0x", int64(0), "x"},
+		{"1e", int64(1), "e"},
+		{"1e+", int64(1), "e+"},
+		{"12abc", int64(12), "abc"},
+		{"5.", int64(5), "."},
+		{"1..5", int64(1), "..5"},
+		{"1.e5", int64(1), ".e5"},
+	}
+	for _, test := range tests {
+		ctx := NewStringContext(testStringOrigin, test.input)
+		node, err := IntOrFloatParser(ctx)
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.expected, node, test.input)
+		rest, _ := ctx.Peek(0, len(test.rest))
+		assert.Equal(t, test.rest, string(rest), test.input)
+	}
+
+	ctx := NewStringContext(testStringOrigin, "1.5")
+	node, err := IntParser(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), node)
+
+	for _, input := range []string{"", "abc", "-", "_1", ".", ".5"} {
+		ctx := NewStringContext(testStringOrigin, input)
+		_, err := IntOrFloatParser(ctx)
+		assert.ErrorIs(t, err, ErrParseErr, input)
+		assert.NotErrorIs(t, err, ErrParseErrConsumed, input)
+	}
+}
+
+func TestNumberLiteralOverflow(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "  99999999999999999999")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	_, err := IntParser(ctx)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+	assert.Equal(t, Origin{Name: testStringOrigin, LineNum: 1, ColNum: 3}, err.(*ParseErrorConsumed).Origin)
+
+	ctx = NewStringContext(testStringOrigin, "1e999")
+	_, err = FloatParser(ctx)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+
+	ctx = NewStringContext(testStringOrigin, "0x1_0000_0000_0000_0000")
+	_, err = IntOrFloatParser(ctx)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+}
+
+func TestJSONNumberDialect(t *testing.T) {
+	p := NumberLiteral(JSONNumberDialect)
+
+	ctx := NewStringContext(testStringOrigin, "-0.5e+2")
+	node, err := Parse[rune](ctx, p, DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, -50.0, node)
+
+	for _, input := range []string{"01", "+1", ".5", "5.", "0x1F", "1_000"} {
+		ctx := NewStringContext(testStringOrigin, input)
+		_, err := Parse[rune](ctx, p, DefaultParseConfig)
+		assert.ErrorIs(t, err, ErrParseErr, input)
+	}
+}
+
+func TestGoNumberDialectSign(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "-1")
+	_, err := NumberLiteral(GoNumberDialect)(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+}
+
+func TestGoNumberDialectDots(t *testing.T) {
+	p := NumberLiteral(GoNumberDialect)
+	for input, expected := range map[string]float64{".5": 0.5, "5.": 5.0, "1.e5": 1e5} {
+		ctx := NewStringContext(testStringOrigin, input)
+		node, err := Parse[rune](ctx, p, DefaultParseConfig)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, node, input)
+	}
+}
+
+func TestBigNumberLiterals(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "99999999999999999999")
+	node, err := BigNumberLiteral(DefaultNumberDialect)(ctx)
+	assert.NoError(t, err)
+	expected, _ := new(big.Int).SetString("99999999999999999999", 10)
+	assert.Equal(t, 0, expected.Cmp(node.(*big.Int)))
+
+	ctx = NewStringContext(testStringOrigin, "0x_FFFF_FFFF_FFFF_FFFF_FF")
+	bigInt, err := BigIntLiteral(DefaultNumberDialect)(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "4722366482869645213695", bigInt.String())
+
+	ctx = NewStringContext(testStringOrigin, "1e999")
+	bigFloat, err := BigFloatLiteral(DefaultNumberDialect)(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "1e+999", bigFloat.Text('g', 10))
+}
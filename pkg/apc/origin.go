@@ -10,6 +10,9 @@ type Origin struct {
 	LineNum int
 	// The column number location.
 	ColNum int
+	// The Origin of the include directive that included the source, if any.
+	// Shared by all Origins of the same included source. See Include.
+	IncludedFrom *Origin
 }
 
 // Returns a string representation of an Origin.
@@ -24,3 +27,16 @@ type OriginRange struct {
 	// The end origin.
 	End Origin
 }
+
+// Returns true if the OriginRange is the zero value (unknown).
+func (orgRange OriginRange) IsZero() bool {
+	return orgRange == OriginRange{}
+}
+
+// Returns a string representation of an OriginRange.
+func (orgRange OriginRange) String() string {
+	if orgRange.Start.Name == orgRange.End.Name {
+		return fmt.Sprintf("%v-%v:%v", orgRange.Start, orgRange.End.LineNum, orgRange.End.ColNum)
+	}
+	return fmt.Sprintf("%v-%v", orgRange.Start, orgRange.End)
+}
@@ -0,0 +1,136 @@
+package apc
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// The unit in which the columns of Origins are counted.
+type ColumnUnit int
+
+const (
+	// Each rune is one column.
+	ColumnUnitRune ColumnUnit = iota
+	// Each byte a rune was read from, such as each byte of its UTF-8 encoding, is one column.
+	ColumnUnitByte
+	// Each UTF-16 code unit is one column, as used by the Language Server Protocol
+	// and many editors: runes outside the Basic Multilingual Plane are two columns.
+	ColumnUnitUTF16
+	// Each grapheme cluster, as perceived by the user, is one column. Clusters are
+	// approximated: combining marks, variation selectors, emoji modifiers, the zero
+	// width joiner and the rune following it, and the second of a pair of regional
+	// indicators extend the preceding cluster.
+	ColumnUnitGrapheme
+)
+
+// Returns the name of the ColumnUnit.
+func (unit ColumnUnit) String() string {
+	switch unit {
+	case ColumnUnitRune:
+		return "rune"
+	case ColumnUnitByte:
+		return "byte"
+	case ColumnUnitUTF16:
+		return "utf-16"
+	case ColumnUnitGrapheme:
+		return "grapheme"
+	default:
+		return fmt.Sprintf("ColumnUnit(%d)", int(unit))
+	}
+}
+
+// OriginConfig contains settings for how a RuneReaderWithOrigin reads runes and
+// counts the lines and columns of their Origins.
+type OriginConfig struct {
+	// If greater than 0, a tab advances the column to the next tab stop, every
+	// TabWidth columns. Otherwise, a tab is one column.
+	TabWidth int
+	// If true, "\r\n" and a lone "\r" are read as a single '\n' rune, at the Origin
+	// of the '\r'. Otherwise, '\r' is read as a rune that does not end the line.
+	TranslateNewlines bool
+	// The unit in which columns are counted.
+	ColumnUnit ColumnUnit
+}
+
+// A sane default for OriginConfig, counting each rune (including tabs) as one
+// column and reading runes unchanged.
+var DefaultOriginConfig = OriginConfig{
+	TabWidth:          0,
+	TranslateNewlines: false,
+	ColumnUnit:        ColumnUnitRune,
+}
+
+// Counts the columns of runes read on a line according to an OriginConfig.
+type columnCounter struct {
+	config OriginConfig
+	// The column of the next rune.
+	col int
+	// The column of the current grapheme cluster, the previous rune, and whether
+	// the previous rune is a regional indicator starting a pair.
+	clusterCol int
+	prev       rune
+	riOpen     bool
+}
+
+// Resets the counter to the start of a line.
+func (counter *columnCounter) startLine() {
+	counter.col = 1
+	counter.clusterCol = 1
+	counter.prev = 0
+	counter.riOpen = false
+}
+
+// Returns the column of rn, whose UTF-8 encoding has size bytes, and advances
+// past it. Line terminators are handled by the caller.
+func (counter *columnCounter) advance(rn rune, size int) int {
+	col := counter.col
+	if rn == '\t' && counter.config.TabWidth > 0 {
+		width := counter.config.TabWidth
+		counter.col = ((counter.col-1)/width+1)*width + 1
+		counter.clusterCol, counter.prev, counter.riOpen = col, rn, false
+		return col
+	}
+
+	switch counter.config.ColumnUnit {
+	case ColumnUnitByte:
+		counter.col += size
+	case ColumnUnitUTF16:
+		if rn >= 0x10000 && rn <= unicode.MaxRune {
+			counter.col += 2
+		} else {
+			counter.col++
+		}
+	case ColumnUnitGrapheme:
+		isRI := rn >= 0x1F1E6 && rn <= 0x1F1FF
+		if counter.extendsCluster(rn, isRI) {
+			col = counter.clusterCol
+			counter.riOpen = false
+		} else {
+			counter.clusterCol = col
+			counter.col++
+			counter.riOpen = isRI
+		}
+		counter.prev = rn
+		return col
+	default:
+		counter.col++
+	}
+	return col
+}
+
+// Returns true if rn extends the grapheme cluster of the previous rune.
+func (counter *columnCounter) extendsCluster(rn rune, isRI bool) bool {
+	if counter.col == 1 && counter.prev == 0 {
+		return false
+	}
+	switch {
+	case isRI:
+		return counter.riOpen
+	case counter.prev == 0x200D: // zero width joiner
+		return true
+	case rn == 0x200D, rn >= 0x1F3FB && rn <= 0x1F3FF: // emoji modifiers
+		return true
+	default:
+		return unicode.In(rn, unicode.Mn, unicode.Me, unicode.Mc)
+	}
+}
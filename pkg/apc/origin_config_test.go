@@ -0,0 +1,94 @@
+package apc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns "<rune>@<line>:<col>" for each rune read from input with config, and
+// "EOF@<line>:<col>" for the end of input.
+func readOrigins(input string, config OriginConfig) []string {
+	reader := NewRuneReaderWithOriginConfig("test", strings.NewReader(input), config)
+	var result []string
+	for {
+		rn, origin, err := reader.Read()
+		text := strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(string(rn))
+		if err != nil {
+			text = "EOF"
+		}
+		result = append(result, text+"@"+strings.TrimPrefix(origin.String(), "test:"))
+		if err != nil {
+			return result
+		}
+	}
+}
+
+func TestOriginConfigDefault(t *testing.T) {
+	assert.Equal(t, []string{"a@1:1", `\t@1:2`, `\r@1:3`, `\n@1:4`, "é@2:1", "😀@2:2", "EOF@2:3"},
+		readOrigins("a\t\r\né😀", DefaultOriginConfig))
+}
+
+func TestOriginConfigTabWidth(t *testing.T) {
+	config := OriginConfig{TabWidth: 4}
+	assert.Equal(t, []string{`\t@1:1`, "a@1:5", `\t@1:6`, `\t@1:9`, "b@1:13", "EOF@1:14"},
+		readOrigins("\ta\t\tb", config))
+	assert.Equal(t, []string{"a@1:1", "b@1:2", "c@1:3", `\t@1:4`, "d@1:5", "EOF@1:6"},
+		readOrigins("abc\td", config))
+}
+
+func TestOriginConfigTranslateNewlines(t *testing.T) {
+	config := OriginConfig{TranslateNewlines: true}
+	assert.Equal(t, []string{
+		"a@1:1", `\n@1:2`,
+		"b@2:1", `\n@2:2`,
+		"c@3:1", `\n@3:2`,
+		`\n@4:1`,
+		`\n@5:1`,
+		"d@6:1", `\n@6:2`,
+		"EOF@7:1",
+	}, readOrigins("a\r\nb\rc\n\r\rd\r", config))
+}
+
+func TestOriginConfigColumnUnits(t *testing.T) {
+	input := "aé😀b"
+	assert.Equal(t, []string{"a@1:1", "é@1:2", "😀@1:4", "b@1:8", "EOF@1:9"},
+		readOrigins(input, OriginConfig{ColumnUnit: ColumnUnitByte}))
+	assert.Equal(t, []string{"a@1:1", "é@1:2", "😀@1:3", "b@1:5", "EOF@1:6"},
+		readOrigins(input, OriginConfig{ColumnUnit: ColumnUnitUTF16}))
+	assert.Equal(t, []string{"a@1:1", "é@1:2", "😀@1:3", "b@1:4", "EOF@1:5"},
+		readOrigins(input, OriginConfig{ColumnUnit: ColumnUnitRune}))
+}
+
+func TestOriginConfigGraphemeColumns(t *testing.T) {
+	config := OriginConfig{ColumnUnit: ColumnUnitGrapheme}
+	// e + combining acute accent, thumbs up + skin tone, a ZWJ family, and a flag
+	assert.Equal(t, []string{
+		"e@1:1", "́@1:1",
+		"👍@1:2", "🏽@1:2",
+		"👨@1:3", "‍@1:3", "👩@1:3",
+		"🇯@1:4", "🇵@1:4", "🇺@1:5",
+		"x@1:6", "EOF@1:7",
+	}, readOrigins("é👍🏽👨‍👩🇯🇵🇺x", config))
+	// A combining mark at the start of a line does not extend the previous line
+	assert.Equal(t, []string{"a@1:1", `\n@1:2`, "́@2:1", "EOF@2:2"},
+		readOrigins("a\ń", config))
+}
+
+func TestOriginConfigAppliesToErrors(t *testing.T) {
+	ctx := NewStringContextWithConfig("test", "\tx = 😀\r\n\t!",
+		OriginConfig{TabWidth: 8, TranslateNewlines: true, ColumnUnit: ColumnUnitUTF16})
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	_, err := Parse[rune](ctx, Seq(ExactStr("x"), ExactStr("="), ExactStr("😀"), ExactStr("y")), DefaultParseConfig)
+	assert.ErrorContains(t, err, "test:2:9")
+
+	_, err = Parse[rune](NewStringContextWithConfig("test", "\t😀!", OriginConfig{TabWidth: 4, ColumnUnit: ColumnUnitUTF16}),
+		Seq(ExactStr("\t😀"), ExactStr("?")), DefaultParseConfig)
+	assert.ErrorContains(t, err, "test:1:7")
+}
+
+func TestColumnUnitString(t *testing.T) {
+	assert.Equal(t, "utf-16", ColumnUnitUTF16.String())
+	assert.Equal(t, "ColumnUnit(9)", ColumnUnit(9).String())
+}
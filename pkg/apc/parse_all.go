@@ -0,0 +1,64 @@
+package apc
+
+import (
+	"os"
+	"runtime"
+	"sync"
+)
+
+// The result of parsing one file with ParseAll.
+type ParseAllResult[T any] struct {
+	// The name of the file.
+	File string
+	// The result of the parser, if Err is nil.
+	Value T
+	// The error opening or parsing the file, if any.
+	Err error
+}
+
+// Parses each of files with parser, as by Parse with DefaultParseConfig and a
+// context from NewFileContext, using up to workers goroutines (or one per CPU if
+// workers is less than 1). Returns the result of each file in the order of files.
+//
+// parser is called concurrently with separate contexts, so it must be safe to
+// share (see Parser). Skip parsers should be part of parser, such as by using Skip.
+func ParseAll[T any](files []string, parser Parser[rune, T], workers int) []ParseAllResult[T] {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	results := make([]ParseAllResult[T], len(files))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				results[index] = parseFile(files[index], parser)
+			}
+		}()
+	}
+	for index := range files {
+		indices <- index
+	}
+	close(indices)
+	wg.Wait()
+	return results
+}
+
+// Parses the file with parser for ParseAll.
+func parseFile[T any](name string, parser Parser[rune, T]) ParseAllResult[T] {
+	result := ParseAllResult[T]{File: name}
+	file, err := os.Open(name)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer file.Close()
+	result.Value, result.Err = Parse[rune](NewFileContext(file), parser, DefaultParseConfig)
+	return result
+}
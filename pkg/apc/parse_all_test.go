@@ -0,0 +1,50 @@
+package apc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAll(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 20; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("file%v.txt", i))
+		content := strings.Repeat(fmt.Sprintf("item%v ", i), i+1)
+		if i == 7 {
+			content += "!"
+		}
+		assert.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+		files = append(files, name)
+	}
+	files = append(files, filepath.Join(dir, "missing.txt"))
+
+	p := Map(Skip(CastToAny(WhitespaceParser), ZeroOrMore(IdentifierParser)), func(idents []string) int {
+		return len(idents)
+	})
+	for _, workers := range []int{0, 1, 3, 100} {
+		results := ParseAll(files, p, workers)
+		if !assert.Len(t, results, len(files)) {
+			continue
+		}
+		for i, result := range results {
+			assert.Equal(t, files[i], result.File)
+			switch i {
+			case 7:
+				assert.ErrorContains(t, result.Err, "file7.txt:1:49: expected EOF but got !")
+			case 20:
+				assert.ErrorIs(t, result.Err, os.ErrNotExist)
+			default:
+				assert.NoError(t, result.Err)
+				assert.Equal(t, i+1, result.Value)
+			}
+		}
+	}
+
+	assert.Len(t, ParseAll(nil, p, 0), 0)
+}
@@ -1,6 +1,7 @@
 package apc
 
 import (
+	"errors"
 	"io"
 )
 
@@ -28,49 +29,117 @@ func NewParseReader[CT, T any](ctx Context[CT], parser Parser[CT, T]) *ParseRead
 }
 
 // Calls the parser with the corresponding ctx, returning the result and Origin of the result.
+// The Origin is that of the first element consumed after running any skip parsers.
+// If the result is a Token without an OriginRange, its OriginRange and Raw text are set
+// from the consumed input.
 // If an error occurs or if no element is available, an error is returned.
 func (r *ParseReader[CT, T]) Read() (T, Origin, error) {
-	origin := r.ctx.GetCurOrigin()
-	val, err := r.parser(r.ctx)
-	return val, origin, err
+	err := r.ctx.RunSkipParsers()
+	if err != nil {
+		return zeroVal[T](), r.ctx.GetCurOrigin(), err
+	}
+
+	recorder := newConsumeRecorder(r.ctx, true)
+	val, err := r.parser(recorder)
+	if err != nil {
+		return val, recorder.startOrigin(), err
+	}
+	if tok, ok := any(val).(Token); ok && tok.OriginRange.IsZero() {
+		vals := recorder.consumedElements()
+		tok.OriginRange = recorder.originRange(vals)
+		if tok.Raw == "" {
+			tok.Raw = rawTextOf(vals)
+		}
+		val = any(tok).(T)
+	}
+	return val, recorder.startOrigin(), nil
 }
 
 // Implements ReaderWithOrigin[rune] by calling reader.ReadRune.
 type RuneReaderWithOrigin struct {
 	reader    io.RuneReader
+	config    OriginConfig
 	curOrigin Origin
+	columns   columnCounter
+	// A rune (or error) read ahead when checking for "\r\n", to return next.
+	pending     rune
+	pendingSize int
+	hasPending  bool
+	pendingErr  error
 }
 
-// Returns a *RuneReaderWithOrigin with the provided origin name and reader.
+// Returns a *RuneReaderWithOrigin with the provided origin name and reader,
+// using DefaultOriginConfig.
 func NewRuneReaderWithOrigin(originName string, reader io.RuneReader) *RuneReaderWithOrigin {
-	return &RuneReaderWithOrigin{
+	return NewRuneReaderWithOriginConfig(originName, reader, DefaultOriginConfig)
+}
+
+// Returns a *RuneReaderWithOrigin with the provided origin name and reader, reading
+// runes and counting Origins according to config.
+func NewRuneReaderWithOriginConfig(originName string, reader io.RuneReader, config OriginConfig) *RuneReaderWithOrigin {
+	r := &RuneReaderWithOrigin{
 		reader: reader,
+		config: config,
 		curOrigin: Origin{
 			Name:    originName,
 			LineNum: 1,
 			ColNum:  1,
 		},
+		columns: columnCounter{config: config},
 	}
+	r.columns.startLine()
+	return r
+}
+
+func (r *RuneReaderWithOrigin) readRune() (rune, int, error) {
+	if r.hasPending {
+		r.hasPending = false
+		return r.pending, r.pendingSize, nil
+	}
+	if r.pendingErr != nil {
+		return rune(-1), 0, r.pendingErr
+	}
+	return r.reader.ReadRune()
 }
 
 // Calls reader.ReadRune, returning the resulting rune and Origin of the rune.
 // If an error occurs or if no rune is available, an error is returned.
 func (r *RuneReaderWithOrigin) Read() (rune, Origin, error) {
-	rn, _, err := r.reader.ReadRune()
+	rn, size, err := r.readRune()
 	if err != nil {
 		if err == io.EOF {
 			return rune(-1), r.curOrigin, ErrEOF
 		}
+		var decodeErr *DecodeError
+		if errors.As(err, &decodeErr) {
+			// Input that cannot be decoded must not be backtracked over
+			return rune(-1), r.curOrigin, &ParseErrorConsumed{
+				Err:     err,
+				Message: "could not decode input",
+				Origin:  r.curOrigin,
+			}
+		}
 		return rune(-1), r.curOrigin, err
 	}
 
+	if rn == '\r' && r.config.TranslateNewlines {
+		next, nextSize, err := r.readRune()
+		if err != nil {
+			r.pendingErr = err
+		} else if next != '\n' {
+			r.pending, r.pendingSize, r.hasPending = next, nextSize, true
+		}
+		rn = '\n'
+	}
+
 	origin := r.curOrigin
 	if rn == '\n' {
 		r.curOrigin.LineNum += 1
-		r.curOrigin.ColNum = 1
+		r.columns.startLine()
 	} else {
-		r.curOrigin.ColNum += 1
+		origin.ColNum = r.columns.advance(rn, size)
 	}
+	r.curOrigin.ColNum = r.columns.col
 
 	return rn, origin, nil
 }
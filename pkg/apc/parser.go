@@ -1,6 +1,8 @@
 // Package apc provides a minimalist parser combinator library.
 package apc
 
+import "errors"
+
 // A sane default for ParseConfig.
 var DefaultParseConfig = ParseConfig{
 	MustParseToEOF: true,
@@ -14,6 +16,10 @@ var DefaultParseConfig = ParseConfig{
 // Any other error type may be returned, and is treated like ParseErrorConsumed.
 //
 // Any terminal parser (such as Exact or Regex) should call ctx.RunSkipParsers first.
+//
+// The parsers of this package keep no state between calls, and so may be used
+// concurrently with separate contexts, as long as any functions passed to them
+// (such as to Map) may be too. All state of a parse is kept in its Context.
 type Parser[CT, T any] func(ctx Context[CT]) (T, error)
 
 // ParseConfig contains settings that can be passed to the Parse function.
@@ -35,9 +41,13 @@ func Parse[CT, T any](ctx Context[CT], parser Parser[CT, T], parseConfig ParseCo
 			return zeroVal[T](), err
 		}
 
-		if _, err := ctx.Peek(0, 1); err == nil {
+		_, err = ctx.Peek(0, 1)
+		if err == nil {
 			return zeroVal[T](), ParseErrExpectedButGotNext(ctx, "EOF", nil)
 		}
+		if IsMustReturnParseErr(err) && !errors.Is(err, ErrEOF) {
+			return zeroVal[T](), err
+		}
 	}
 
 	return node, nil
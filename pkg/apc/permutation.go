@@ -0,0 +1,115 @@
+package apc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PermutationSlot[CT, R] is a clause of a Permutation parser that may match
+// at most once, setting its result into a value of type R.
+type PermutationSlot[CT, R any] struct {
+	name     string
+	required bool
+	parse    func(ctx Context[CT], result *R) error
+}
+
+// Returns a PermutationSlot that must match exactly once. The result of parser
+// is set into the Permutation result by calling setFunc.
+func RequiredSlot[CT, R, T any](name string, parser Parser[CT, T], setFunc func(result *R, node T)) PermutationSlot[CT, R] {
+	return newPermutationSlot(name, true, parser, setFunc)
+}
+
+// Returns a PermutationSlot that may match at most once. The result of parser
+// is set into the Permutation result by calling setFunc.
+func OptionalSlot[CT, R, T any](name string, parser Parser[CT, T], setFunc func(result *R, node T)) PermutationSlot[CT, R] {
+	return newPermutationSlot(name, false, parser, setFunc)
+}
+
+func newPermutationSlot[CT, R, T any](name string, required bool, parser Parser[CT, T],
+	setFunc func(result *R, node T)) PermutationSlot[CT, R] {
+	return PermutationSlot[CT, R]{
+		name:     name,
+		required: required,
+		parse: func(ctx Context[CT], result *R) error {
+			node, err := parser(ctx)
+			if err != nil {
+				return err
+			}
+			setFunc(result, node)
+			return nil
+		},
+	}
+}
+
+// Returns a parser that parses the provided slots in any order, each at most once.
+// Returns the value of type R populated by each matched slot.
+//
+// A slot matching a second time results in a ParseErrorConsumed reporting a
+// duplicate clause at the Origin of the second match.
+// A required slot that did not match results in an error reporting the missing clause.
+func Permutation[CT, R any](slots ...PermutationSlot[CT, R]) Parser[CT, R] {
+	if len(slots) == 0 {
+		panic("must provide at least 1 slot to Permutation")
+	}
+
+	return func(ctx Context[CT]) (R, error) {
+		ctx.DebugStart("permutation")
+		defer ctx.DebugEnd("permutation")
+
+		var result R
+		matchOrigins := make([]*Origin, len(slots))
+		anyMatched := false
+
+		for {
+			err := ctx.RunSkipParsers()
+			if err != nil {
+				return zeroVal[R](), err
+			}
+			clauseOrigin := ctx.GetCurOrigin()
+
+			matched := false
+			for i, slot := range slots {
+				// Already matched slots still parse into a scratch result to detect duplicates.
+				target := &result
+				if matchOrigins[i] != nil {
+					target = new(R)
+				}
+				err := slot.parse(ctx, target)
+				if err != nil {
+					if IsMustReturnParseErr(err) {
+						return zeroVal[R](), err
+					}
+					continue
+				}
+				if matchOrigins[i] != nil {
+					return zeroVal[R](), &ParseErrorConsumed{
+						Message: fmt.Sprintf("duplicate clause %v (first at %v)", slot.name, *matchOrigins[i]),
+						Origin:  clauseOrigin,
+					}
+				}
+				matchOrigins[i] = &clauseOrigin
+				matched = true
+				anyMatched = true
+				break
+			}
+			if !matched {
+				break
+			}
+		}
+
+		missing := make([]string, 0)
+		for i, slot := range slots {
+			if slot.required && matchOrigins[i] == nil {
+				missing = append(missing, slot.name)
+			}
+		}
+		if len(missing) > 0 {
+			msg := fmt.Sprintf("missing required clause %v", strings.Join(missing, ", "))
+			if anyMatched {
+				return zeroVal[R](), &ParseErrorConsumed{Message: msg, Origin: ctx.GetCurOrigin()}
+			}
+			return zeroVal[R](), &ParseError{Message: msg, Origin: ctx.GetCurOrigin()}
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,70 @@
+package apc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTableOptions struct {
+	Engine  string
+	Comment string
+	With    int64
+}
+
+func newTestTableOptionsParser() Parser[rune, testTableOptions] {
+	return Permutation(
+		RequiredSlot("ENGINE",
+			Map(Seq3(ExactStr("ENGINE"), ExactStr("="), IdentifierParser),
+				func(node *Seq3Node[string, string, string]) string { return node.Result3 }),
+			func(result *testTableOptions, node string) { result.Engine = node }),
+		OptionalSlot("COMMENT",
+			Map(Seq2(ExactStr("COMMENT"), SingleQuotedStringParser),
+				func(node *Seq2Node[string, string]) string { return node.Result2 }),
+			func(result *testTableOptions, node string) { result.Comment = node }),
+		OptionalSlot("WITH",
+			Map(Seq2(ExactStr("WITH"), Between(ExactStr("("), ExactStr(")"), IntParser)),
+				func(node *Seq2Node[string, int64]) int64 { return node.Result2 }),
+			func(result *testTableOptions, node int64) { result.With = node }),
+	)
+}
+
+func TestPermutationParser(t *testing.T) {
+	p := newTestTableOptionsParser()
+
+	inputs := map[string]testTableOptions{
+		"ENGINE=x":                        {Engine: "x"},
+		"WITH (1) COMMENT 'hi' ENGINE=x":  {Engine: "x", Comment: "hi", With: 1},
+		"COMMENT 'hi' ENGINE=y WITH (2)":  {Engine: "y", Comment: "hi", With: 2},
+		"ENGINE = z   WITH(3) COMMENT ''": {Engine: "z", With: 3},
+	}
+	for input, expected := range inputs {
+		ctx := NewStringContext(testStringOrigin, input)
+		ctx.AddSkipParser(CastToAny(WhitespaceParser))
+		node, err := Parse[rune](ctx, p, DefaultParseConfig)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, node, input)
+	}
+}
+
+func TestPermutationParserErrors(t *testing.T) {
+	p := newTestTableOptionsParser()
+
+	ctx := NewStringContext(testStringOrigin, "ENGINE=x COMMENT 'a' COMMENT 'b'")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	_, err := p(ctx)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+	assert.Equal(t, Origin{Name: testStringOrigin, LineNum: 1, ColNum: 22}, err.(*ParseErrorConsumed).Origin)
+	assert.Contains(t, err.Error(), "duplicate clause COMMENT (first at <origin>:1:10)")
+
+	ctx = NewStringContext(testStringOrigin, "COMMENT 'a'")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+	assert.Contains(t, err.Error(), "missing required clause ENGINE")
+
+	ctx = NewStringContext(testStringOrigin, "nothing")
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	assert.NotErrorIs(t, err, ErrParseErrConsumed)
+}
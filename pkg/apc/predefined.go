@@ -1,10 +1,5 @@
 package apc
 
-import (
-	"strconv"
-	"strings"
-)
-
 // Parses one or more whitespace characters and returns the string result.
 var WhitespaceParser = Named("whitespace", Regex("\\s+"))
 
@@ -34,47 +29,13 @@ var IdentifierParser = Named("identifier", Regex("[a-zA-Z_][a-zA-Z_0-9]*"))
 var BoolParser = Named("boolean", Any(Bind(ExactStr("true"), true), Bind(ExactStr("false"), false)))
 
 // Parses int and floating point numbers and returns a int64 or float64 result.
-// May be preceded with '+' or '-'.
-var IntOrFloatParser = Named("int or float",
-	Map(
-		Regex("[+\\-]?\\d+(\\.\\d+)?"),
-		func(node string) any {
-			if strings.ContainsAny(node, ".") {
-				val, err := strconv.ParseFloat(node, 64)
-				if err != nil {
-					panic(err)
-				}
-				return val
-			}
-			val, err := strconv.ParseInt(node, 10, 64)
-			if err != nil {
-				panic(err)
-			}
-			return val
-		}))
+// May be preceded with '+' or '-'. Accepts the literals of DefaultNumberDialect.
+var IntOrFloatParser = Named("int or float", NumberLiteral(DefaultNumberDialect))
 
 // Parses floating point numbers and returns a float64 result.
-// May be preceded with '+' or '-'.
-var FloatParser = Named("float",
-	Map(
-		Regex("[+\\-]?\\d+(\\.\\d+)?"),
-		func(node string) float64 {
-			val, err := strconv.ParseFloat(node, 64)
-			if err != nil {
-				panic(err)
-			}
-			return val
-		}))
+// May be preceded with '+' or '-'. Accepts the literals of DefaultNumberDialect.
+var FloatParser = Named("float", FloatLiteral(DefaultNumberDialect))
 
 // Parses integer numbers and returns an int64 result.
-// May be preceded with '+' or '-'.
-var IntParser = Named("integer",
-	Map(
-		Regex("[+\\-]?\\d+"),
-		func(node string) int64 {
-			val, err := strconv.ParseInt(node, 10, 64)
-			if err != nil {
-				panic(err)
-			}
-			return val
-		}))
+// May be preceded with '+' or '-'. Accepts the integer literals of DefaultNumberDialect.
+var IntParser = Named("integer", IntLiteral(DefaultNumberDialect))
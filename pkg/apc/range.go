@@ -17,9 +17,12 @@ func Range[CT, T any](min int, max int, parser Parser[CT, T]) Parser[CT, []T] {
 		panic("max must be either -1 (no limit) or > 0")
 	}
 
+	site := newRangeCoverageSite(min, max)
+
 	return func(ctx Context[CT]) ([]T, error) {
 		ctx.DebugStart("range: %v to %v", min, max)
 		defer ctx.DebugEnd("range: %v to %v", min, max)
+		cov := enterCoverage(ctx, site)
 
 		nodes := make([]T, 0)
 
@@ -42,6 +45,7 @@ func Range[CT, T any](min int, max int, parser Parser[CT, T]) Parser[CT, []T] {
 			}
 			return nil, ParseErrConsumedExpectedButGot(ctx, msg, len(nodes), err)
 		}
+		cov.hit(site.rangeBranch(len(nodes)))
 		return nodes, nil
 	}
 }
@@ -59,9 +63,12 @@ func OneOrMore[CT, T any](parser Parser[CT, T]) Parser[CT, []T] {
 // Same as Range(0, 1, parser), but with the resulting slice mapped
 // to a single value, or default T if 0 matches occurred.
 func Maybe[CT, T any](parser Parser[CT, T]) Parser[CT, MaybeValue[T]] {
+	site := newCoverageSite("maybe", "matched", "not matched")
+
 	return func(ctx Context[CT]) (MaybeValue[T], error) {
 		ctx.DebugStart("maybe")
 		defer ctx.DebugEnd("maybe")
+		cov := enterCoverage(ctx, site)
 
 		node, err := parser(ctx)
 		if IsMustReturnParseErr(err) {
@@ -69,8 +76,10 @@ func Maybe[CT, T any](parser Parser[CT, T]) Parser[CT, MaybeValue[T]] {
 		}
 
 		if err == nil {
+			cov.hit(0)
 			return NewMaybeValue(node), nil
 		}
+		cov.hit(1)
 		return NewNilMaybeValue[T](), nil
 	}
 }
@@ -106,3 +115,166 @@ func ZeroOrMoreSeparated[CT, T, U any](parser Parser[CT, T],
 			return node.Value()
 		})
 }
+
+// Returns a parser that runs parser exactly count times.
+// Returns each parser result in order as a slice.
+//
+// The count must be >= 0.
+func Count[CT, T any](count int, parser Parser[CT, T]) Parser[CT, []T] {
+	if count < 0 {
+		panic("count must be >= 0")
+	}
+
+	return func(ctx Context[CT]) ([]T, error) {
+		ctx.DebugStart("count: %v", count)
+		defer ctx.DebugEnd("count: %v", count)
+
+		nodes := make([]T, 0, count)
+		for len(nodes) < count {
+			node, err := parser(ctx)
+			if err != nil {
+				if IsMustReturnParseErr(err) {
+					return nil, err
+				}
+				msg := fmt.Sprintf("exactly %v of %v", count, ctx.GetCurParserName())
+				if len(nodes) == 0 {
+					return nil, ParseErrExpectedButGot(ctx, msg, len(nodes), err)
+				}
+				return nil, ParseErrConsumedExpectedButGot(ctx, msg, len(nodes), err)
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes, nil
+	}
+}
+
+// Same as OneOrMoreSeparated(parser, sepParser), but allows an optional trailing
+// match of sepParser after the last match of parser.
+func OneOrMoreSeparatedAllowTrailing[CT, T, U any](parser Parser[CT, T],
+	sepParser Parser[CT, U]) Parser[CT, []T] {
+	return func(ctx Context[CT]) ([]T, error) {
+		ctx.DebugStart("one or more separated allow trailing")
+		defer ctx.DebugEnd("one or more separated allow trailing")
+
+		node, err := parser(ctx)
+		if err != nil {
+			return nil, err
+		}
+		nodes := []T{node}
+		for {
+			_, err := sepParser(ctx)
+			if err != nil {
+				if IsMustReturnParseErr(err) {
+					return nil, err
+				}
+				return nodes, nil
+			}
+			node, err := parser(ctx)
+			if err != nil {
+				if IsMustReturnParseErr(err) {
+					return nil, err
+				}
+				return nodes, nil
+			}
+			nodes = append(nodes, node)
+		}
+	}
+}
+
+// Same as ZeroOrMoreSeparated(parser, sepParser), but allows an optional trailing
+// match of sepParser after the last match of parser.
+func ZeroOrMoreSeparatedAllowTrailing[CT, T, U any](parser Parser[CT, T],
+	sepParser Parser[CT, U]) Parser[CT, []T] {
+	return Map(
+		Maybe(OneOrMoreSeparatedAllowTrailing(parser, sepParser)),
+		func(node MaybeValue[[]T]) []T {
+			if node.IsNil() {
+				return []T{}
+			}
+			return node.Value()
+		})
+}
+
+// Returns a parser that runs parser zero or more times until endParser matches
+// (sometimes known as "many till"). The results of endParser are not returned.
+// Returns each parser result in order as a slice.
+//
+// If neither endParser nor parser match, a ParseErrorConsumed is returned if
+// any input was consumed.
+func ZeroOrMoreUntil[CT, T, U any](parser Parser[CT, T], endParser Parser[CT, U]) Parser[CT, []T] {
+	return func(ctx Context[CT]) ([]T, error) {
+		ctx.DebugStart("zero or more until")
+		defer ctx.DebugEnd("zero or more until")
+
+		nodes := make([]T, 0)
+		for {
+			_, err := endParser(ctx)
+			if err == nil {
+				return nodes, nil
+			}
+			if IsMustReturnParseErr(err) {
+				return nil, err
+			}
+
+			node, err := parser(ctx)
+			if err != nil {
+				if IsMustReturnParseErr(err) {
+					return nil, err
+				}
+				if len(nodes) == 0 {
+					return nil, ParseErrExpectedButGotNext(ctx, ctx.GetCurParserName(), err)
+				}
+				return nil, ParseErrConsumedExpectedButGotNext(ctx, ctx.GetCurParserName(), err)
+			}
+			nodes = append(nodes, node)
+		}
+	}
+}
+
+// Returns a parser that parses openParser, then parser, then closeParser, returning
+// the result of parser.
+//
+// If closeParser does not match, the returned ParseErrorConsumed reports the
+// opening match and its Origin, such as: unclosed '[' opened at file:3:4.
+func Between[CT, T, U, V any](openParser Parser[CT, U], closeParser Parser[CT, V],
+	parser Parser[CT, T]) Parser[CT, T] {
+	return func(ctx Context[CT]) (T, error) {
+		ctx.DebugStart("between")
+		defer ctx.DebugEnd("between")
+
+		err := ctx.RunSkipParsers()
+		if err != nil {
+			return zeroVal[T](), err
+		}
+		openOrigin := ctx.GetCurOrigin()
+		openNode, err := openParser(ctx)
+		if err != nil {
+			return zeroVal[T](), err
+		}
+
+		node, err := parser(ctx)
+		if err != nil {
+			if IsMustReturnParseErr(err) {
+				return zeroVal[T](), err
+			}
+			return zeroVal[T](), ParseErrConsumedExpectedButGotNext(ctx, ctx.GetCurParserName(), err)
+		}
+
+		_, err = closeParser(ctx)
+		if err != nil {
+			if IsMustReturnParseErr(err) {
+				return zeroVal[T](), err
+			}
+			openStr := anyConvertRunesToString(openNode)
+			if tok, ok := any(openNode).(Token); ok && tok.Raw != "" {
+				openStr = tok.Raw
+			}
+			return zeroVal[T](), &ParseErrorConsumed{
+				Err:     err,
+				Message: fmt.Sprintf("unclosed '%v' opened at %v", openStr, openOrigin),
+				Origin:  ctx.GetCurOrigin(),
+			}
+		}
+		return node, nil
+	}
+}
@@ -85,3 +85,103 @@ func TestOneOrMoreSeparatedParser(t *testing.T) {
 	_, err = p(ctx)
 	assert.ErrorIs(t, err, ErrParseErrConsumed)
 }
+
+func TestCountParser(t *testing.T) {
+	p := Count(3, ExactStr("hi"))
+
+	ctx := NewStringContext(testStringOrigin, "hihihihi")
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hi", "hi", "hi"}, node)
+	r, err := ctx.Peek(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []rune{'h'}, r)
+
+	ctx = NewStringContext(testStringOrigin, "hihi")
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+
+	ctx = NewStringContext(testStringOrigin, "__")
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	assert.NotErrorIs(t, err, ErrParseErrConsumed)
+
+	node, err = Count(0, ExactStr("hi"))(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, node)
+}
+
+func TestSeparatedAllowTrailingParsers(t *testing.T) {
+	p := OneOrMoreSeparatedAllowTrailing(IntParser, ExactStr(","))
+
+	for _, input := range []string{"55,66", "55,66,"} {
+		ctx := NewStringContext(testStringOrigin, input)
+		node, err := Parse[rune](ctx, p, DefaultParseConfig)
+		assert.NoError(t, err, input)
+		assert.Equal(t, []int64{55, 66}, node, input)
+	}
+
+	ctx := NewStringContext(testStringOrigin, "55,,")
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{55}, node)
+	r, err := ctx.Peek(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []rune{','}, r)
+
+	ctx = NewStringContext(testStringOrigin, ",")
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+
+	ctx = NewStringContext(testStringOrigin, "]")
+	nodes, err := ZeroOrMoreSeparatedAllowTrailing(IntParser, ExactStr(","))(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{}, nodes)
+}
+
+func TestZeroOrMoreUntilParser(t *testing.T) {
+	p := ZeroOrMoreUntil(Regex("[a-z]"), ExactStr("*/"))
+
+	ctx := NewStringContext(testStringOrigin, "abc*/d")
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, node)
+	r, err := ctx.Peek(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []rune{'d'}, r)
+
+	ctx = NewStringContext(testStringOrigin, "*/")
+	node, err = p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, node)
+
+	ctx = NewStringContext(testStringOrigin, "ab")
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+
+	ctx = NewStringContext(testStringOrigin, "1")
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	assert.NotErrorIs(t, err, ErrParseErrConsumed)
+}
+
+func TestBetweenParser(t *testing.T) {
+	p := Between(ExactStr("["), ExactStr("]"), ZeroOrMoreSeparated(IntParser, ExactStr(",")))
+
+	ctx := NewStringContext(testStringOrigin, "[1, 2]")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	node, err := Parse[rune](ctx, p, DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, node)
+
+	ctx = NewStringContext(testStringOrigin, "x")
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	assert.NotErrorIs(t, err, ErrParseErrConsumed)
+
+	ctx = NewStringContext(testStringOrigin, "\n\n   [1, 2 3")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	_, err = p(ctx)
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+	assert.Contains(t, err.Error(), "unclosed '[' opened at <origin>:3:4")
+}
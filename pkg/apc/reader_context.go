@@ -3,7 +3,6 @@ package apc
 import (
 	"bufio"
 	"errors"
-	"fmt"
 	"io"
 	"os"
 	"reflect"
@@ -20,36 +19,23 @@ type ReaderContext[CT any] struct {
 	// The elements in this slice must always correspond to the elements in buffer.
 	bufferOrigins []Origin
 	lastOrigin    Origin
-	// List of parsers to attempt to run, discarding their results if successful.
-	skipParsers []Parser[CT, any]
-	// Whether or not RunSkipParsers is currently running.
-	skipping bool
-	// Current parser name.
-	curParserName string
-	// Current look offset value.
-	lookOffset int
-	// Current debug indentation.
-	debugIndentation string
-	// Whether or not to enable debugging.
-	DebugParsers bool
-	// User data storage
-	userData any
+	// The Origin returned by reader along with ErrEOF, if reached.
+	eofOrigin    Origin
+	hasEOFOrigin bool
+	// Number of elements consumed outside of a Look frame.
+	consumedCount int
+	// Skip parsers, look offset, parser name, debugging and user data.
+	contextBase[CT]
 }
 
 // Returns a *ReaderContext[CT] with the given reader.
 func NewReaderContext[CT any](reader ReaderWithOrigin[CT]) *ReaderContext[CT] {
 	return &ReaderContext[CT]{
-		reader:           reader,
-		buffer:           make([]CT, 0),
-		bufferOrigins:    make([]Origin, 0),
-		lastOrigin:       Origin{},
-		skipParsers:      make([]Parser[CT, any], 0),
-		skipping:         false,
-		curParserName:    "<unknown>",
-		lookOffset:       InvalidLookOffset,
-		debugIndentation: "",
-		DebugParsers:     false,
-		userData:         nil,
+		reader:        reader,
+		buffer:        make([]CT, 0),
+		bufferOrigins: make([]Origin, 0),
+		lastOrigin:    Origin{},
+		contextBase:   newContextBase[CT](),
 	}
 }
 
@@ -58,6 +44,12 @@ func NewRuneReaderContext(originName string, reader io.RuneReader) *ReaderContex
 	return NewReaderContext[rune](NewRuneReaderWithOrigin(originName, reader))
 }
 
+// Returns a *ReaderContext[rune] from an io.RuneReader, reading runes and counting
+// Origins according to config.
+func NewRuneReaderContextWithConfig(originName string, reader io.RuneReader, config OriginConfig) *ReaderContext[rune] {
+	return NewReaderContext[rune](NewRuneReaderWithOriginConfig(originName, reader, config))
+}
+
 // Returns a *ReaderContext[byte] from an io.Reader.
 func NewByteReaderContext(originName string, reader io.Reader) *ReaderContext[byte] {
 	return NewReaderContext[byte](NewByteReaderWithOrigin(originName, reader))
@@ -68,6 +60,12 @@ func NewStringContext(originName string, data string) *ReaderContext[rune] {
 	return NewRuneReaderContext(originName, strings.NewReader(data))
 }
 
+// Returns a *ReaderContext[rune] from a string, reading runes and counting Origins
+// according to config.
+func NewStringContextWithConfig(originName string, data string, config OriginConfig) *ReaderContext[rune] {
+	return NewRuneReaderContextWithConfig(originName, strings.NewReader(data), config)
+}
+
 // Returns a *ReaderContext[rune] from a file.
 func NewFileContext(file *os.File) *ReaderContext[rune] {
 	return NewRuneReaderContext(file.Name(), bufio.NewReader(file))
@@ -101,6 +99,8 @@ func (ctx *ReaderContext[CT]) maybeEnsureBufferLoaded(num int) error {
 		val, origin, err = ctx.reader.Read()
 	}
 	if err != nil && errors.Is(err, ErrEOF) {
+		ctx.eofOrigin = origin
+		ctx.hasEOFOrigin = true
 		return nil
 	}
 	return err
@@ -138,6 +138,18 @@ func (ctx *ReaderContext[CT]) Peek(offset int, num int) ([]CT, error) {
 // with any consumed elements (which may be less than num elements in length
 // if end of input has been reached).
 func (ctx *ReaderContext[CT]) Consume(num int) ([]CT, error) {
+	if ctx.openSpans == 0 {
+		return ctx.consume(num)
+	}
+	pos, _ := ctx.position()
+	startOrigin := ctx.GetCurOrigin()
+	vals, err := ctx.consume(num)
+	ctx.logConsume(pos, vals, startOrigin, curOriginOf[CT](ctx))
+	return vals, err
+}
+
+// Implements Consume, without logging the consumed elements for any open spans.
+func (ctx *ReaderContext[CT]) consume(num int) ([]CT, error) {
 	lookOffset := 0
 	if ctx.lookOffset != InvalidLookOffset {
 		lookOffset = ctx.lookOffset
@@ -148,12 +160,21 @@ func (ctx *ReaderContext[CT]) Consume(num int) ([]CT, error) {
 		return nil, err
 	}
 	buf := ctx.buffer[lookOffset:]
+	if ctx.skipping && ctx.retainTrivia {
+		pos, _ := ctx.position()
+		if len(buf) < num {
+			ctx.appendTrivia(pos, buf)
+		} else {
+			ctx.appendTrivia(pos, buf[:num])
+		}
+	}
 	if len(buf) < num {
 		if ctx.lookOffset != InvalidLookOffset {
 			ctx.lookOffset += len(buf)
 		} else {
 			ctx.buffer = ctx.buffer[:0]
 			ctx.bufferOrigins = ctx.bufferOrigins[:0]
+			ctx.consumedCount += len(buf)
 		}
 		return buf, ErrEOF
 	}
@@ -163,10 +184,52 @@ func (ctx *ReaderContext[CT]) Consume(num int) ([]CT, error) {
 	} else {
 		ctx.buffer = ctx.buffer[num:]
 		ctx.bufferOrigins = ctx.bufferOrigins[num:]
+		ctx.consumedCount += num
 	}
 	return buf, nil
 }
 
+// Returns the absolute position of the next unconsumed element in the input
+// stream, taking the look offset into account.
+func (ctx *ReaderContext[CT]) position() (int, bool) {
+	if ctx.lookOffset == InvalidLookOffset {
+		return ctx.consumedCount, true
+	}
+	return ctx.consumedCount + ctx.lookOffset, true
+}
+
+// Removes and returns the read, but unconsumed, elements and their Origins, so that
+// the reader can return them again after other input. Also forgets the end of input.
+func (ctx *ReaderContext[CT]) takeUnread() ([]CT, []Origin) {
+	lookOffset := 0
+	if ctx.lookOffset != InvalidLookOffset {
+		lookOffset = ctx.lookOffset
+	}
+	if lookOffset > len(ctx.buffer) {
+		lookOffset = len(ctx.buffer)
+	}
+
+	vals := append([]CT(nil), ctx.buffer[lookOffset:]...)
+	origins := append([]Origin(nil), ctx.bufferOrigins[lookOffset:]...)
+	ctx.buffer = ctx.buffer[:lookOffset]
+	ctx.bufferOrigins = ctx.bufferOrigins[:lookOffset]
+	ctx.eofOrigin = Origin{}
+	ctx.hasEOFOrigin = false
+	return vals, origins
+}
+
+// Undoes takeUnread, returning the elements it removed to the buffer.
+func (ctx *ReaderContext[CT]) restoreUnread(vals []CT, origins []Origin) {
+	ctx.buffer = append(ctx.buffer, vals...)
+	ctx.bufferOrigins = append(ctx.bufferOrigins, origins...)
+}
+
+// Returns the Origin reported by the reader at the end of input, which is
+// just past the last element, if the end of input has been reached.
+func (ctx *ReaderContext[CT]) endOfInputOrigin() (Origin, bool) {
+	return ctx.eofOrigin, ctx.hasEOFOrigin
+}
+
 // Returns an Origin representing the next unconsumed element in the
 // input stream.
 func (ctx *ReaderContext[CT]) GetCurOrigin() Origin {
@@ -182,114 +245,34 @@ func (ctx *ReaderContext[CT]) GetCurOrigin() Origin {
 	return ctx.bufferOrigins[lookOffset]
 }
 
-// Adds the parser to the list of parsers that attempt to run when
-// RunSkipParsers is called. If the parser matches, its result will
-// be discarded. Duplicate parsers cannot be added.
-func (ctx *ReaderContext[CT]) AddSkipParser(parser Parser[CT, any]) {
-	for _, p := range ctx.skipParsers {
-		if &p == &parser {
-			panic("cannot add duplicate skip parser")
-		}
-	}
-	ctx.skipParsers = append(ctx.skipParsers, parser)
+// Opens a span logging the input consumed until it is closed.
+func (ctx *ReaderContext[CT]) openSpan() (spanStart, bool) {
+	return ctx.beginSpan(ctx), true
 }
 
-// Removes the parser from the list of parsers that attempt to run
-// when RunSkipParsers is called. If the parser has not been added,
-// the function panics.
-func (ctx *ReaderContext[CT]) RemoveSkipParser(parser Parser[CT, any]) {
-	i := -1
-	var p Parser[CT, any]
-	for i, p = range ctx.skipParsers {
-		if &p == &parser {
-			break
-		}
-	}
-	if i == -1 {
-		panic("cannot remove non-existent skip parser")
-	}
-	ctx.skipParsers = append(ctx.skipParsers[:i], ctx.skipParsers[i+1:]...)
+// Closes a span returned by openSpan, returning the OriginRange of the input consumed since.
+func (ctx *ReaderContext[CT]) closeSpan(start spanStart) OriginRange {
+	return ctx.endSpan(ctx, start)
 }
 
 // Attempts to run any added skip parsers as long as one of the parsers
 // successfully matches. The results of any matched parsers is discarded.
 // Should only return nil or non-ParseError errors.
 func (ctx *ReaderContext[CT]) RunSkipParsers() error {
-	if ctx.skipping {
-		return nil
-	}
-
-	ctx.skipping = true
-
-	skip := true
-	for skip {
-		skip = false
-		for _, parser := range ctx.skipParsers {
-			_, err := parser(ctx)
-			if err == nil {
-				skip = true
-				break
-			} else if IsMustReturnParseErr(err) {
-				ctx.skipping = false
-				return err
-			}
-		}
-	}
-
-	ctx.skipping = false
-	return nil
-}
-
-// Sets the look value.
-func (ctx *ReaderContext[CT]) SetLookOffset(val int) {
-	ctx.lookOffset = val
-}
-
-// Gets the look value.
-func (ctx *ReaderContext[CT]) GetLookOffset() int {
-	return ctx.lookOffset
-}
-
-// Sets the name of all subsequent parsers.
-func (ctx *ReaderContext[CT]) SetCurParserName(name string) {
-	ctx.curParserName = name
-}
-
-// Gets the current name of parsers.
-func (ctx *ReaderContext[CT]) GetCurParserName() string {
-	return ctx.curParserName
+	return ctx.runSkipParsers(ctx)
 }
 
 // TODO: document
 func (ctx *ReaderContext[CT]) DebugStart(format string, formatArgs ...interface{}) {
-	if !ctx.DebugParsers {
-		return
-	}
-	fmt.Printf("%vSTART: %v (in %v) @ %v\n", ctx.debugIndentation, fmt.Sprintf(format, formatArgs...), ctx.GetCurParserName(), ctx.GetCurOrigin())
-	ctx.debugIndentation += "  "
+	ctx.debugStart(ctx, format, formatArgs...)
 }
 
 // TODO: document
 func (ctx *ReaderContext[CT]) DebugPrint(format string, formatArgs ...interface{}) {
-	if !ctx.DebugParsers {
-		return
-	}
-	fmt.Printf("%vDEBUG: %v (in %v) @ %v\n", ctx.debugIndentation, fmt.Sprintf(format, formatArgs...), ctx.GetCurParserName(), ctx.GetCurOrigin())
+	ctx.debugPrint(ctx, format, formatArgs...)
 }
 
 // TODO: document
 func (ctx *ReaderContext[CT]) DebugEnd(format string, formatArgs ...interface{}) {
-	if !ctx.DebugParsers {
-		return
-	}
-	ctx.debugIndentation = ctx.debugIndentation[:len(ctx.debugIndentation)-2]
-	fmt.Printf("%vEND: %v @ %v\n", ctx.debugIndentation, fmt.Sprintf(format, formatArgs...), ctx.GetCurOrigin())
-}
-
-func (ctx *ReaderContext[CT]) SetUserData(data any) {
-	ctx.userData = data
-}
-
-func (ctx *ReaderContext[CT]) GetUserData() any {
-	return ctx.userData
+	ctx.debugEnd(ctx, format, formatArgs...)
 }
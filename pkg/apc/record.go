@@ -0,0 +1,303 @@
+package apc
+
+import (
+	"errors"
+	"strings"
+)
+
+// Implemented by contexts that know the absolute position of the next
+// unconsumed element in the input stream, counting from the start of input.
+type positionedContext interface {
+	position() (int, bool)
+}
+
+// Implemented by contexts that know the Origin just past the last element of
+// the input stream, once the end of input has been reached.
+type endOfInputContext interface {
+	endOfInputOrigin() (Origin, bool)
+}
+
+// Implemented by context wrappers that must observe a Look frame being committed.
+type lookCommitter interface {
+	commitLook(num int) error
+}
+
+// Implemented by contexts that wrap another Context[CT], such as recorders.
+type wrappingContext[CT any] interface {
+	unwrap() Context[CT]
+}
+
+// Returns the first context of type I found by walking the chain of
+// wrapped contexts starting at ctx.
+func findContext[I, CT any](ctx Context[CT]) (I, bool) {
+	for {
+		if ictx, ok := ctx.(I); ok {
+			return ictx, true
+		}
+		wctx, ok := ctx.(wrappingContext[CT])
+		if !ok {
+			return zeroVal[I](), false
+		}
+		ctx = wctx.unwrap()
+	}
+}
+
+// Returns the absolute position of the next unconsumed element of ctx, if known.
+// Context wrappers forward position to the context they wrap.
+func contextPosition[CT any](ctx Context[CT]) (int, bool) {
+	if pctx, ok := ctx.(positionedContext); ok {
+		return pctx.position()
+	}
+	return 0, false
+}
+
+// Returns the Origin just past the last element of ctx, if known and the end of
+// input has been reached. Context wrappers forward endOfInputOrigin to the context
+// they wrap.
+func endOfInputOriginOf[CT any](ctx Context[CT]) (Origin, bool) {
+	if ectx, ok := ctx.(endOfInputContext); ok {
+		return ectx.endOfInputOrigin()
+	}
+	return Origin{}, false
+}
+
+// Returns the Origin of the next unconsumed element of ctx. Unlike GetCurOrigin,
+// returns the Origin just past the last element once the end of input has been
+// reached, if ctx knows it.
+func curOriginOf[CT any](ctx Context[CT]) Origin {
+	if _, ok := ctx.(endOfInputContext); ok {
+		if _, err := ctx.Peek(0, 1); errors.Is(err, ErrEOF) {
+			if org, ok := endOfInputOriginOf(ctx); ok {
+				return org
+			}
+		}
+	}
+	return ctx.GetCurOrigin()
+}
+
+// Commits the elements consumed in the current Look frame of ctx, which must
+// be the outermost Look frame.
+func commitLook[CT any](ctx Context[CT], num int) error {
+	if lctx, ok := ctx.(lookCommitter); ok {
+		return lctx.commitLook(num)
+	}
+	ctx.SetLookOffset(InvalidLookOffset)
+	_, err := ctx.Consume(num)
+	return err
+}
+
+// A single call to Consume seen by a consumeRecorder[CT].
+type recordedConsume[CT any] struct {
+	// Absolute positions of the first consumed element and just past the last one.
+	startPos int
+	endPos   int
+	// Origins of the first consumed element and just past the last one.
+	startOrigin Origin
+	endOrigin   Origin
+	// The consumed elements; only the first and last if keepAll is false.
+	vals []CT
+}
+
+// consumeRecorder[CT] wraps a Context[CT] and keeps track of the elements
+// consumed through it, so that the span and text of a parse can be determined.
+//
+// Skip parsers run on the wrapped context, so input they consume is never recorded.
+// Consumptions undone by a Look frame backtracking are discarded, as long as the
+// wrapped context knows its position.
+type consumeRecorder[CT any] struct {
+	Context[CT]
+	// Whether or not to keep every consumed element, rather than only the first and last.
+	keepAll bool
+	// The Origin of the next unconsumed element when the recorder was created.
+	createOrigin Origin
+	// The recorded consumptions, in input order.
+	consumes []recordedConsume[CT]
+	// The coverageContext[CT] of the wrapped context, if any.
+	cov *coverageContext[CT]
+}
+
+// Returns a *consumeRecorder[CT] wrapping ctx.
+func newConsumeRecorder[CT any](ctx Context[CT], keepAll bool) *consumeRecorder[CT] {
+	return &consumeRecorder[CT]{
+		Context:      ctx,
+		keepAll:      keepAll,
+		createOrigin: curOriginOf(ctx),
+		consumes:     make([]recordedConsume[CT], 0),
+		cov:          coverageOf(ctx),
+	}
+}
+
+// Advances the input stream by num elements, returning the consumed
+// elements, and records them.
+func (r *consumeRecorder[CT]) Consume(num int) ([]CT, error) {
+	startPos, hasPos := contextPosition(r.Context)
+	startOrigin := r.Context.GetCurOrigin()
+	vals, err := r.Context.Consume(num)
+	if len(vals) == 0 {
+		return vals, err
+	}
+
+	if hasPos {
+		r.discardAfter(startPos)
+	}
+	rec := recordedConsume[CT]{
+		startPos:    startPos,
+		endPos:      startPos + len(vals),
+		startOrigin: startOrigin,
+		endOrigin:   curOriginOf(r.Context),
+	}
+	if r.keepAll {
+		rec.vals = append([]CT(nil), vals...)
+	} else {
+		rec.vals = []CT{vals[0], vals[len(vals)-1]}
+	}
+	r.consumes = append(r.consumes, rec)
+	r.maybeCollapse()
+	return vals, err
+}
+
+// Commits the outermost Look frame without recording the committed elements
+// again, as they were recorded when consumed in the Look frame.
+func (r *consumeRecorder[CT]) commitLook(num int) error {
+	err := commitLook(r.Context, num)
+	r.maybeCollapse()
+	return err
+}
+
+func (r *consumeRecorder[CT]) coverage() *coverageContext[CT] {
+	return r.cov
+}
+
+func (r *consumeRecorder[CT]) position() (int, bool) {
+	return contextPosition(r.Context)
+}
+
+func (r *consumeRecorder[CT]) endOfInputOrigin() (Origin, bool) {
+	return endOfInputOriginOf(r.Context)
+}
+
+func (r *consumeRecorder[CT]) openSpan() (spanStart, bool) {
+	return openSpanOf(r.Context)
+}
+
+func (r *consumeRecorder[CT]) closeSpan(start spanStart) OriginRange {
+	return r.Context.(spanLogger[CT]).closeSpan(start)
+}
+
+func (r *consumeRecorder[CT]) unwrap() Context[CT] {
+	return r.Context
+}
+
+// Discards recorded consumptions that end after the position pos, as they
+// were undone by backtracking.
+func (r *consumeRecorder[CT]) discardAfter(pos int) {
+	for len(r.consumes) > 0 && r.consumes[len(r.consumes)-1].endPos > pos {
+		r.consumes = r.consumes[:len(r.consumes)-1]
+	}
+}
+
+// Merges the recorded consumptions into one if no Look frame is active,
+// since they can no longer be undone.
+func (r *consumeRecorder[CT]) maybeCollapse() {
+	if len(r.consumes) < 2 || r.Context.GetLookOffset() != InvalidLookOffset {
+		return
+	}
+	first := r.consumes[0]
+	last := r.consumes[len(r.consumes)-1]
+	merged := recordedConsume[CT]{
+		startPos:    first.startPos,
+		endPos:      last.endPos,
+		startOrigin: first.startOrigin,
+		endOrigin:   last.endOrigin,
+	}
+	if r.keepAll {
+		for _, rec := range r.consumes {
+			merged.vals = append(merged.vals, rec.vals...)
+		}
+	} else {
+		merged.vals = []CT{first.vals[0], last.vals[len(last.vals)-1]}
+	}
+	r.consumes = append(r.consumes[:0], merged)
+}
+
+// Returns the recorded consumptions not undone by backtracking.
+func (r *consumeRecorder[CT]) validConsumes() []recordedConsume[CT] {
+	if pos, ok := contextPosition(r.Context); ok {
+		r.discardAfter(pos)
+	}
+	return r.consumes
+}
+
+// Returns the Origin of the first consumed element, or the Origin of the next
+// unconsumed element when the recorder was created if nothing was consumed.
+func (r *consumeRecorder[CT]) startOrigin() Origin {
+	consumes := r.validConsumes()
+	if len(consumes) == 0 {
+		return r.createOrigin
+	}
+	return consumes[0].startOrigin
+}
+
+// Returns the elements consumed since the recorder was created.
+// Only returns every element if keepAll is true; otherwise returns
+// the first and last elements.
+func (r *consumeRecorder[CT]) consumedElements() []CT {
+	consumes := r.validConsumes()
+	if len(consumes) == 0 {
+		return nil
+	}
+	if !r.keepAll {
+		last := consumes[len(consumes)-1].vals
+		return []CT{consumes[0].vals[0], last[len(last)-1]}
+	}
+	vals := make([]CT, 0)
+	for _, rec := range consumes {
+		vals = append(vals, rec.vals...)
+	}
+	return vals
+}
+
+// Returns the OriginRange of the consumed elements vals, from the Origin of the
+// first consumed element to the Origin just past the last consumed element.
+// Input consumed by skip parsers before or after the consumed elements is excluded.
+func (r *consumeRecorder[CT]) originRange(vals []CT) OriginRange {
+	consumes := r.validConsumes()
+	orgRange := OriginRange{Start: r.createOrigin, End: r.createOrigin}
+	if len(consumes) > 0 {
+		orgRange.Start = consumes[0].startOrigin
+		orgRange.End = consumes[len(consumes)-1].endOrigin
+	}
+	return tokenSpan(vals, orgRange)
+}
+
+// Returns orgRange, but if vals are Tokens with known OriginRanges, the returned
+// range spans from the start of the first Token to the end of the last Token.
+func tokenSpan[CT any](vals []CT, orgRange OriginRange) OriginRange {
+	if toks, ok := any(vals).([]Token); ok && len(toks) > 0 {
+		if first := toks[0].OriginRange; !first.IsZero() {
+			orgRange.Start = first.Start
+		}
+		if last := toks[len(toks)-1].OriginRange; !last.IsZero() {
+			orgRange.End = last.End
+		}
+	}
+	return orgRange
+}
+
+// Returns the raw text of vals if they are runes, bytes or Tokens with raw text.
+func rawTextOf[CT any](vals []CT) string {
+	switch cvals := any(vals).(type) {
+	case []rune:
+		return string(cvals)
+	case []byte:
+		return string(cvals)
+	case []Token:
+		var sb strings.Builder
+		for _, tok := range cvals {
+			sb.WriteString(tok.Raw)
+		}
+		return sb.String()
+	default:
+		return ""
+	}
+}
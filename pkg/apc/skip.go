@@ -1,23 +1,102 @@
 package apc
 
+// Identifies a skip parser added to a Context, to remove or disable it with.
+type SkipHandle struct {
+	id uint64
+}
+
+// A named set of skip parsers, which can replace those of a Context as a unit.
+// For example, a set named "inside string" without any parsers prevents skipping
+// inside string literals.
+type SkipSet[CT any] struct {
+	// The name of the set, for debugging.
+	Name string
+	// The parsers to attempt to run when RunSkipParsers is called.
+	Parsers []Parser[CT, any]
+}
+
+// Returns a SkipSet[CT] with the given name and parsers.
+func NewSkipSet[CT any](name string, parsers ...Parser[CT, any]) SkipSet[CT] {
+	return SkipSet[CT]{
+		Name:    name,
+		Parsers: parsers,
+	}
+}
+
+// A skip parser and the handle it was added with.
+type registeredSkipParser[CT any] struct {
+	handle SkipHandle
+	parser Parser[CT, any]
+	// The number of DisableSkipParser calls not yet reverted; the parser
+	// is only run if 0.
+	disabled int
+}
+
+// The skip parsers of a Context, and those of the skip sets pushed over.
+type skipState[CT any] struct {
+	parsers []registeredSkipParser[CT]
+	// The name of the current skip set, and the state it was pushed over;
+	// nil if no skip set has been pushed.
+	setName string
+	saved   *skipState[CT]
+}
+
+// Implemented by contexts whose skip state can be saved and restored.
+type skipStateSaver[CT any] interface {
+	saveSkipState() skipState[CT]
+	restoreSkipState(state skipState[CT])
+}
+
+// Returns a function that restores the skip state of ctx to the current one,
+// or does nothing if ctx cannot save its skip state.
+func saveSkipStateOf[CT any](ctx Context[CT]) func() {
+	saver, ok := findContext[skipStateSaver[CT]](ctx)
+	if !ok {
+		return func() {}
+	}
+	state := saver.saveSkipState()
+	return func() {
+		saver.restoreSkipState(state)
+	}
+}
+
 // Returns a parser that temporarily adds the skipParser to the Context
 // while parsing with parser.
 func Skip[CT, T any](skipParser Parser[CT, any], parser Parser[CT, T]) Parser[CT, T] {
 	return func(ctx Context[CT]) (T, error) {
-		ctx.AddSkipParser(skipParser)
-		defer ctx.RemoveSkipParser(skipParser)
+		handle := ctx.AddSkipParser(skipParser)
+		defer ctx.RemoveSkipParser(handle)
 
 		node, err := parser(ctx)
 		return node, err
 	}
 }
 
-// Returns a parser that temporarily removes the skipParser from the Context
-// while parsing with parser.
-func Unskip[CT, T any](skipParser Parser[CT, any], parser Parser[CT, T]) Parser[CT, T] {
+// Returns a parser that temporarily disables the skip parser added with the
+// handle while parsing with parser. If the skip parser is not in the current
+// skip set, the parser panics.
+func Unskip[CT, T any](handle SkipHandle, parser Parser[CT, T]) Parser[CT, T] {
+	return func(ctx Context[CT]) (T, error) {
+		ctx.DisableSkipParser(handle)
+		defer ctx.EnableSkipParser(handle)
+
+		node, err := parser(ctx)
+		return node, err
+	}
+}
+
+// Returns a parser that parses with parser without running any skip parsers,
+// by temporarily pushing an empty skip set named "no skip".
+func NoSkip[CT, T any](parser Parser[CT, T]) Parser[CT, T] {
+	return WithSkipSet(NewSkipSet[CT]("no skip"), parser)
+}
+
+// Returns a parser that temporarily pushes the skip set to the Context while
+// parsing with parser, so that only the parsers of set are run as skip parsers.
+func WithSkipSet[CT, T any](set SkipSet[CT], parser Parser[CT, T]) Parser[CT, T] {
 	return func(ctx Context[CT]) (T, error) {
-		ctx.RemoveSkipParser(skipParser)
-		defer ctx.AddSkipParser(skipParser)
+		ctx.PushSkipSet(set)
+		defer ctx.PopSkipSet()
 
 		node, err := parser(ctx)
 		return node, err
@@ -25,9 +25,8 @@ func TestSkipParser(t *testing.T) {
 
 func TestUnskipParser(t *testing.T) {
 	ctx := NewStringContext(testStringOrigin, " hi hi_")
-	wsp := CastToAny(WhitespaceParser)
-	ctx.AddSkipParser(wsp)
-	p := Unskip(wsp, ExactStr("hi"))
+	handle := ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	p := Unskip(handle, ExactStr("hi"))
 
 	_, err := p(ctx)
 	assert.ErrorIs(t, err, ErrParseErr)
@@ -49,3 +48,186 @@ func TestUnskipParser(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []rune{'_'}, r)
 }
+
+func TestSkipParserHandles(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, " \t hi")
+	wsp := CastToAny(WhitespaceParser)
+	first := ctx.AddSkipParser(wsp)
+	second := ctx.AddSkipParser(wsp)
+	assert.NotEqual(t, first, second)
+
+	ctx.RemoveSkipParser(first)
+	assert.Panics(t, func() { ctx.RemoveSkipParser(first) })
+	node, err := ExactStr("hi")(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", node)
+
+	// Parsers added before a skip set was pushed cannot be removed until it is popped.
+	ctx.PushSkipSet(NewSkipSet[rune]("empty"))
+	assert.Panics(t, func() { ctx.RemoveSkipParser(second) })
+	assert.Equal(t, "empty", ctx.PopSkipSet())
+	ctx.RemoveSkipParser(second)
+	assert.Panics(t, func() { ctx.PopSkipSet() })
+}
+
+func TestWithSkipSetParser(t *testing.T) {
+	var setName string
+	p := Seq3(
+		ExactStr("a"),
+		WithSkipSet(NewSkipSet("comma", CastToAny(ExactStr(","))), Seq(
+			ExactStr("b"),
+			func(ctx Context[rune]) (string, error) {
+				setName = ctx.GetSkipSetName()
+				return ExactStr("c")(ctx)
+			},
+		)),
+		ExactStr("d"),
+	)
+
+	ctx := NewStringContext(testStringOrigin, "a,b,, c d")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	_, err := p(ctx)
+	assert.ErrorContains(t, err, "1:6: expected c but got  ")
+
+	ctx = NewStringContext(testStringOrigin, " a,b,,c d")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &Seq3Node[string, []string, string]{"a", []string{"b", "c"}, "d"}, node)
+	assert.Equal(t, "comma", setName)
+	assert.Equal(t, "", ctx.GetSkipSetName())
+}
+
+func TestLookRestoresSkipState(t *testing.T) {
+	wsp := CastToAny(WhitespaceParser)
+	changeSkipState := func(parser Parser[rune, string]) Parser[rune, string] {
+		return func(ctx Context[rune]) (string, error) {
+			ctx.AddSkipParser(wsp)
+			ctx.PushSkipSet(NewSkipSet[rune]("comma", CastToAny(ExactStr(","))))
+			return parser(ctx)
+		}
+	}
+
+	ctx := NewStringContext(testStringOrigin, " ,x")
+	ctx.AddSkipParser(wsp)
+	_, err := Look(changeSkipState(ExactStr("y")))(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	assert.Equal(t, "", ctx.GetSkipSetName())
+	_, err = ExactStr(",")(ctx)
+	assert.NoError(t, err)
+
+	// Panics revert the skip state too
+	ctx = NewStringContext(testStringOrigin, " ,x")
+	assert.Panics(t, func() {
+		Look(changeSkipState(func(ctx Context[rune]) (string, error) {
+			panic("parser panicked")
+		}))(ctx)
+	})
+	assert.Equal(t, "", ctx.GetSkipSetName())
+	assert.Equal(t, InvalidLookOffset, ctx.GetLookOffset())
+	_, err = ExactStr(" ")(ctx)
+	assert.NoError(t, err)
+
+	// Successful parsing keeps the skip state
+	ctx = NewStringContext(testStringOrigin, ",,x,,y")
+	_, err = Look(changeSkipState(ExactStr("x")))(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "comma", ctx.GetSkipSetName())
+	_, err = ExactStr("y")(ctx)
+	assert.NoError(t, err)
+}
+
+func TestSkipParserPanicDoesNotDisableSkipping(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "  x")
+	panicking := ctx.AddSkipParser(func(ctx Context[rune]) (any, error) {
+		panic("skip parser panicked")
+	})
+	assert.Panics(t, func() { ctx.RunSkipParsers() })
+
+	ctx.RemoveSkipParser(panicking)
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	_, err := ExactStr("x")(ctx)
+	assert.NoError(t, err)
+}
+
+func TestUnskipParserKeepsOtherSkipParsers(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, ", hi,hi")
+	handle := ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	ctx.AddSkipParser(CastToAny(ExactStr(",")))
+	p := Unskip(handle, ExactStr("hi"))
+
+	_, err := p(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	ctx.Consume(1)
+
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", node)
+	assert.Panics(t, func() { Unskip(SkipHandle{}, ExactStr("hi"))(ctx) })
+}
+
+func TestUnskipParserKeepsHandles(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, ", hi hi")
+	handle := ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	comma := ctx.AddSkipParser(CastToAny(ExactStr(",")))
+	p := Unskip(handle, func(ctx Context[rune]) (string, error) {
+		// Handles added outside of the Unskip remain valid within it
+		ctx.RemoveSkipParser(comma)
+		return ExactStr("hi")(ctx)
+	})
+
+	_, err := p(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	ctx.Consume(2)
+	comma = ctx.AddSkipParser(CastToAny(ExactStr(",")))
+
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", node)
+
+	// The handle remains valid after the Unskip
+	node, err = ExactStr("hi")(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", node)
+	ctx.RemoveSkipParser(handle)
+	assert.Panics(t, func() { ctx.EnableSkipParser(handle) })
+}
+
+func TestUnskipParserNested(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, " hi")
+	handle := ctx.AddSkipParser(CastToAny(WhitespaceParser))
+
+	_, err := Unskip(handle, Unskip(handle, ExactStr("hi")))(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	assert.Panics(t, func() { ctx.EnableSkipParser(handle) })
+
+	node, err := ExactStr("hi")(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", node)
+}
+
+func TestUnskipParserEmbeddingContext(t *testing.T) {
+	// Contexts embedding a Context[CT] support Unskip
+	ctx := struct{ Context[rune] }{NewStringContext(testStringOrigin, " hi")}
+	handle := ctx.AddSkipParser(CastToAny(WhitespaceParser))
+
+	_, err := Unskip(handle, ExactStr("hi"))(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	node, err := ExactStr("hi")(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", node)
+}
+
+func TestNoSkipParser(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, " ,hi")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	ctx.AddSkipParser(CastToAny(ExactStr(",")))
+	p := NoSkip(ExactStr("hi"))
+
+	_, err := p(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	ctx.Consume(2)
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", node)
+}
@@ -0,0 +1,283 @@
+package apc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A position in a SliceContext that it can be restored to.
+type SliceCheckpoint struct {
+	// The index of the next unconsumed element.
+	offset int
+}
+
+// SliceContext implements Context[CT] over a slice of elements already in memory,
+// such as the Tokens of a whole file lexed up front. Peek and Consume are O(1) and
+// return subslices of the elements without copying them.
+//
+// Like a StringContext, a SliceContext allows random access: it can be restored
+// to a checkpoint, and return any element or its Origin.
+type SliceContext[CT any] struct {
+	name    string
+	vals    []CT
+	origins []Origin
+	// The Origin just past the last element, if known.
+	eofOrigin    Origin
+	hasEOFOrigin bool
+	// Number of elements consumed outside of a Look frame.
+	consumedCount int
+	// Skip parsers, look offset, parser name, debugging and user data.
+	contextBase[CT]
+}
+
+// Returns a *SliceContext[CT] of vals, where origins holds the Origin of each
+// element of vals, optionally followed by the Origin just past the last element.
+// If origins is nil, each element is given the Origin of its index (at line 1,
+// column index+1) with the given origin name.
+//
+// Panics if origins is not nil and does not have len(vals) or len(vals)+1 elements.
+func NewSliceContext[CT any](originName string, vals []CT, origins []Origin) *SliceContext[CT] {
+	ctx := &SliceContext[CT]{
+		name:        originName,
+		vals:        vals,
+		contextBase: newContextBase[CT](),
+	}
+	switch {
+	case origins == nil:
+		ctx.eofOrigin = Origin{Name: originName, LineNum: 1, ColNum: len(vals) + 1}
+		ctx.hasEOFOrigin = true
+	case len(origins) == len(vals)+1:
+		ctx.origins = origins[:len(vals):len(vals)]
+		ctx.eofOrigin = origins[len(vals)]
+		ctx.hasEOFOrigin = true
+	case len(origins) == len(vals):
+		ctx.origins = origins
+	default:
+		panic(fmt.Sprintf("%v origins given for %v elements", len(origins), len(vals)))
+	}
+	return ctx
+}
+
+// Returns the number of elements of the input.
+func (ctx *SliceContext[CT]) Len() int {
+	return len(ctx.vals)
+}
+
+// Returns the element at index, which must be in [0, Len()).
+func (ctx *SliceContext[CT]) At(index int) CT {
+	return ctx.vals[index]
+}
+
+// Returns the elements in [start, end) without copying them, with the indices
+// clamped to the input.
+func (ctx *SliceContext[CT]) Slice(start int, end int) []CT {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(ctx.vals) {
+		end = len(ctx.vals)
+	}
+	if start >= end {
+		return nil
+	}
+	return ctx.vals[start:end:end]
+}
+
+// Returns the index of the next unconsumed element, taking the look offset into account.
+func (ctx *SliceContext[CT]) offset() int {
+	return ctx.consumedCount + ctx.lookOffsetOrZero()
+}
+
+// Returns the elements in [start, start+num), or fewer with ErrEOF if the end of input is reached.
+func (ctx *SliceContext[CT]) slice(start int, num int) ([]CT, error) {
+	if start > len(ctx.vals) {
+		start = len(ctx.vals)
+	}
+	end := start + num
+	if end > len(ctx.vals) {
+		return ctx.vals[start:len(ctx.vals):len(ctx.vals)], ErrEOF
+	}
+	return ctx.vals[start:end:end], nil
+}
+
+// Returns a []CT of num elements beginning at offset without consuming
+// the elements.
+// The offset is a non-negative value relative to the next unconsumed
+// element in the input stream.
+//
+// If the end of input is reached, an EOFError is returned along
+// with any peeked elements (which may be less than num elements in length
+// if end of input has been reached).
+func (ctx *SliceContext[CT]) Peek(offset int, num int) ([]CT, error) {
+	return ctx.slice(ctx.offset()+offset, num)
+}
+
+// Advances the input stream by num elements, returning the consumed
+// elements.
+//
+// If the end of input is reached, an EOFError is returned along
+// with any consumed elements (which may be less than num elements in length
+// if end of input has been reached).
+func (ctx *SliceContext[CT]) Consume(num int) ([]CT, error) {
+	pos := ctx.offset()
+	vals, err := ctx.slice(pos, num)
+	if ctx.skipping && ctx.retainTrivia {
+		ctx.appendTrivia(pos, vals)
+	}
+	if ctx.lookOffset != InvalidLookOffset {
+		ctx.lookOffset += len(vals)
+	} else {
+		ctx.consumedCount += len(vals)
+	}
+	if ctx.openSpans > 0 {
+		ctx.logConsume(pos, vals, ctx.OriginAt(pos), ctx.OriginAt(pos+len(vals)))
+	}
+	return vals, err
+}
+
+// Returns the absolute position of the next unconsumed element in the input
+// stream, taking the look offset into account.
+func (ctx *SliceContext[CT]) position() (int, bool) {
+	return ctx.offset(), true
+}
+
+// Returns the Origin of the element at index, or the Origin just past the last
+// element if index is Len() and that Origin is known.
+func (ctx *SliceContext[CT]) OriginAt(index int) Origin {
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(ctx.vals) {
+		if ctx.hasEOFOrigin {
+			return ctx.eofOrigin
+		}
+		index = len(ctx.vals) - 1
+	}
+	if index < 0 {
+		return Origin{}
+	}
+	if ctx.origins == nil {
+		return Origin{Name: ctx.name, LineNum: 1, ColNum: index + 1}
+	}
+	return ctx.origins[index]
+}
+
+// Returns an Origin representing the next unconsumed element in the
+// input stream. At the end of input, it is the Origin of the last element.
+func (ctx *SliceContext[CT]) GetCurOrigin() Origin {
+	offset := ctx.offset()
+	if offset >= len(ctx.vals) {
+		if len(ctx.vals) == 0 {
+			return Origin{}
+		}
+		offset = len(ctx.vals) - 1
+	}
+	return ctx.OriginAt(offset)
+}
+
+// Returns the Origin just past the last element, if known.
+func (ctx *SliceContext[CT]) endOfInputOrigin() (Origin, bool) {
+	return ctx.eofOrigin, ctx.hasEOFOrigin
+}
+
+// Returns a checkpoint of the next unconsumed element, taking the look offset into account.
+func (ctx *SliceContext[CT]) Checkpoint() SliceCheckpoint {
+	return SliceCheckpoint{offset: ctx.offset()}
+}
+
+// Restores the next unconsumed element to that of checkpoint, which may be before
+// or after it. In a Look frame, the checkpoint must not be before the start of
+// the outermost Look frame, or the function panics.
+func (ctx *SliceContext[CT]) Restore(checkpoint SliceCheckpoint) {
+	if ctx.lookOffset == InvalidLookOffset {
+		ctx.consumedCount = checkpoint.offset
+		return
+	}
+	if checkpoint.offset < ctx.consumedCount {
+		panic("cannot restore a checkpoint before the start of the outermost Look frame")
+	}
+	ctx.lookOffset = checkpoint.offset - ctx.consumedCount
+}
+
+// Opens a span logging the input consumed until it is closed.
+func (ctx *SliceContext[CT]) openSpan() (spanStart, bool) {
+	return ctx.beginSpan(ctx), true
+}
+
+// Closes a span returned by openSpan, returning the OriginRange of the input consumed since.
+func (ctx *SliceContext[CT]) closeSpan(start spanStart) OriginRange {
+	return ctx.endSpan(ctx, start)
+}
+
+// Attempts to run any added skip parsers as long as one of the parsers
+// successfully matches. The results of any matched parsers is discarded.
+// Should only return nil or non-ParseError errors.
+func (ctx *SliceContext[CT]) RunSkipParsers() error {
+	return ctx.runSkipParsers(ctx)
+}
+
+// Prints the start of a debug section, if debugging is enabled.
+func (ctx *SliceContext[CT]) DebugStart(format string, formatArgs ...interface{}) {
+	ctx.debugStart(ctx, format, formatArgs...)
+}
+
+// Prints a debug message, if debugging is enabled.
+func (ctx *SliceContext[CT]) DebugPrint(format string, formatArgs ...interface{}) {
+	ctx.debugPrint(ctx, format, formatArgs...)
+}
+
+// Prints the end of a debug section, if debugging is enabled.
+func (ctx *SliceContext[CT]) DebugEnd(format string, formatArgs ...interface{}) {
+	ctx.debugEnd(ctx, format, formatArgs...)
+}
+
+// Runs lexer repeatedly over all of ctx, after running any skip parsers of ctx,
+// and returns a *SliceContext[T] of the results and the Origins they started at.
+// The Origin just past the last result is that of the end of ctx.
+//
+// Returns the first error of lexer, which is a ParseError at the Origin it
+// occurred if lexer failed to match, unless only input matched by the skip parsers
+// remained. Returns a ParseError if lexer matched without consuming any input; if
+// ctx does not know its position, this is detected by its Origin not advancing.
+func LexAll[T any](ctx Context[rune], lexer Parser[rune, T]) (*SliceContext[T], error) {
+	var vals []T
+	var origins []Origin
+	reader := NewParseReader(ctx, lexer)
+	for {
+		if _, err := ctx.Peek(0, 1); errors.Is(err, ErrEOF) {
+			break
+		}
+		startPos, hasPos := contextPosition(ctx)
+		var startOrigin Origin
+		if !hasPos {
+			startOrigin = curOriginOf(ctx)
+		}
+		val, origin, err := reader.Read()
+		if err != nil {
+			if _, peekErr := ctx.Peek(0, 1); errors.Is(peekErr, ErrEOF) && (errors.Is(err, ErrEOF) || !IsMustReturnParseErr(err)) {
+				// The skip parsers consumed the rest of the input
+				break
+			}
+			return nil, err
+		}
+		var progressed bool
+		if hasPos {
+			pos, _ := contextPosition(ctx)
+			progressed = pos != startPos
+		} else if _, peekErr := ctx.Peek(0, 1); errors.Is(peekErr, ErrEOF) {
+			// The input was not at EOF before the read, and the origin at EOF
+			// may be that of the last rune without an end of input origin.
+			progressed = true
+		} else {
+			progressed = curOriginOf(ctx) != startOrigin
+		}
+		if !progressed {
+			// The lexer would match forever.
+			return nil, &ParseError{Message: "lexer matched without consuming input", Origin: origin}
+		}
+		vals = append(vals, val)
+		origins = append(origins, origin)
+	}
+	eofOrigin := curOriginOf(ctx)
+	return NewSliceContext(eofOrigin.Name, vals, append(origins, eofOrigin)), nil
+}
@@ -0,0 +1,150 @@
+package apc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLexer() Parser[rune, Token] {
+	return Any(
+		BindToToken(IdentifierParser, testTokenTypeIdent),
+		BindToToken(IntParser, testTokenTypeInt),
+	)
+}
+
+func TestSliceContextBasic(t *testing.T) {
+	ctx := NewSliceContext("test", []byte("abc"), nil)
+	val, err := ctx.Peek(1, 5)
+	assert.ErrorIs(t, err, ErrEOF)
+	assert.Equal(t, []byte("bc"), val)
+
+	val, err = ctx.Consume(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ab"), val)
+	assert.Equal(t, Origin{Name: "test", LineNum: 1, ColNum: 3}, ctx.GetCurOrigin())
+	_, err = ctx.Consume(2)
+	assert.ErrorIs(t, err, ErrEOF)
+	assert.Equal(t, Origin{Name: "test", LineNum: 1, ColNum: 3}, ctx.GetCurOrigin())
+	assert.Equal(t, Origin{Name: "test", LineNum: 1, ColNum: 4}, curOriginOf[byte](ctx))
+
+	assert.Equal(t, byte('b'), ctx.At(1))
+	assert.Equal(t, []byte("bc"), ctx.Slice(1, 10))
+	assert.Nil(t, ctx.Slice(2, 1))
+
+	empty := NewSliceContext[byte]("test", nil, nil)
+	assert.Equal(t, Origin{}, empty.GetCurOrigin())
+	assert.Panics(t, func() { NewSliceContext("test", []byte("ab"), []Origin{{}}) })
+}
+
+func TestSliceContextOrigins(t *testing.T) {
+	origins := []Origin{
+		{Name: "test", LineNum: 1, ColNum: 1},
+		{Name: "test", LineNum: 2, ColNum: 4},
+	}
+	ctx := NewSliceContext("test", []rune("ab"), origins)
+	assert.Equal(t, origins[1], ctx.OriginAt(1))
+	// Without an end of input Origin, the last Origin is used.
+	assert.Equal(t, origins[1], ctx.OriginAt(2))
+	_, hasEOFOrigin := ctx.endOfInputOrigin()
+	assert.False(t, hasEOFOrigin)
+
+	eofOrigin := Origin{Name: "test", LineNum: 2, ColNum: 5}
+	ctx = NewSliceContext("test", []rune("ab"), append(origins, eofOrigin))
+	assert.Equal(t, eofOrigin, ctx.OriginAt(2))
+	node, err := Span(Seq(Exact('a'), Exact('b')))(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, OriginRange{Start: origins[0], End: eofOrigin}, node.OriginRange)
+}
+
+func TestSliceContextCheckpoint(t *testing.T) {
+	ctx := NewSliceContext("test", []rune("abcd"), nil)
+	start := ctx.Checkpoint()
+	_, _ = ctx.Consume(3)
+	end := ctx.Checkpoint()
+	ctx.Restore(start)
+	val, _ := ctx.Peek(0, 1)
+	assert.Equal(t, []rune("a"), val)
+
+	ctx.SetLookOffset(0)
+	ctx.Restore(end)
+	assert.Equal(t, 3, ctx.GetLookOffset())
+	ctx.SetLookOffset(InvalidLookOffset)
+	_, _ = ctx.Consume(1)
+	ctx.SetLookOffset(0)
+	assert.Panics(t, func() { ctx.Restore(start) })
+}
+
+func TestLexAll(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "  abc\n 0x1F x ")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	tokens, err := LexAll(ctx, newTestLexer())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, tokens.Len())
+	assert.Equal(t, Token{
+		Type:        testTokenTypeInt,
+		Value:       int64(31),
+		OriginRange: testOriginRange(2, 2, 2, 6),
+		Raw:         "0x1F",
+	}, tokens.At(1))
+	assert.Equal(t, Origin{Name: testStringOrigin, LineNum: 1, ColNum: 3}, tokens.OriginAt(0))
+	assert.Equal(t, Origin{Name: testStringOrigin, LineNum: 2, ColNum: 9}, tokens.OriginAt(3))
+
+	// Parsing backtracks over the tokens.
+	p := Any(
+		Look(Seq(ExactTokenType(testTokenTypeIdent), ExactTokenType(testTokenTypeIdent))),
+		Seq(ExactTokenType(testTokenTypeIdent), ExactTokenType(testTokenTypeInt), ExactTokenType(testTokenTypeIdent)),
+	)
+	node, err := Parse[Token](tokens, p, DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Len(t, node, 3)
+
+	_, err = Parse[Token](NewSliceContext("test", tokens.Slice(0, 2), nil), p, DefaultParseConfig)
+	assert.ErrorContains(t, err, "expected token of type ident but got EOF")
+}
+
+func TestLexAllError(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "abc\n 12 !x")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	_, err := LexAll(ctx, newTestLexer())
+	var perr *ParseError
+	if assert.True(t, errors.As(err, &perr)) {
+		assert.Equal(t, Origin{Name: testStringOrigin, LineNum: 2, ColNum: 5}, perr.Origin)
+	}
+
+	ctx = NewStringContext(testStringOrigin, "ab")
+	_, err = LexAll(ctx, Maybe(ExactStr("a")))
+	assert.ErrorContains(t, err, "lexer matched without consuming input")
+
+	tokens, err := LexAll(NewStringContext(testStringOrigin, ""), newTestLexer())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, tokens.Len())
+
+	// An error at the end of input after consuming input is still returned
+	ctx = NewStringContext(testStringOrigin, "ab \"")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	_, err = LexAll(ctx, Any(CastToAny(IdentifierParser), CastToAny(Seq(ExactStr("\""), ExactStr("\"")))))
+	assert.ErrorIs(t, err, ErrParseErrConsumed)
+}
+
+// Wraps a Context[CT] without exposing its position.
+type unpositionedContext[CT any] struct {
+	Context[CT]
+}
+
+func TestLexAllWithoutPosition(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, " abc 12 ")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	tokens, err := LexAll[Token](unpositionedContext[rune]{ctx}, newTestLexer())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, tokens.Len())
+
+	// A final one rune token ends at the origin of its own rune
+	letters, err := LexAll[string](unpositionedContext[rune]{NewStringContext(testStringOrigin, "ab")}, Regex("[a-z]"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, letters.Len())
+
+	_, err = LexAll(unpositionedContext[rune]{NewStringContext(testStringOrigin, "ab")}, Maybe(ExactStr("a")))
+	assert.ErrorContains(t, err, "lexer matched without consuming input")
+}
@@ -0,0 +1,179 @@
+package apc
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Maps generated lines, such as those of preprocessed input, to lines of the
+// source they were generated from.
+type SourceMapping struct {
+	// The first generated line the mapping applies to. It applies to each following
+	// line until the next mapping.
+	GeneratedLine int
+	// The name of the original source. If empty, the name of the generated input is kept.
+	Name string
+	// The line in the original source of GeneratedLine. Each following generated
+	// line maps to the following original line.
+	LineNum int
+}
+
+// Returns the line number and, if specified, source name of a line directive
+// such as `#line 12 "file.c"`, or false if line is not a line directive.
+// line excludes its line terminator.
+type LineDirectiveFunc func(line string) (lineNum int, name string, ok bool)
+
+// Returns a LineDirectiveFunc matching lines to the regex pattern, which must
+// match the whole line. The line number is the submatch named "line", and the
+// optional source name is the submatch named "name".
+func RegexLineDirective(pattern string) LineDirectiveFunc {
+	re := regexp.MustCompile(pattern)
+	lineIndex, nameIndex := re.SubexpIndex("line"), re.SubexpIndex("name")
+	if lineIndex < 0 {
+		panic("line directive pattern must have a submatch named 'line'")
+	}
+	return func(line string) (int, string, bool) {
+		loc := re.FindStringSubmatchIndex(line)
+		if loc == nil || loc[0] != 0 || loc[1] != len(line) {
+			return 0, "", false
+		}
+		lineNum, err := strconv.Atoi(line[loc[2*lineIndex]:loc[2*lineIndex+1]])
+		if err != nil {
+			return 0, "", false
+		}
+		name := ""
+		if nameIndex >= 0 && loc[2*nameIndex] >= 0 {
+			name = line[loc[2*nameIndex]:loc[2*nameIndex+1]]
+		}
+		return lineNum, name, true
+	}
+}
+
+// Matches C preprocessor line directives: `#line 12 "file.c"`, `#line 12`, and the
+// `# 12 "file.c" 2` form of preprocessor output, ignoring any trailing flags.
+var CLineDirective = RegexLineDirective(`\s*#\s*(?:line\s+)?(?P<line>[0-9]+)(?:\s+"(?P<name>[^"]*)")?(?:\s+[0-9]+)*\s*`)
+
+// SourceMapConfig contains settings for a SourceMapReader.
+type SourceMapConfig struct {
+	// Recognizes line directives, which are removed from the input and map the
+	// lines following them. Nil to not recognize line directives.
+	LineDirective LineDirectiveFunc
+	// Mappings of generated lines, in addition to those of line directives.
+	Mappings []SourceMapping
+}
+
+// A sane default for SourceMapConfig, recognizing C line directives.
+var DefaultSourceMapConfig = SourceMapConfig{
+	LineDirective: CLineDirective,
+}
+
+// SourceMapReader implements ReaderWithOrigin[rune] by reading preprocessed input
+// from a reader, remapping the Origin of each rune to the original source as
+// given by line directives and mappings. Columns are not remapped.
+//
+// Input is read a line at a time to recognize line directives.
+type SourceMapReader struct {
+	reader   ReaderWithOrigin[rune]
+	config   SourceMapConfig
+	mappings []SourceMapping
+	// The mapping applied to the current line, if any.
+	active *SourceMapping
+	// The unreturned runes of the current line, and their generated Origins.
+	line        []rune
+	lineOrigins []Origin
+	// The error that ended the current line, if any.
+	lineErr       error
+	lineErrOrigin Origin
+}
+
+// Returns a *SourceMapReader of the preprocessed input read from reader.
+func NewSourceMapReader(reader ReaderWithOrigin[rune], config SourceMapConfig) *SourceMapReader {
+	mappings := append([]SourceMapping(nil), config.Mappings...)
+	sort.SliceStable(mappings, func(i, j int) bool {
+		return mappings[i].GeneratedLine < mappings[j].GeneratedLine
+	})
+	return &SourceMapReader{
+		reader:   reader,
+		config:   config,
+		mappings: mappings,
+	}
+}
+
+// Returns a *ReaderContext[rune] of a SourceMapReader of the preprocessed input
+// read from reader, with the given origin name.
+func NewSourceMapContext(originName string, reader io.RuneReader, config SourceMapConfig) *ReaderContext[rune] {
+	return NewReaderContext[rune](NewSourceMapReader(NewRuneReaderWithOrigin(originName, reader), config))
+}
+
+// Returns the next rune and its remapped Origin.
+// If an error occurs or if no rune is available, an error is returned.
+func (r *SourceMapReader) Read() (rune, Origin, error) {
+	for len(r.line) == 0 {
+		if r.lineErr != nil {
+			return rune(-1), r.remap(r.lineErrOrigin), r.lineErr
+		}
+		r.readLine()
+	}
+	val, origin := r.line[0], r.lineOrigins[0]
+	r.line, r.lineOrigins = r.line[1:], r.lineOrigins[1:]
+	return val, r.remap(origin), nil
+}
+
+// Reads the next line, including its line terminator, removing it if it is a line directive.
+func (r *SourceMapReader) readLine() {
+	for {
+		val, origin, err := r.reader.Read()
+		if err != nil {
+			r.lineErr, r.lineErrOrigin = err, origin
+			break
+		}
+		r.line = append(r.line, val)
+		r.lineOrigins = append(r.lineOrigins, origin)
+		if val == '\n' {
+			break
+		}
+	}
+	if len(r.line) == 0 || r.config.LineDirective == nil {
+		return
+	}
+
+	text := strings.TrimSuffix(strings.TrimSuffix(string(r.line), "\n"), "\r")
+	lineNum, name, ok := r.config.LineDirective(text)
+	if !ok {
+		return
+	}
+	// The line after the directive is the given line
+	mapping := SourceMapping{
+		GeneratedLine: r.lineOrigins[0].LineNum + 1,
+		Name:          name,
+		LineNum:       lineNum,
+	}
+	if name == "" && r.active != nil {
+		// A directive without a name keeps the current original source
+		mapping.Name = r.active.Name
+	}
+	r.active = &mapping
+	r.line, r.lineOrigins = r.line[:0], r.lineOrigins[:0]
+}
+
+// Returns the original Origin of the generated Origin origin.
+func (r *SourceMapReader) remap(origin Origin) Origin {
+	for len(r.mappings) > 0 && r.mappings[0].GeneratedLine <= origin.LineNum {
+		if r.active == nil || r.mappings[0].GeneratedLine >= r.active.GeneratedLine {
+			mapping := r.mappings[0]
+			r.active = &mapping
+		}
+		r.mappings = r.mappings[1:]
+	}
+	if r.active == nil || origin.LineNum < r.active.GeneratedLine {
+		return origin
+	}
+	if r.active.Name != "" {
+		origin.Name = r.active.Name
+	}
+	origin.LineNum = r.active.LineNum + origin.LineNum - r.active.GeneratedLine
+	return origin
+}
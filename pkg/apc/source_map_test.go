@@ -0,0 +1,95 @@
+package apc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseSourceMapped(t *testing.T, input string, config SourceMapConfig) ([]string, error) {
+	ctx := NewSourceMapContext("gen", strings.NewReader(input), config)
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	entries, err := Parse[rune](ctx, newTestIncludeParser(), DefaultParseConfig)
+	var origins []string
+	for _, entry := range entries {
+		origins = append(origins, entry.OriginRange.String())
+	}
+	return origins, err
+}
+
+func TestSourceMapLineDirectives(t *testing.T) {
+	input := "#line 10 \"orig.c\"\n" +
+		"a = 1\n" +
+		"  b = 2\n" +
+		"# 40 \"other.h\" 1 3\n" +
+		"c = 3\n" +
+		"#line 7\r\n" +
+		"d = 4"
+	origins, err := parseSourceMapped(t, input, DefaultSourceMapConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"orig.c:10:1-10:2",
+		"orig.c:11:3-11:4",
+		"other.h:40:1-40:2",
+		"other.h:7:1-7:2",
+	}, origins)
+}
+
+func TestSourceMapMappings(t *testing.T) {
+	config := SourceMapConfig{
+		Mappings: []SourceMapping{
+			{GeneratedLine: 4, LineNum: 1},
+			{GeneratedLine: 2, Name: "x.conf", LineNum: 100},
+		},
+	}
+	origins, err := parseSourceMapped(t, "a = 1\nb = 2\nc = 3\nd = 4\n", config)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"gen:1:1-1:2",
+		"x.conf:100:1-100:2",
+		"x.conf:101:1-101:2",
+		"gen:1:1-1:2",
+	}, origins)
+
+	// Line directives are not recognized without a LineDirectiveFunc
+	_, err = parseSourceMapped(t, "#line 2\na = 1", SourceMapConfig{})
+	assert.ErrorContains(t, err, "gen:1:1")
+}
+
+func TestSourceMapParseError(t *testing.T) {
+	_, err := parseSourceMapped(t, "a = 1\n#line 5 \"orig.c\"\nb = x", DefaultSourceMapConfig)
+	assert.ErrorContains(t, err, "orig.c:5:5")
+	assert.Equal(t, "orig.c", NewDiagnostic(err).OriginRange.Start.Name)
+}
+
+func TestRegexLineDirective(t *testing.T) {
+	directive := RegexLineDirective(`//@line (?P<line>[0-9]+)(?: (?P<name>\S+))?`)
+	lineNum, name, ok := directive("//@line 12 a.tmpl")
+	assert.True(t, ok)
+	assert.Equal(t, 12, lineNum)
+	assert.Equal(t, "a.tmpl", name)
+	lineNum, name, ok = directive("//@line 3")
+	assert.True(t, ok)
+	assert.Equal(t, 3, lineNum)
+	assert.Equal(t, "", name)
+	_, _, ok = directive("x //@line 3")
+	assert.False(t, ok)
+
+	origins, err := parseSourceMapped(t, "//@line 8 a.tmpl\na = 1", SourceMapConfig{LineDirective: directive})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.tmpl:8:1-8:2"}, origins)
+
+	assert.Panics(t, func() { RegexLineDirective(`#(?P<num>[0-9]+)`) })
+}
+
+func TestCLineDirective(t *testing.T) {
+	for _, line := range []string{`#line 1 "a.c"`, `  #  line 1`, `# 1 "a.c" 1 3 4`, `#1`} {
+		_, _, ok := CLineDirective(line)
+		assert.True(t, ok, line)
+	}
+	for _, line := range []string{`#include "a.h"`, `#line`, `x #line 1`, `#line 1 a.c`} {
+		_, _, ok := CLineDirective(line)
+		assert.False(t, ok, line)
+	}
+}
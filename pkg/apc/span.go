@@ -0,0 +1,188 @@
+package apc
+
+import "sort"
+
+// Spanned[T] holds the result of a parser along with the OriginRange
+// of the input it matched.
+type Spanned[T any] struct {
+	Value       T
+	OriginRange OriginRange
+}
+
+// Returns a parser that wraps the result of parser in a Spanned[T] recording
+// the OriginRange of the matched input.
+//
+// The OriginRange starts at the first element consumed by parser, after running
+// any skip parsers, and ends at the Origin just past the last element consumed
+// by parser. Input consumed by skip parsers before or after the matched input,
+// including the end of input, is excluded. If parser consumes nothing, the
+// OriginRange is empty and located at the next unconsumed element.
+func Span[CT, T any](parser Parser[CT, T]) Parser[CT, Spanned[T]] {
+	return func(ctx Context[CT]) (Spanned[T], error) {
+		err := ctx.RunSkipParsers()
+		if err != nil {
+			return Spanned[T]{}, err
+		}
+
+		start, ok := openSpanOf(ctx)
+		if !ok {
+			recorder := newConsumeRecorder(ctx, false)
+			node, err := parser(recorder)
+			if err != nil {
+				return Spanned[T]{}, err
+			}
+			return Spanned[T]{
+				Value:       node,
+				OriginRange: recorder.originRange(recorder.consumedElements()),
+			}, nil
+		}
+
+		logger := ctx.(spanLogger[CT])
+		closed := false
+		defer func() {
+			// Close the span even if parser panics, so the log is not kept
+			if !closed {
+				logger.closeSpan(start)
+			}
+		}()
+		node, err := parser(ctx)
+		orgRange := logger.closeSpan(start)
+		closed = true
+		if err != nil {
+			return Spanned[T]{}, err
+		}
+		return Spanned[T]{
+			Value:       node,
+			OriginRange: orgRange,
+		}, nil
+	}
+}
+
+// Implemented by contexts that log the input consumed while a span is open, so
+// that Span does not need to wrap them in a consumeRecorder. Context wrappers
+// forward openSpan and closeSpan to the context they wrap.
+type spanLogger[CT any] interface {
+	// Opens a span at the next unconsumed element. Returns false if the context
+	// cannot log its input, such as if it wraps one that cannot.
+	openSpan() (spanStart, bool)
+	// Closes a span returned by openSpan, returning the OriginRange of the input
+	// consumed since, as by Span.
+	closeSpan(start spanStart) OriginRange
+}
+
+// Opens a span of ctx, if ctx logs the input consumed while a span is open.
+func openSpanOf[CT any](ctx Context[CT]) (spanStart, bool) {
+	if lctx, ok := ctx.(spanLogger[CT]); ok {
+		return lctx.openSpan()
+	}
+	return spanStart{}, false
+}
+
+// The state of a context when a span was opened.
+type spanStart struct {
+	// The position and Origin of the next unconsumed element.
+	pos    int
+	origin Origin
+	// Whether or not skip parsers were running, in which case the input they
+	// consume is part of the span.
+	skipping bool
+}
+
+// A single call to Consume logged while a span is open, or several merged once
+// the span containing them was closed.
+type loggedConsume[CT any] struct {
+	// Absolute positions of the first consumed element and just past the last one.
+	startPos int
+	endPos   int
+	// Origins of the first consumed element and just past the last one.
+	startOrigin Origin
+	endOrigin   Origin
+	// The first and last consumed elements.
+	first CT
+	last  CT
+	// Whether or not the elements were consumed by skip parsers.
+	skipped bool
+}
+
+// Opens a span of outer, the Context embedding the contextBase, logging the input
+// consumed until it is closed.
+func (ctx *contextBase[CT]) beginSpan(outer Context[CT]) spanStart {
+	pos, _ := contextPosition(outer)
+	ctx.openSpans++
+	return spanStart{
+		pos:      pos,
+		origin:   curOriginOf(outer),
+		skipping: ctx.skipping,
+	}
+}
+
+// Logs vals, consumed from startOrigin to endOrigin at the position pos, if a
+// span is open. Discards any logged input after pos, as it was undone by backtracking.
+func (ctx *contextBase[CT]) logConsume(pos int, vals []CT, startOrigin Origin, endOrigin Origin) {
+	if ctx.openSpans == 0 || len(vals) == 0 {
+		return
+	}
+	ctx.discardLogAfter(pos)
+	ctx.consumeLog = append(ctx.consumeLog, loggedConsume[CT]{
+		startPos:    pos,
+		endPos:      pos + len(vals),
+		startOrigin: startOrigin,
+		endOrigin:   endOrigin,
+		first:       vals[0],
+		last:        vals[len(vals)-1],
+		skipped:     ctx.skipping,
+	})
+}
+
+// Discards logged input that ends after the position pos.
+func (ctx *contextBase[CT]) discardLogAfter(pos int) {
+	for len(ctx.consumeLog) > 0 && ctx.consumeLog[len(ctx.consumeLog)-1].endPos > pos {
+		ctx.consumeLog = ctx.consumeLog[:len(ctx.consumeLog)-1]
+	}
+}
+
+// Closes a span of outer, the Context embedding the contextBase, returning the
+// OriginRange of the input consumed since it was opened.
+//
+// The logged input of the span is merged into a single entry, as any spans still
+// open only need its first and last elements.
+func (ctx *contextBase[CT]) endSpan(outer Context[CT], start spanStart) OriginRange {
+	pos, _ := contextPosition(outer)
+	ctx.discardLogAfter(pos)
+	index := sort.Search(len(ctx.consumeLog), func(i int) bool {
+		return ctx.consumeLog[i].startPos >= start.pos
+	})
+	first, last := index, len(ctx.consumeLog)-1
+	if !start.skipping {
+		for first <= last && ctx.consumeLog[first].skipped {
+			first++
+		}
+		for last >= first && ctx.consumeLog[last].skipped {
+			last--
+		}
+	}
+
+	orgRange := OriginRange{Start: start.origin, End: start.origin}
+	if first <= last {
+		merged := loggedConsume[CT]{
+			startPos:    ctx.consumeLog[first].startPos,
+			endPos:      ctx.consumeLog[last].endPos,
+			startOrigin: ctx.consumeLog[first].startOrigin,
+			endOrigin:   ctx.consumeLog[last].endOrigin,
+			first:       ctx.consumeLog[first].first,
+			last:        ctx.consumeLog[last].last,
+			skipped:     start.skipping,
+		}
+		orgRange = tokenSpan([]CT{merged.first, merged.last}, OriginRange{
+			Start: merged.startOrigin,
+			End:   merged.endOrigin,
+		})
+		ctx.consumeLog = append(append(ctx.consumeLog[:first], merged), ctx.consumeLog[last+1:]...)
+	}
+
+	ctx.openSpans--
+	if ctx.openSpans == 0 {
+		ctx.consumeLog = ctx.consumeLog[:0]
+	}
+	return orgRange
+}
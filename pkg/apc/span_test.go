@@ -0,0 +1,133 @@
+package apc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanParser(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "  ab  cd  ;")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	p := Span(OneOrMore(Regex("[a-z]+")))
+
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ab", "cd"}, node.Value)
+	// Trailing whitespace consumed by the failing Regex is excluded.
+	assert.Equal(t, testOriginRange(1, 3, 1, 9), node.OriginRange)
+}
+
+func TestSpanParserEndOfInput(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "ab\ncd")
+	p := Span(Seq2(ExactStr("ab\n"), ExactStr("cd")))
+
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, testOriginRange(1, 1, 2, 3), node.OriginRange)
+}
+
+func TestSpanParserBacktracking(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "a b c")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	p := Span(Seq2(
+		ExactStr("a"),
+		Maybe(Look(Seq2(ExactStr("b"), ExactStr("x")))),
+	))
+
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, testOriginRange(1, 1, 1, 2), node.OriginRange)
+
+	ctx = NewStringContext(testStringOrigin, "a b c")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	p = Span(Seq2(
+		ExactStr("a"),
+		Maybe(Look(Seq2(ExactStr("b"), ExactStr("c")))),
+	))
+	node, err = p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, testOriginRange(1, 1, 1, 6), node.OriginRange)
+}
+
+func TestSpanParserNothingConsumed(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "  x")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	p := Span(Maybe(ExactStr("y")))
+
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, testOriginRange(1, 3, 1, 3), node.OriginRange)
+
+	_, err = Span(ExactStr("y"))(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+}
+
+func TestSpanParserTokens(t *testing.T) {
+	ctx := newTestTokenContext("abc  123 ")
+	p := Span(Seq2(ExactTokenType(testTokenTypeIdent), ExactTokenType(testTokenTypeInt)))
+
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "123", node.Value.Result2.Raw)
+	assert.Equal(t, testOriginRange(1, 1, 1, 9), node.OriginRange)
+}
+
+func TestSpanParserNested(t *testing.T) {
+	newContexts := map[string]func(input string) Context[rune]{
+		"reader": func(input string) Context[rune] { return NewStringContext(testStringOrigin, input) },
+		"indexed": func(input string) Context[rune] {
+			return NewIndexedStringContext(testStringOrigin, input)
+		},
+		// Without a spanLogger, spans are recorded by wrapping the context
+		"unlogged": func(input string) Context[rune] {
+			return unpositionedContext[rune]{NewStringContext(testStringOrigin, input)}
+		},
+	}
+	for name, newContext := range newContexts {
+		ctx := newContext("[a [b c ] ];")
+		ctx.AddSkipParser(CastToAny(WhitespaceParser))
+		var list Parser[rune, Spanned[[]any]]
+		list = Span(Map(
+			Seq3(ExactStr("["), ZeroOrMore(Any(CastToAny(Regex("[a-z]")), CastToAny(Ref(&list)))), ExactStr("]")),
+			func(node *Seq3Node[string, []any, string]) []any {
+				return node.Result2
+			},
+		))
+
+		node, err := list(ctx)
+		assert.NoError(t, err, name)
+		assert.Equal(t, testOriginRange(1, 1, 1, 12), node.OriginRange, name)
+		inner := node.Value[1].(Spanned[[]any])
+		assert.Equal(t, testOriginRange(1, 4, 1, 10), inner.OriginRange, name)
+	}
+}
+
+func TestSpanParserLog(t *testing.T) {
+	ctx := NewStringContext(testStringOrigin, "a b c d")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	inner := Span(Seq2(ExactStr("b"), ExactStr("c")))
+	var logLen int
+	p := Span(Seq3(ExactStr("a"), inner, Map(ExactStr("d"), func(node string) string {
+		logLen = len(ctx.consumeLog)
+		return node
+	})))
+
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, testOriginRange(1, 1, 1, 8), node.OriginRange)
+	assert.Equal(t, testOriginRange(1, 3, 1, 6), node.Value.Result2.OriginRange)
+	// "a", the whitespace before "b", the merged "b c", the whitespace and "d"
+	assert.Equal(t, 5, logLen)
+	assert.Empty(t, ctx.consumeLog)
+	assert.Equal(t, 0, ctx.openSpans)
+
+	assert.Panics(t, func() {
+		_, _ = Span(Map(ExactStr("x"), func(node string) string { panic("test") }))(NewStringContext(testStringOrigin, "x"))
+	})
+	ctx = NewStringContext(testStringOrigin, "x")
+	assert.Panics(t, func() {
+		_, _ = Span(Map(ExactStr("x"), func(node string) string { panic("test") }))(ctx)
+	})
+	assert.Equal(t, 0, ctx.openSpans)
+}
@@ -0,0 +1,320 @@
+package apc
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// A position in a StringContext that it can be restored to.
+type StringCheckpoint struct {
+	// The offset in runes of the next unconsumed rune.
+	offset int
+}
+
+// StringContext implements Context[rune] directly over an in-memory string,
+// without a reader or per-element Origins. As Peek and Consume return []rune, the
+// string is decoded to runes once, taking 4 bytes per rune rather than the string
+// itself; Peek and Consume are then O(1) and return slices of those runes without
+// copying them, and Origins are computed when requested from a table of line starts.
+//
+// Unlike a ReaderContext, a StringContext allows random access: it can be restored
+// to a checkpoint, and return any substring or the Origin of any offset of the
+// input.
+type StringContext struct {
+	name   string
+	data   string
+	config OriginConfig
+	// The runes of data, as read according to config.
+	runes []rune
+	// The byte offset in data of every byteIndexInterval-th rune; nil if each
+	// rune is read from a single byte.
+	byteIndex []int
+	// The rune and byte offsets at which each line starts.
+	lineStarts     []int
+	lineByteStarts []int
+	// The line of the last Origin computed, as Origins are usually requested in order.
+	lastLine int
+	// The columns counted on line colLine up to the rune at colOffset, at byte
+	// colByte, so that columns not counted by rune are counted from there.
+	colLine   int
+	colOffset int
+	colByte   int
+	columns   columnCounter
+	// Number of runes consumed outside of a Look frame.
+	consumedCount int
+	// Skip parsers, look offset, parser name, debugging and user data.
+	contextBase[rune]
+}
+
+// The number of runes between the entries of StringContext.byteIndex.
+const byteIndexInterval = 64
+
+// Returns a *StringContext of data, with the given origin name, using
+// DefaultOriginConfig.
+func NewIndexedStringContext(originName string, data string) *StringContext {
+	return NewIndexedStringContextWithConfig(originName, data, DefaultOriginConfig)
+}
+
+// Returns a *StringContext of data, with the given origin name, reading runes and
+// counting Origins according to config.
+func NewIndexedStringContextWithConfig(originName string, data string, config OriginConfig) *StringContext {
+	ctx := &StringContext{
+		name:           originName,
+		data:           data,
+		config:         config,
+		runes:          make([]rune, 0, utf8.RuneCountInString(data)),
+		lineStarts:     []int{0},
+		lineByteStarts: []int{0},
+		colLine:        -1,
+		contextBase:    newContextBase[rune](),
+	}
+	for i := 0; i < len(data); {
+		if len(ctx.runes)%byteIndexInterval == 0 {
+			ctx.byteIndex = append(ctx.byteIndex, i)
+		}
+		rn, size := ctx.decodeAt(i)
+		ctx.runes = append(ctx.runes, rn)
+		i += size
+		if rn == '\n' {
+			ctx.lineStarts = append(ctx.lineStarts, len(ctx.runes))
+			ctx.lineByteStarts = append(ctx.lineByteStarts, i)
+		}
+	}
+	if len(ctx.runes) == len(data) {
+		ctx.byteIndex = nil
+	}
+	return ctx
+}
+
+// Returns the rune read at byte offset i of the input and the number of bytes it
+// was read from, translating newlines if configured.
+func (ctx *StringContext) decodeAt(i int) (rune, int) {
+	b := ctx.data[i]
+	if b == '\r' && ctx.config.TranslateNewlines {
+		if i+1 < len(ctx.data) && ctx.data[i+1] == '\n' {
+			return '\n', 2
+		}
+		return '\n', 1
+	}
+	if b < utf8.RuneSelf {
+		return rune(b), 1
+	}
+	return utf8.DecodeRuneInString(ctx.data[i:])
+}
+
+// Returns the byte offset in the input of the rune at offset, which must not be negative.
+func (ctx *StringContext) byteOffset(offset int) int {
+	if ctx.byteIndex == nil {
+		return offset
+	}
+	if offset >= len(ctx.runes) {
+		return len(ctx.data)
+	}
+	i := ctx.byteIndex[offset/byteIndexInterval]
+	for n := offset % byteIndexInterval; n > 0; n-- {
+		_, size := ctx.decodeAt(i)
+		i += size
+	}
+	return i
+}
+
+// Returns the number of runes of the input.
+func (ctx *StringContext) Len() int {
+	return len(ctx.runes)
+}
+
+// Returns the offset in runes of the next unconsumed rune, taking the look offset into account.
+func (ctx *StringContext) offset() int {
+	return ctx.consumedCount + ctx.lookOffsetOrZero()
+}
+
+// Returns the runes in [start, start+num), or fewer with ErrEOF if the end of input is reached.
+func (ctx *StringContext) slice(start int, num int) ([]rune, error) {
+	if start > len(ctx.runes) {
+		start = len(ctx.runes)
+	}
+	end := start + num
+	if end > len(ctx.runes) {
+		return ctx.runes[start:len(ctx.runes):len(ctx.runes)], ErrEOF
+	}
+	return ctx.runes[start:end:end], nil
+}
+
+// Returns a []rune of num elements beginning at offset without consuming
+// the elements.
+// The offset is a non-negative value relative to the next unconsumed
+// element in the input stream.
+//
+// If the end of input is reached, an EOFError is returned along
+// with any peeked elements (which may be less than num elements in length
+// if end of input has been reached).
+func (ctx *StringContext) Peek(offset int, num int) ([]rune, error) {
+	return ctx.slice(ctx.offset()+offset, num)
+}
+
+// Advances the input stream by num elements, returning the consumed
+// elements.
+//
+// If the end of input is reached, an EOFError is returned along
+// with any consumed elements (which may be less than num elements in length
+// if end of input has been reached).
+func (ctx *StringContext) Consume(num int) ([]rune, error) {
+	pos := ctx.offset()
+	vals, err := ctx.slice(pos, num)
+	if ctx.skipping && ctx.retainTrivia {
+		ctx.appendTrivia(pos, vals)
+	}
+	if ctx.lookOffset != InvalidLookOffset {
+		ctx.lookOffset += len(vals)
+	} else {
+		ctx.consumedCount += len(vals)
+	}
+	if ctx.openSpans > 0 {
+		ctx.logConsume(pos, vals, ctx.OriginAt(pos), ctx.OriginAt(pos+len(vals)))
+	}
+	return vals, err
+}
+
+// Returns the absolute position of the next unconsumed element in the input
+// stream, taking the look offset into account.
+func (ctx *StringContext) position() (int, bool) {
+	return ctx.offset(), true
+}
+
+// Returns the Origin of the rune at offset, or just past the last rune if offset
+// is the length of the input.
+func (ctx *StringContext) OriginAt(offset int) Origin {
+	if offset < 0 {
+		offset = 0
+	} else if offset > len(ctx.runes) {
+		offset = len(ctx.runes)
+	}
+
+	line := ctx.lastLine
+	if offset < ctx.lineStarts[line] || (line+1 < len(ctx.lineStarts) && offset >= ctx.lineStarts[line+1]) {
+		line = sort.SearchInts(ctx.lineStarts, offset+1) - 1
+		ctx.lastLine = line
+	}
+	return Origin{
+		Name:    ctx.name,
+		LineNum: line + 1,
+		ColNum:  ctx.column(line, offset),
+	}
+}
+
+// Returns the column of the rune at offset on line, or just past the last rune if
+// offset is the length of the input.
+func (ctx *StringContext) column(line int, offset int) int {
+	start := ctx.lineStarts[line]
+	if ctx.config.ColumnUnit == ColumnUnitRune && ctx.config.TabWidth <= 0 {
+		return offset - start + 1
+	}
+
+	if line != ctx.colLine || offset < ctx.colOffset {
+		ctx.colLine, ctx.colOffset, ctx.colByte = line, start, ctx.lineByteStarts[line]
+		ctx.columns = columnCounter{config: ctx.config}
+		ctx.columns.startLine()
+	}
+	for ; ctx.colOffset < offset; ctx.colOffset++ {
+		rn, size := ctx.decodeAt(ctx.colByte)
+		ctx.columns.advance(rn, size)
+		ctx.colByte += size
+	}
+	if offset == len(ctx.runes) || ctx.runes[offset] == '\n' {
+		return ctx.columns.col
+	}
+	// Counting the rune itself gives the column of its grapheme cluster
+	columns := ctx.columns
+	rn, size := ctx.decodeAt(ctx.colByte)
+	return columns.advance(rn, size)
+}
+
+// Returns an Origin representing the next unconsumed element in the
+// input stream. At the end of input, it is the Origin of the last element.
+func (ctx *StringContext) GetCurOrigin() Origin {
+	offset := ctx.offset()
+	if offset >= len(ctx.runes) {
+		if len(ctx.runes) == 0 {
+			return Origin{}
+		}
+		offset = len(ctx.runes) - 1
+	}
+	return ctx.OriginAt(offset)
+}
+
+// Returns the Origin just past the last element.
+func (ctx *StringContext) endOfInputOrigin() (Origin, bool) {
+	return ctx.OriginAt(len(ctx.runes)), true
+}
+
+// Returns a checkpoint of the next unconsumed rune, taking the look offset into account.
+func (ctx *StringContext) Checkpoint() StringCheckpoint {
+	return StringCheckpoint{offset: ctx.offset()}
+}
+
+// Restores the next unconsumed rune to that of checkpoint, which may be before
+// or after it. In a Look frame, the checkpoint must not be before the start of
+// the outermost Look frame, or the function panics.
+func (ctx *StringContext) Restore(checkpoint StringCheckpoint) {
+	if ctx.lookOffset == InvalidLookOffset {
+		ctx.consumedCount = checkpoint.offset
+		return
+	}
+	if checkpoint.offset < ctx.consumedCount {
+		panic("cannot restore a checkpoint before the start of the outermost Look frame")
+	}
+	ctx.lookOffset = checkpoint.offset - ctx.consumedCount
+}
+
+// Returns the text of the runes in [start, end) without copying it, with the
+// offsets clamped to the input. The text is that of the input, so newlines are
+// not translated.
+func (ctx *StringContext) Substring(start int, end int) string {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(ctx.runes) {
+		end = len(ctx.runes)
+	}
+	if start >= end {
+		return ""
+	}
+	return ctx.data[ctx.byteOffset(start):ctx.byteOffset(end)]
+}
+
+// Returns the text between two checkpoints without copying it.
+func (ctx *StringContext) SubstringBetween(start StringCheckpoint, end StringCheckpoint) string {
+	return ctx.Substring(start.offset, end.offset)
+}
+
+// Opens a span logging the input consumed until it is closed.
+func (ctx *StringContext) openSpan() (spanStart, bool) {
+	return ctx.beginSpan(ctx), true
+}
+
+// Closes a span returned by openSpan, returning the OriginRange of the input consumed since.
+func (ctx *StringContext) closeSpan(start spanStart) OriginRange {
+	return ctx.endSpan(ctx, start)
+}
+
+// Attempts to run any added skip parsers as long as one of the parsers
+// successfully matches. The results of any matched parsers is discarded.
+// Should only return nil or non-ParseError errors.
+func (ctx *StringContext) RunSkipParsers() error {
+	return ctx.runSkipParsers(ctx)
+}
+
+// Prints the start of a debug section, if debugging is enabled.
+func (ctx *StringContext) DebugStart(format string, formatArgs ...interface{}) {
+	ctx.debugStart(ctx, format, formatArgs...)
+}
+
+// Prints a debug message, if debugging is enabled.
+func (ctx *StringContext) DebugPrint(format string, formatArgs ...interface{}) {
+	ctx.debugPrint(ctx, format, formatArgs...)
+}
+
+// Prints the end of a debug section, if debugging is enabled.
+func (ctx *StringContext) DebugEnd(format string, formatArgs ...interface{}) {
+	ctx.debugEnd(ctx, format, formatArgs...)
+}
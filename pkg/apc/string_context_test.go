@@ -0,0 +1,232 @@
+package apc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexedStringContextEmptyInput(t *testing.T) {
+	ctx := NewIndexedStringContext(testStringOrigin, "")
+	val, err := ctx.Peek(0, 1)
+	assert.ErrorIs(t, err, ErrEOF)
+	assert.Len(t, val, 0)
+	assert.Equal(t, Origin{}, ctx.GetCurOrigin())
+	assert.Equal(t, "", ctx.Substring(0, 1))
+}
+
+func TestIndexedStringContextBasic(t *testing.T) {
+	ctx := NewIndexedStringContext(testStringOrigin, "ab\ncd")
+
+	assert := func(val []rune, exp string, line int, col int, err error, expErr bool) {
+		if expErr {
+			assert.ErrorIs(t, err, ErrEOF)
+		} else {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, exp, string(val))
+		assert.Equal(t, Origin{
+			Name:    testStringOrigin,
+			LineNum: line,
+			ColNum:  col,
+		}, ctx.GetCurOrigin())
+	}
+
+	val, err := ctx.Peek(2, 3)
+	assert(val, "\ncd", 1, 1, err, false)
+	val, err = ctx.Peek(0, 7)
+	assert(val, "ab\ncd", 1, 1, err, true)
+
+	val, err = ctx.Consume(1)
+	assert(val, "a", 1, 2, err, false)
+	val, err = ctx.Consume(2)
+	assert(val, "b\n", 2, 1, err, false)
+	val, err = ctx.Consume(3)
+	assert(val, "cd", 2, 2, err, true)
+}
+
+func TestIndexedStringContextMatchesReaderContext(t *testing.T) {
+	input := "  # héllo\na = 1 // 😀\n\n/* block */ b=c   \n# end\n"
+	parse := func(ctx Context[rune]) (*CSTNode, error) {
+		ctx.AddSkipParser(CastToAny(CStyleWhitespaceAndCommentsParser))
+		ctx.AddSkipParser(CastToAny(HashLineCommentParser))
+		return Parse[rune](ctx, newTestCSTKeyValueParser(), DefaultParseConfig)
+	}
+
+	exp, expErr := parse(NewStringContext(testStringOrigin, input))
+	node, err := parse(NewIndexedStringContext(testStringOrigin, input))
+	assert.NoError(t, expErr)
+	assert.NoError(t, err)
+	assert.Equal(t, exp, node)
+	assert.Equal(t, input, node.String())
+
+	input = "a = 1 // é\nb = ?"
+	_, expErr = parse(NewStringContext(testStringOrigin, input))
+	_, err = parse(NewIndexedStringContext(testStringOrigin, input))
+	assert.Error(t, err)
+	assert.Equal(t, expErr, err)
+}
+
+func TestIndexedStringContextSpan(t *testing.T) {
+	ctx := NewIndexedStringContext(testStringOrigin, "é😀\nçd")
+	p := Span(Seq2(ExactStr("é😀\n"), ExactStr("çd")))
+
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, testOriginRange(1, 1, 2, 3), node.OriginRange)
+}
+
+func TestIndexedStringContextOriginAt(t *testing.T) {
+	ctx := NewIndexedStringContext(testStringOrigin, "ab\n\nc😀d\n")
+	origins := make([]string, 0, ctx.Len()+1)
+	for i := 0; i <= ctx.Len(); i++ {
+		origins = append(origins, strings.TrimPrefix(ctx.OriginAt(i).String(), testStringOrigin+":"))
+	}
+	assert.Equal(t, []string{"1:1", "1:2", "1:3", "2:1", "3:1", "3:2", "3:3", "3:4", "4:1"}, origins)
+	// Out of order and out of range
+	assert.Equal(t, testOriginRange(3, 3, 1, 2), OriginRange{Start: ctx.OriginAt(6), End: ctx.OriginAt(1)})
+	assert.Equal(t, ctx.OriginAt(0), ctx.OriginAt(-5))
+	assert.Equal(t, ctx.OriginAt(ctx.Len()), ctx.OriginAt(100))
+}
+
+func TestIndexedStringContextCheckpoint(t *testing.T) {
+	ctx := NewIndexedStringContext(testStringOrigin, "let x = 1")
+	start := ctx.Checkpoint()
+	_, err := ctx.Consume(5)
+	assert.NoError(t, err)
+	mid := ctx.Checkpoint()
+	assert.Equal(t, "let x", ctx.SubstringBetween(start, mid))
+
+	ctx.Restore(start)
+	val, _ := ctx.Peek(0, 3)
+	assert.Equal(t, "let", string(val))
+	ctx.Restore(mid)
+	val, _ = ctx.Peek(0, 4)
+	assert.Equal(t, " = 1", string(val))
+
+	// Within a Look frame, restoring is relative to the look offset.
+	ctx.Restore(start)
+	ctx.SetLookOffset(0)
+	_, _ = ctx.Consume(4)
+	inLook := ctx.Checkpoint()
+	ctx.Restore(mid)
+	assert.Equal(t, 5, ctx.GetLookOffset())
+	ctx.Restore(inLook)
+	assert.Equal(t, 4, ctx.GetLookOffset())
+	ctx.SetLookOffset(InvalidLookOffset)
+	assert.Equal(t, start, ctx.Checkpoint())
+
+	_, _ = ctx.Consume(2)
+	ctx.SetLookOffset(0)
+	assert.Panics(t, func() { ctx.Restore(start) })
+}
+
+func TestIndexedStringContextSubstring(t *testing.T) {
+	ctx := NewIndexedStringContext(testStringOrigin, "abc")
+	assert.Equal(t, "bc", ctx.Substring(1, 3))
+	assert.Equal(t, "abc", ctx.Substring(-1, 10))
+	assert.Equal(t, "", ctx.Substring(2, 1))
+
+	ctx = NewIndexedStringContext(testStringOrigin, "aé😀b")
+	assert.Equal(t, 4, ctx.Len())
+	assert.Equal(t, "é😀", ctx.Substring(1, 3))
+	assert.Equal(t, "😀b", ctx.Substring(2, 4))
+}
+
+func TestIndexedStringContextSubstringLongInput(t *testing.T) {
+	input := strings.Repeat("aé😀\n", 100)
+	ctx := NewIndexedStringContext(testStringOrigin, input)
+	assert.Equal(t, 400, ctx.Len())
+	assert.Equal(t, input, ctx.Substring(0, ctx.Len()))
+	assert.Equal(t, "😀\naé", ctx.Substring(202, 206))
+	assert.Equal(t, "\n", ctx.Substring(399, 400))
+}
+
+func TestIndexedStringContextOriginConfig(t *testing.T) {
+	input := "a\tbc\r\n\té😀x\r\ry\u0301🇫🇷\t\r\n"
+	configs := []OriginConfig{
+		DefaultOriginConfig,
+		{TabWidth: 4, TranslateNewlines: true},
+		{TabWidth: 8, ColumnUnit: ColumnUnitUTF16},
+		{TranslateNewlines: true, ColumnUnit: ColumnUnitByte},
+		{TabWidth: 2, TranslateNewlines: true, ColumnUnit: ColumnUnitGrapheme},
+	}
+	for _, config := range configs {
+		reader := NewRuneReaderWithOriginConfig(testStringOrigin, strings.NewReader(input), config)
+		var expRunes []rune
+		var expOrigins []Origin
+		for {
+			rn, origin, err := reader.Read()
+			if err != nil {
+				expOrigins = append(expOrigins, origin)
+				break
+			}
+			expRunes = append(expRunes, rn)
+			expOrigins = append(expOrigins, origin)
+		}
+
+		ctx := NewIndexedStringContextWithConfig(testStringOrigin, input, config)
+		runes, _ := ctx.Peek(0, ctx.Len())
+		assert.Equal(t, expRunes, runes, "%+v", config)
+		origins := make([]Origin, 0, ctx.Len()+1)
+		for i := 0; i <= ctx.Len(); i++ {
+			origins = append(origins, ctx.OriginAt(i))
+		}
+		assert.Equal(t, expOrigins, origins, "%+v", config)
+		// Out of order
+		assert.Equal(t, expOrigins[3], ctx.OriginAt(3), "%+v", config)
+	}
+
+	ctx := NewIndexedStringContextWithConfig(testStringOrigin, "a\r\nb", OriginConfig{TranslateNewlines: true})
+	assert.Equal(t, 3, ctx.Len())
+	assert.Equal(t, "\r\nb", ctx.Substring(1, 3))
+}
+
+func TestIndexedStringContextLook(t *testing.T) {
+	ctx := NewIndexedStringContext(testStringOrigin, "ab ac")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	p := OneOrMore(Any(Look(Seq(ExactStr("a"), ExactStr("c"))), Seq(ExactStr("a"), ExactStr("b"))))
+
+	node, err := Parse[rune](ctx, p, DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "b"}, {"a", "c"}}, node)
+	assert.Equal(t, Origin{Name: testStringOrigin, LineNum: 1, ColNum: 5}, ctx.GetCurOrigin())
+}
+
+// Returns a large input of key/value pairs for benchmarking contexts.
+func benchmarkContextInput() string {
+	var sb strings.Builder
+	for i := 0; i < 1000; i++ {
+		sb.WriteString("key")
+		sb.WriteString(strings.Repeat("_", i%5))
+		sb.WriteString(" = value // commenté\n")
+	}
+	return sb.String()
+}
+
+func benchmarkContext(b *testing.B, newContext func(input string) Context[rune]) {
+	input := benchmarkContextInput()
+	p := ZeroOrMore(Seq3(IdentifierParser, ExactStr("="), IdentifierParser))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx := newContext(input)
+		ctx.AddSkipParser(CastToAny(CStyleWhitespaceAndCommentsParser))
+		if _, err := Parse[rune](ctx, p, DefaultParseConfig); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIndexedStringContext(b *testing.B) {
+	benchmarkContext(b, func(input string) Context[rune] {
+		return NewIndexedStringContext(testStringOrigin, input)
+	})
+}
+
+func BenchmarkReaderContext(b *testing.B) {
+	benchmarkContext(b, func(input string) Context[rune] {
+		return NewStringContext(testStringOrigin, input)
+	})
+}
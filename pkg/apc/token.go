@@ -11,11 +11,18 @@ type TokenType string
 // TokenType representing a nil token.
 const NilTokenType TokenType = ""
 
-// Contains a TokenType and some Value.
+// Contains a TokenType and some Value, along with where the Token
+// came from in the input.
 // If TokenType is NilTokenType, this Token should be assumed to be nil.
 type Token struct {
 	Type  TokenType
 	Value any
+	// The OriginRange of the input that produced the Token, from the first
+	// consumed element to just past the last consumed element.
+	// Zero if unknown.
+	OriginRange OriginRange
+	// The raw input text that produced the Token. Empty if unknown.
+	Raw string
 }
 
 // Return the string version of a Token.
@@ -90,13 +97,29 @@ func ExactTokenValue(tokenType TokenType, value any) Parser[Token, Token] {
 
 // Returns a parser that maps a Parser[CT, T] into a Parser[CT, Token] by returning a new
 // Token with Type tokenType and Value being the result of parser.
+// The OriginRange and Raw text of the Token are set from the input consumed by parser.
+// Input consumed by skip parsers between the first and last consumed elements is not
+// part of the Raw text.
 func BindToToken[CT, T any](parser Parser[CT, T], tokenType TokenType) Parser[CT, Token] {
-	return Map(parser, func(node T) Token {
-		return Token{
-			Type:  tokenType,
-			Value: node,
+	return func(ctx Context[CT]) (Token, error) {
+		err := ctx.RunSkipParsers()
+		if err != nil {
+			return Token{}, err
 		}
-	})
+
+		recorder := newConsumeRecorder(ctx, true)
+		node, err := parser(recorder)
+		if err != nil {
+			return Token{}, err
+		}
+		vals := recorder.consumedElements()
+		return Token{
+			Type:        tokenType,
+			Value:       node,
+			OriginRange: recorder.originRange(vals),
+			Raw:         rawTextOf(vals),
+		}, nil
+	}
 }
 
 // Returns a parser that maps a Parser[CT, Token] into a Parser[CT, T] by returning
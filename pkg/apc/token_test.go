@@ -0,0 +1,97 @@
+package apc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testTokenTypeIdent TokenType = "ident"
+	testTokenTypeInt   TokenType = "int"
+)
+
+func newTestTokenContext(input string) *ReaderContext[Token] {
+	lexParser := Skip(
+		CastToAny(WhitespaceParser),
+		Any(
+			BindToToken(IdentifierParser, testTokenTypeIdent),
+			BindToToken(IntParser, testTokenTypeInt),
+		),
+	)
+	ctx := NewStringContext(testStringOrigin, input)
+	return NewReaderContext[Token](NewParseReader[rune](ctx, lexParser))
+}
+
+func testOriginRange(startLine int, startCol int, endLine int, endCol int) OriginRange {
+	return OriginRange{
+		Start: Origin{Name: testStringOrigin, LineNum: startLine, ColNum: startCol},
+		End:   Origin{Name: testStringOrigin, LineNum: endLine, ColNum: endCol},
+	}
+}
+
+func TestBindToTokenOriginRangeAndRaw(t *testing.T) {
+	ctx := newTestTokenContext("  abc\n 0x1F x")
+
+	tok, err := ExactTokenType(testTokenTypeIdent)(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Token{
+		Type:        testTokenTypeIdent,
+		Value:       "abc",
+		OriginRange: testOriginRange(1, 3, 1, 6),
+		Raw:         "abc",
+	}, tok)
+
+	tok, err = ExactTokenType(testTokenTypeInt)(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Token{
+		Type:        testTokenTypeInt,
+		Value:       int64(31),
+		OriginRange: testOriginRange(2, 2, 2, 6),
+		Raw:         "0x1F",
+	}, tok)
+}
+
+func TestParseReaderSetsTokenOriginRange(t *testing.T) {
+	lexParser := Map(Regex("[a-z]+"), func(node string) Token {
+		return Token{Type: testTokenTypeIdent, Value: node}
+	})
+	ctx := NewStringContext(testStringOrigin, "ab cd")
+	ctx.AddSkipParser(CastToAny(WhitespaceParser))
+	reader := NewParseReader[rune](ctx, lexParser)
+
+	reader.Read()
+	tok, origin, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, Origin{Name: testStringOrigin, LineNum: 1, ColNum: 4}, origin)
+	assert.Equal(t, "cd", tok.Raw)
+	assert.Equal(t, origin, tok.OriginRange.Start)
+}
+
+func TestExactTokenErrorsReportTokenSpan(t *testing.T) {
+	ctx := newTestTokenContext("abc 1234 x")
+
+	_, err := ExactTokenType(testTokenTypeInt)(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	assert.Equal(t, testOriginRange(1, 1, 1, 4), err.(*ParseError).OriginRange)
+	assert.Contains(t, err.Error(), "<origin>:1:1-1:4")
+
+	_, err = ExactTokenType(testTokenTypeIdent)(ctx)
+	assert.NoError(t, err)
+	_, err = ExactTokenValue(testTokenTypeInt, int64(5))(ctx)
+	assert.ErrorIs(t, err, ErrParseErr)
+	assert.Equal(t, testOriginRange(1, 5, 1, 9), err.(*ParseError).OriginRange)
+}
+
+func TestMapDetailedTokenSpan(t *testing.T) {
+	ctx := newTestTokenContext("abc  1234 x")
+	p := MapDetailed(
+		Look(Seq(ExactTokenType(testTokenTypeIdent), ExactTokenType(testTokenTypeInt))),
+		func(_ []Token, orgRange OriginRange) (OriginRange, error) {
+			return orgRange, nil
+		})
+
+	node, err := p(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, testOriginRange(1, 1, 1, 10), node)
+}
@@ -5,11 +5,16 @@ import (
 )
 
 var (
-	runeParserCache  parserCache[rune]      = make(parserCache[rune])
-	tokenParserCache parserCache[apc.Token] = make(parserCache[apc.Token])
+	runeParserCache  = newParserCache[rune]()
+	tokenParserCache = newParserCache[apc.Token]()
 )
 
+// Builds the parser for RT from its apc struct tags. BuildParser may be called
+// concurrently, and the returned parser may be used concurrently with separate contexts.
 func BuildParser[RT any](buildOpts *BuildOptions[rune]) apc.Parser[rune, RT] {
+	runeParserCache.lock.Lock()
+	defer runeParserCache.lock.Unlock()
+
 	resultType := reflectTypeOf[RT]()
 	buildCtx := newBuildContext(runeParserCache, buildOpts.ProvidedParsers)
 	baseParser := buildRuneParserForType(buildCtx, resultType)
@@ -18,7 +23,11 @@ func BuildParser[RT any](buildOpts *BuildOptions[rune]) apc.Parser[rune, RT] {
 	return parser
 }
 
+// Like BuildParser, but builds a parser of apc.Tokens.
 func BuildTokenizedParser[RT any](buildOpts *BuildOptions[apc.Token]) apc.Parser[apc.Token, RT] {
+	tokenParserCache.lock.Lock()
+	defer tokenParserCache.lock.Unlock()
+
 	resultType := reflectTypeOf[RT]()
 	buildCtx := newBuildContext(tokenParserCache, buildOpts.ProvidedParsers)
 	baseParser := buildTokenParserForType(buildCtx, resultType)
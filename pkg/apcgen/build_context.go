@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/tpillow/apc/pkg/apc"
 )
@@ -13,10 +14,22 @@ type captureResult struct {
 	value      any
 }
 
-type parserCache[CT any] map[reflect.Type]*apc.Parser[CT, any]
+// The parsers built for each result type, shared by every build.
+type parserCache[CT any] struct {
+	// Held for the whole of a build, as a parser being built is cached
+	// before it is complete.
+	lock    sync.Mutex
+	parsers map[reflect.Type]*apc.Parser[CT, any]
+}
+
+func newParserCache[CT any]() *parserCache[CT] {
+	return &parserCache[CT]{
+		parsers: make(map[reflect.Type]*apc.Parser[CT, any]),
+	}
+}
 
-func (cache parserCache[CT]) maybeGetCachedParserFromType(typ reflect.Type) apc.Parser[CT, any] {
-	if parserPtr, has := cache[typ]; has {
+func (cache *parserCache[CT]) maybeGetCachedParserFromType(typ reflect.Type) apc.Parser[CT, any] {
+	if parserPtr, has := cache.parsers[typ]; has {
 		if *parserPtr != nil {
 			// Already fully generated; skip the Ref parser wrapper
 			return *parserPtr
@@ -29,11 +42,11 @@ func (cache parserCache[CT]) maybeGetCachedParserFromType(typ reflect.Type) apc.
 }
 
 type buildContext[CT any] struct {
-	parserCache       parserCache[CT]
+	parserCache       *parserCache[CT]
 	providedParserMap map[string]apc.Parser[CT, any]
 }
 
-func newBuildContext[CT any](parserCache parserCache[CT], providedParsers map[string]apc.Parser[CT, any]) *buildContext[CT] {
+func newBuildContext[CT any](parserCache *parserCache[CT], providedParsers map[string]apc.Parser[CT, any]) *buildContext[CT] {
 	return &buildContext[CT]{
 		parserCache:       parserCache,
 		providedParserMap: providedParsers,
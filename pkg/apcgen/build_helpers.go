@@ -42,7 +42,7 @@ func buildParserForTypeCommon[CT any](buildCtx *buildContext[CT], resultType ref
 
 	// Log that this result type parser is being built
 	parserPtr := new(apc.Parser[CT, any])
-	buildCtx.parserCache[resultType] = parserPtr
+	buildCtx.parserCache.parsers[resultType] = parserPtr
 	// Actually build the parser and set it
 	*parserPtr = buildParserFromRootNodeFunc(buildCtx, subCtx, node)
 	return *parserPtr
@@ -50,27 +50,57 @@ func buildParserForTypeCommon[CT any](buildCtx *buildContext[CT], resultType ref
 
 func buildParserFromRootNodeCommon[CT any](buildCtx *buildContext[CT], subCtx *buildSubcontext[CT], node *rootNode,
 	buildParserFromNodeFunc func(*buildContext[CT], *buildSubcontext[CT], Node) apc.Parser[CT, any]) apc.Parser[CT, any] {
-	rootParser := buildParserFromNodeFunc(buildCtx, subCtx, node.Child)
-	return apc.Named(
+	// Coverage of the grammar is labeled by the struct type, and the field within it where known
+	rootParser := apc.CoverageLabel(
 		subCtx.resultStructType.Name(),
-		apc.MapDetailed(
-			rootParser,
-			func(parseNode any, orgRange apc.OriginRange) (any, error) {
-				// Set fields of the resulting populated struct
-				resultPtrVal := reflect.New(subCtx.resultStructType)
-				setCaptureHelper(subCtx, resultPtrVal, parseNode)
+		nil,
+		buildParserFromNodeFunc(buildCtx, subCtx, node.Child),
+	)
+	// Populate the fields of the resulting struct
+	resultParser := apc.Map(
+		rootParser,
+		func(parseNode any) reflect.Value {
+			resultPtrVal := reflect.New(subCtx.resultStructType)
+			setCaptureHelper(subCtx, resultPtrVal, parseNode)
+			return resultPtrVal
+		},
+	)
 
-				// If the struct has an "OriginRange" field of type apc.OriginRange, set the origin range that matched
-				if orgRangeField, ok := subCtx.resultStructType.FieldByName("OriginRange"); ok && orgRangeField.Type == reflectTypeOf[apc.OriginRange]() {
-					resultPtrVal.Elem().FieldByName("OriginRange").Set(reflect.ValueOf(orgRange))
-				}
+	// If the struct has an "OriginRange" field of type apc.OriginRange, set the origin range that matched,
+	// excluding any input skipped before or after it
+	if orgRangeField, ok := subCtx.resultStructType.FieldByName("OriginRange"); ok && orgRangeField.Type == reflectTypeOf[apc.OriginRange]() {
+		resultParser = apc.Map(
+			apc.Span(resultParser),
+			func(spanned apc.Spanned[reflect.Value]) reflect.Value {
+				spanned.Value.Elem().FieldByName("OriginRange").Set(reflect.ValueOf(spanned.OriginRange))
+				return spanned.Value
+			},
+		)
+	}
+
+	// If the struct has a "CST" field of type *apc.CSTNode, set the lossless concrete syntax tree of the matched input
+	if cstField, ok := subCtx.resultStructType.FieldByName("CST"); ok && cstField.Type == reflectTypeOf[*apc.CSTNode]() {
+		resultParser = apc.MapCST(
+			subCtx.resultStructType.Name(),
+			resultParser,
+			func(resultPtrVal reflect.Value, cst *apc.CSTNode) reflect.Value {
+				resultPtrVal.Elem().FieldByName("CST").Set(reflect.ValueOf(cst))
+				return resultPtrVal
+			},
+		)
+	}
 
+	return apc.Named(
+		subCtx.resultStructType.Name(),
+		apc.Map(
+			resultParser,
+			func(resultPtrVal reflect.Value) any {
 				// Return the actual value
 				switch subCtx.resultType.Kind() {
 				case reflect.Pointer:
-					return resultPtrVal.Interface(), nil
+					return resultPtrVal.Interface()
 				default:
-					return reflect.Indirect(resultPtrVal).Interface(), nil
+					return reflect.Indirect(resultPtrVal).Interface()
 				}
 			},
 		),
@@ -140,10 +170,43 @@ func buildAnyParserFromNodesCommon[CT any](buildCtx *buildContext[CT], subCtx *b
 	buildParserFromNodeFunc func(*buildContext[CT], *buildSubcontext[CT], Node) apc.Parser[CT, any]) apc.Parser[CT, any] {
 
 	parsers := make([]apc.Parser[CT, any], len(nodes))
+	branchNames := make([]string, len(nodes))
 	for i, rawNode := range nodes {
 		parsers[i] = buildParserFromNodeFunc(buildCtx, subCtx, rawNode)
+		if fieldName, ok := fieldNameOfNode(subCtx, rawNode); ok {
+			branchNames[i] = fmt.Sprintf("alternative %v (%v)", i+1, fieldName)
+		}
 	}
-	return apc.Any(parsers...)
+	return apc.CoverageLabel("", branchNames, apc.Any(parsers...))
+}
+
+// Returns the name of the field the grammar of rawNode begins in, if rawNode captures any field.
+func fieldNameOfNode[CT any](subCtx *buildSubcontext[CT], rawNode Node) (string, bool) {
+	inputIndex := -1
+	walkNodes(rawNode, func(rawNode Node) {
+		if inputIndex != -1 {
+			return
+		}
+		switch node := rawNode.(type) {
+		case *captureNode:
+			inputIndex = node.InputIndex
+		case *inferNode:
+			inputIndex = node.InputIndex
+		}
+	})
+	if inputIndex == -1 {
+		return "", false
+	}
+	return subCtx.fieldNameFromCaptureIdx(inputIndex), true
+}
+
+// Returns the coverage label of the grammar of rawNode: its struct type and the
+// field it begins in, or "" to keep the enclosing label if it captures no field.
+func coverageLabelOfNode[CT any](subCtx *buildSubcontext[CT], rawNode Node) string {
+	if fieldName, ok := fieldNameOfNode(subCtx, rawNode); ok {
+		return subCtx.resultStructType.Name() + "." + fieldName
+	}
+	return ""
 }
 
 func buildParserFromNodeCommon[CT any](buildCtx *buildContext[CT], subCtx *buildSubcontext[CT], rawNode Node,
@@ -164,7 +227,7 @@ func buildParserFromNodeCommon[CT any](buildCtx *buildContext[CT], subCtx *build
 		return buildParserForTypeFunc(buildCtx, field.Type)
 	case *captureNode:
 		return apc.Map(
-			buildParserFromNodeFunc(buildCtx, subCtx, node.Child),
+			apc.CoverageLabel(coverageLabelOfNode(subCtx, node), nil, buildParserFromNodeFunc(buildCtx, subCtx, node.Child)),
 			func(parseNode any) any {
 				return captureResult{
 					inputIndex: node.InputIndex,
@@ -177,17 +240,58 @@ func buildParserFromNodeCommon[CT any](buildCtx *buildContext[CT], subCtx *build
 		return apc.CastToAny(parser)
 	case *rangeNode:
 		childParser := buildParserFromNodeFunc(buildCtx, subCtx, node.Child)
-		return apc.CastToAny(apc.Range(node.Range.min, node.Range.max, childParser))
+		return apc.CoverageLabel(coverageLabelOfNode(subCtx, node), nil,
+			apc.CastToAny(apc.Range(node.Range.min, node.Range.max, childParser)))
 	case *orNode:
 		return buildAnyParserFromNodesCommon(buildCtx, subCtx, node.Children, buildParserFromNodeFunc)
 	case *providedParserKeyNode:
 		return buildCtx.mustGetProvidedParserByName(node.Name)
 	case *maybeNode:
-		return apc.CastToAny(apc.Maybe(buildParserFromNodeFunc(buildCtx, subCtx, node.Child)))
+		return apc.CoverageLabel(coverageLabelOfNode(subCtx, node), nil,
+			apc.CastToAny(apc.Maybe(buildParserFromNodeFunc(buildCtx, subCtx, node.Child))))
 	case *lookNode:
 		return apc.Look(buildParserFromNodeFunc(buildCtx, subCtx, node.Child))
 	case *namedNode:
 		return apc.Named(node.Name, buildParserFromNodeFunc(buildCtx, subCtx, node.Child))
+	case *permNode:
+		slots := make([]apc.PermutationSlot[CT, []any], len(node.Clauses))
+		for i, clause := range node.Clauses {
+			// Clauses are named by the field their grammar begins in
+			name := subCtx.fieldNameFromCaptureIdx(clause.InputIndex)
+			var parser apc.Parser[CT, any]
+			switch child := clause.Child.(type) {
+			case *maybeNode:
+				// The Permutation makes the clause optional, so the child must fail if not matched
+				parser = buildParserFromNodeFunc(buildCtx, subCtx, child.Child)
+			case *captureNode:
+				if mNode, ok := child.Child.(*maybeNode); ok {
+					// Capture the same MaybeValue as if matched by a Maybe parser
+					inputIndex := child.InputIndex
+					parser = apc.Map(
+						buildParserFromNodeFunc(buildCtx, subCtx, mNode.Child),
+						func(parseNode any) any {
+							return captureResult{
+								inputIndex: inputIndex,
+								value:      apc.NewMaybeValue(parseNode),
+							}
+						},
+					)
+				} else {
+					parser = buildParserFromNodeFunc(buildCtx, subCtx, child)
+				}
+			default:
+				parser = buildParserFromNodeFunc(buildCtx, subCtx, child)
+			}
+			setFunc := func(result *[]any, parseNode any) {
+				*result = append(*result, parseNode)
+			}
+			if clause.Required {
+				slots[i] = apc.RequiredSlot(name, parser, setFunc)
+			} else {
+				slots[i] = apc.OptionalSlot(name, parser, setFunc)
+			}
+		}
+		return apc.CastToAny(apc.Permutation(slots...))
 	default:
 		// To be handled in calling function
 		return nil
@@ -207,6 +311,14 @@ func valueSetFieldOrAppendKind(rawVal any, valKind reflect.Kind, fieldName strin
 		panic(fmt.Sprintf("cannot set field '%v' to value '%v' (type %T): cannot convert %v", fieldName, val, val, exp))
 	}
 
+	// A captured optional value only sets a non-bool field if it matched
+	if maybeVal, ok := rawVal.(apc.MaybeValue[any]); ok && valKind != reflect.Bool {
+		if maybeVal.IsNil() {
+			return
+		}
+		rawVal = maybeVal.Value()
+	}
+
 	switch valKind {
 	case reflect.String:
 		switch val := rawVal.(type) {
@@ -1,6 +1,8 @@
 package apcgen
 
 import (
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,13 +49,67 @@ func TestSliceCaptureStringWithOrigin(t *testing.T) {
 			End: apc.Origin{
 				Name:    testOriginName,
 				LineNum: 1,
-				ColNum:  11,
+				ColNum:  12,
 			},
 		},
 		Values: []string{"ha", "ha", "ha", "ha"},
 	}, node)
 }
 
+func TestOriginRangeExcludesSkippedInput(t *testing.T) {
+	type Item struct {
+		OriginRange apc.OriginRange
+		Name        string `apc:"$regex('[a-z]+')"`
+		Value       string `apc:"('=' $regex('[0-9]+'))?"`
+	}
+	type Obj struct {
+		OriginRange apc.OriginRange
+		Items       []*Item `apc:"'{' $.* '}'"`
+	}
+
+	parser := BuildParser[*Obj](WithDefaultBuildOptions(
+		WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)),
+	))
+
+	ctx := apc.NewStringContext(testOriginName, "  {\n  a = 1\n  b\n}")
+	node, err := apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+	assert.NoError(t, err)
+	origin := func(line int, col int) apc.Origin {
+		return apc.Origin{Name: testOriginName, LineNum: line, ColNum: col}
+	}
+	assert.Equal(t, apc.OriginRange{Start: origin(1, 3), End: origin(4, 2)}, node.OriginRange)
+	assert.Equal(t, apc.OriginRange{Start: origin(2, 3), End: origin(2, 8)}, node.Items[0].OriginRange)
+	// The whitespace skipped while trying to match '=' is not part of the range
+	assert.Equal(t, apc.OriginRange{Start: origin(3, 3), End: origin(3, 4)}, node.Items[1].OriginRange)
+}
+
+func TestOriginRangeRemappedBySourceMap(t *testing.T) {
+	type Item struct {
+		OriginRange apc.OriginRange
+		Name        string `apc:"$regex('[a-z]+')"`
+	}
+	type Obj struct {
+		Items []*Item `apc:"$.*"`
+	}
+
+	parser := BuildParser[*Obj](WithDefaultBuildOptions(
+		WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)),
+	))
+
+	input := "a\n#line 20 \"orig.conf\"\n  b\n"
+	ctx := apc.NewSourceMapContext(testOriginName, strings.NewReader(input), apc.DefaultSourceMapConfig)
+	node, err := apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, apc.OriginRange{
+		Start: apc.Origin{Name: testOriginName, LineNum: 1, ColNum: 1},
+		End:   apc.Origin{Name: testOriginName, LineNum: 1, ColNum: 2},
+	}, node.Items[0].OriginRange)
+	assert.Equal(t, apc.OriginRange{
+		Start: apc.Origin{Name: "orig.conf", LineNum: 20, ColNum: 3},
+		End:   apc.Origin{Name: "orig.conf", LineNum: 20, ColNum: 4},
+	}, node.Items[1].OriginRange)
+}
+
 func TestSliceCaptureStruct(t *testing.T) {
 	type NameObj struct {
 		Name string `apc:"$regex('[a-zA-Z0-9]+')"`
@@ -147,3 +203,169 @@ func TestParserIntrinsicConversions(t *testing.T) {
 		BoolFromMaybeF: false,
 	}, node)
 }
+
+func TestOptionalCaptureIntoString(t *testing.T) {
+	type Obj struct {
+		Name  string `apc:"'obj' $regex('[a-z]+')?"`
+		Count int    `apc:"$regex('[0-9]+')?"`
+	}
+	parser := BuildParser[Obj](WithDefaultBuildOptions(
+		WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)),
+	))
+
+	ctx := apc.NewStringContext(testOriginName, `obj abc 3`)
+	node, err := apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, Obj{Name: "abc", Count: 3}, node)
+
+	ctx = apc.NewStringContext(testOriginName, `obj`)
+	node, err = apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, Obj{}, node)
+}
+
+func TestOptionalCaptureIntoSlice(t *testing.T) {
+	type Obj struct {
+		Names []string `apc:"('n' $regex('[a-z]+')? ';')*"`
+	}
+	parser := BuildParser[Obj](WithDefaultBuildOptions(
+		WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)),
+	))
+
+	// Unmatched optional captures are not appended
+	ctx := apc.NewStringContext(testOriginName, `n a; n; n b;`)
+	node, err := apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, Obj{Names: []string{"a", "b"}}, node)
+}
+
+func TestPermutationFields(t *testing.T) {
+	type CreateTable struct {
+		Name    string `apc:"'CREATE' 'TABLE' $regex('[a-z]+') perm("`
+		Comment string `apc:"  ('COMMENT' $regex('[a-z]+'))?"`
+		Engine  string `apc:"  ('ENGINE' '=' $regex('[a-z]+'))"`
+		Temp    bool   `apc:"  $'TEMP'?"`
+		_       string `apc:")"`
+	}
+
+	parser := BuildParser[*CreateTable](WithDefaultBuildOptions(
+		WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)),
+	))
+
+	inputs := map[string]*CreateTable{
+		`CREATE TABLE t ENGINE=x`:                {Name: "t", Engine: "x"},
+		`CREATE TABLE t TEMP ENGINE=x COMMENT c`: {Name: "t", Engine: "x", Comment: "c", Temp: true},
+		`CREATE TABLE t COMMENT c ENGINE = y`:    {Name: "t", Engine: "y", Comment: "c"},
+	}
+	for input, expected := range inputs {
+		ctx := apc.NewStringContext(testOriginName, input)
+		node, err := apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, node, input)
+	}
+
+	ctx := apc.NewStringContext(testOriginName, `CREATE TABLE t ENGINE=x COMMENT a ENGINE=y`)
+	_, err := apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+	assert.ErrorIs(t, err, apc.ErrParseErrConsumed)
+	assert.Contains(t, err.Error(), "duplicate clause Engine (first at testOrigin:1:16)")
+
+	ctx = apc.NewStringContext(testOriginName, `CREATE TABLE t COMMENT a`)
+	_, err = apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+	assert.ErrorIs(t, err, apc.ErrParseErrConsumed)
+	assert.Contains(t, err.Error(), "missing required clause Engine")
+}
+
+func TestCSTField(t *testing.T) {
+	type Entry struct {
+		CST   *apc.CSTNode
+		Key   string `apc:"$regex('[a-z]+') '='"`
+		Value int64  `apc:"$IntParser ';'"`
+	}
+	type Config struct {
+		CST     *apc.CSTNode
+		Entries []*Entry `apc:"$.*"`
+	}
+
+	parser := BuildParser[*Config](WithDefaultBuildOptions(
+		WithSkipParserOption(apc.CastToAny(apc.CStyleWhitespaceAndCommentsParser)),
+		WithParserOption("IntParser", apc.CastToAny(apc.IntParser)),
+	))
+
+	input := "// config\n  a = 1;\n\tb=2 ; /* done */\n"
+	ctx := apc.NewStringContext(testOriginName, input)
+	node, err := apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, input, node.CST.String())
+	assert.Equal(t, "Config", node.CST.Kind)
+	assert.Len(t, node.CST.Children, 2)
+	assert.Same(t, node.Entries[0].CST, node.CST.Children[0])
+	assert.Equal(t, "Entry", node.Entries[1].CST.Kind)
+	assert.Equal(t, "\n\tb=2 ;", node.Entries[1].CST.String())
+	assert.Equal(t, " /* done */\n", node.CST.TrailingTrivia)
+}
+
+func TestCoverageByStructField(t *testing.T) {
+	type Value struct {
+		Num  string `apc:"$regex('[0-9]+') |"`
+		Word string `apc:"$regex('[a-z]+')"`
+	}
+	type Entry struct {
+		Key   string `apc:"$regex('[a-z]+')"`
+		Value *Value `apc:"('=' $.)?"`
+	}
+	type Config struct {
+		Entries []*Entry `apc:"$.* ';'"`
+	}
+
+	parser := BuildParser[*Config](WithDefaultBuildOptions(
+		WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)),
+	))
+
+	cov := apc.NewCoverage()
+	ctx := apc.WithCoverage[rune](apc.NewStringContext(testOriginName, `a = 1 b = 2;`), cov)
+	_, err := apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []apc.CoverageSiteReport{
+		{Kind: "range", Rule: "Config", Label: "Config.Entries", Branches: []string{"zero", "one"}},
+		{Kind: "maybe", Rule: "Entry", Label: "Entry.Value", Branches: []string{"not matched"}},
+		{Kind: "any", Rule: "Value", Label: "Value", Branches: []string{"alternative 2 (Word)"}},
+	}, cov.Report().Uncovered)
+}
+
+func TestBuildParserConcurrently(t *testing.T) {
+	type Tree struct {
+		Name     string  `apc:"$regex('[a-z]+')"`
+		Children []*Tree `apc:"('(' $.* ')')?"`
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	results := make([]*Tree, 8)
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			parser := BuildParser[*Tree](WithDefaultBuildOptions(
+				WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)),
+			))
+			for j := 0; j < 20; j++ {
+				ctx := apc.NewStringContext(testOriginName, `a (b (c d) e)`)
+				results[i], errs[i] = apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	expected := &Tree{Name: "a", Children: []*Tree{
+		{Name: "b", Children: []*Tree{{Name: "c"}, {Name: "d"}}},
+		{Name: "e"},
+	}}
+	for i := range results {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, expected, results[i])
+	}
+}
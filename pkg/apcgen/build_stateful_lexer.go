@@ -0,0 +1,138 @@
+package apcgen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+type LexerMode struct {
+	// Parsers attempted, in order, to lex a token while in this mode.
+	Parsers []apc.Parser[rune, apc.Token]
+	// Parsers whose matches are skipped before each token while in this mode.
+	SkipParsers []apc.Parser[rune, any]
+	// Token types that, once lexed in this mode, push the mode with the mapped name.
+	PushOn map[apc.TokenType]string
+	// Token types that, once lexed in this mode, pop this mode.
+	PopOn []apc.TokenType
+}
+
+type StatefulLexerBuildOptions struct {
+	// The name of the mode the lexer starts in. This mode can never be popped.
+	InitialMode string
+	// All modes of the lexer by name.
+	Modes map[string]LexerMode
+}
+
+// StatefulLexer lexes with a different set of parsers depending on the mode on top
+// of its mode stack. Modes are pushed and popped when specific token types are lexed.
+type StatefulLexer struct {
+	initialMode string
+	modes       map[string]*builtLexerMode
+}
+
+type builtLexerMode struct {
+	parser apc.Parser[rune, apc.Token]
+	pushOn map[apc.TokenType]string
+	popOn  map[apc.TokenType]bool
+}
+
+func BuildStatefulLexer(opts StatefulLexerBuildOptions) *StatefulLexer {
+	if _, has := opts.Modes[opts.InitialMode]; !has {
+		panic(fmt.Sprintf("cannot build stateful lexer: initial mode '%v' not specified", opts.InitialMode))
+	}
+
+	lexer := &StatefulLexer{
+		initialMode: opts.InitialMode,
+		modes:       make(map[string]*builtLexerMode),
+	}
+	for name, mode := range opts.Modes {
+		if len(mode.Parsers) == 0 {
+			panic(fmt.Sprintf("cannot build stateful lexer: mode '%v' has no parsers", name))
+		}
+		for tokType, pushName := range mode.PushOn {
+			if _, has := opts.Modes[pushName]; !has {
+				panic(fmt.Sprintf("cannot build stateful lexer: mode '%v' pushes unknown mode '%v' on token type %v",
+					name, pushName, tokType))
+			}
+		}
+		popOn := make(map[apc.TokenType]bool)
+		for _, tokType := range mode.PopOn {
+			popOn[tokType] = true
+		}
+
+		anyParser := apc.Any(mode.Parsers...)
+		var parser apc.Parser[rune, apc.Token] = func(ctx apc.Context[rune]) (apc.Token, error) {
+			err := ctx.RunSkipParsers()
+			if err != nil {
+				return apc.Token{}, err
+			}
+			if _, err := ctx.Peek(0, 1); err != nil {
+				return apc.Token{}, err
+			}
+			return anyParser(ctx)
+		}
+		lexer.modes[name] = &builtLexerMode{
+			parser: wrapWithSkipParsers(parser, mode.SkipParsers),
+			pushOn: mode.PushOn,
+			popOn:  popOn,
+		}
+	}
+	return lexer
+}
+
+// Returns a new lexer parser with its own mode stack, beginning in the initial mode.
+//
+// At the end of input, the parser returns apc.ErrEOF if only the initial mode is on
+// the mode stack; otherwise it returns a ParseErrorConsumed. All errors report the
+// mode stack.
+//
+// As the mode stack is kept by the parser, it must only be used with one context;
+// call NewParser for each context instead.
+func (lexer *StatefulLexer) NewParser() apc.Parser[rune, apc.Token] {
+	modeStack := []string{lexer.initialMode}
+
+	return func(ctx apc.Context[rune]) (apc.Token, error) {
+		mode := lexer.modes[modeStack[len(modeStack)-1]]
+		modeStackStr := strings.Join(modeStack, " > ")
+
+		tok, err := mode.parser(ctx)
+		if err != nil {
+			switch {
+			case errors.Is(err, apc.ErrEOF):
+				if len(modeStack) == 1 {
+					return apc.Token{}, apc.ErrEOF
+				}
+				return apc.Token{}, &apc.ParseErrorConsumed{
+					Message: fmt.Sprintf("unexpected EOF in lexer mode stack %v", modeStackStr),
+					Origin:  ctx.GetCurOrigin(),
+				}
+			case !apc.IsMustReturnParseErr(err):
+				return apc.Token{}, apc.ParseErrExpectedButGotNext(ctx,
+					fmt.Sprintf("valid token in lexer mode stack %v", modeStackStr), nil)
+			default:
+				return apc.Token{}, &apc.ParseErrorConsumed{
+					Err:     err,
+					Message: fmt.Sprintf("could not lex token in lexer mode stack %v", modeStackStr),
+					Origin:  ctx.GetCurOrigin(),
+				}
+			}
+		}
+
+		if mode.popOn[tok.Type] && len(modeStack) > 1 {
+			modeStack = modeStack[:len(modeStack)-1]
+		}
+		if pushName, has := mode.pushOn[tok.Type]; has {
+			modeStack = append(modeStack, pushName)
+		}
+		return tok, nil
+	}
+}
+
+// Returns a *apc.ReaderContext[apc.Token] whose tokens are lexed from ctx
+// by a new parser from NewParser.
+func (lexer *StatefulLexer) NewTokenContext(ctx apc.Context[rune]) *apc.ReaderContext[apc.Token] {
+	return apc.NewReaderContext[apc.Token](apc.NewParseReader(ctx, lexer.NewParser()))
+}
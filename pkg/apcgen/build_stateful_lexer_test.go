@@ -0,0 +1,86 @@
+package apcgen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+func buildInterpolationTestLexer() *StatefulLexer {
+	wsSkip := apc.CastToAny(apc.WhitespaceParser)
+	ident := apc.BindToToken(apc.IdentifierParser, "Ident")
+	quote := apc.BindToToken(apc.ExactStr("\""), "Quote")
+
+	return BuildStatefulLexer(StatefulLexerBuildOptions{
+		InitialMode: "root",
+		Modes: map[string]LexerMode{
+			"root": {
+				Parsers:     []apc.Parser[rune, apc.Token]{ident, quote},
+				SkipParsers: []apc.Parser[rune, any]{wsSkip},
+				PushOn:      map[apc.TokenType]string{"Quote": "string"},
+			},
+			"string": {
+				Parsers: []apc.Parser[rune, apc.Token]{
+					apc.BindToToken(apc.ExactStr("${"), "InterpStart"),
+					quote,
+					apc.BindToToken(apc.Regex(`[^"$]+|\$`), "Text"),
+				},
+				PushOn: map[apc.TokenType]string{"InterpStart": "interp"},
+				PopOn:  []apc.TokenType{"Quote"},
+			},
+			"interp": {
+				Parsers: []apc.Parser[rune, apc.Token]{
+					ident,
+					apc.BindToToken(apc.ExactStr("}"), "InterpEnd"),
+				},
+				SkipParsers: []apc.Parser[rune, any]{wsSkip},
+				PopOn:       []apc.TokenType{"InterpEnd"},
+			},
+		},
+	})
+}
+
+func TestStatefulLexerInterpolation(t *testing.T) {
+	type Interp struct {
+		Name string `apc:"'InterpStart' $'Ident' 'InterpEnd'"`
+	}
+	type Assign struct {
+		Name  string `apc:"$'Ident' 'Quote'"`
+		Parts []any  `apc:"$('Text' | Interp)* 'Quote'"`
+	}
+	type Result struct {
+		Assigns []*Assign `apc:"$.*"`
+	}
+
+	parser := BuildTokenizedParser[Result](WithDefaultBuildOptions(
+		WithBuildTokenizedParserOption[Interp](),
+	))
+	lexer := buildInterpolationTestLexer()
+
+	ctx := apc.NewStringContext(testOriginName, `greet "hi ${ name }, $5!"  empty ""`)
+	node, err := apc.Parse[apc.Token](lexer.NewTokenContext(ctx), parser, apc.DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{
+		Assigns: []*Assign{
+			{Name: "greet", Parts: []any{"hi ", Interp{Name: "name"}, ", ", "$", "5!"}},
+			{Name: "empty"},
+		},
+	}, node)
+}
+
+func TestStatefulLexerErrorsReportModeStack(t *testing.T) {
+	lexer := buildInterpolationTestLexer()
+
+	ctx := apc.NewStringContext(testOriginName, `x "a ${ y`)
+	tokCtx := lexer.NewTokenContext(ctx)
+	_, err := tokCtx.Peek(0, 10)
+	assert.ErrorIs(t, err, apc.ErrParseErrConsumed)
+	assert.Contains(t, err.Error(), "root > string > interp")
+
+	ctx = apc.NewStringContext(testOriginName, `x "a ${ ! }"`)
+	tokCtx = lexer.NewTokenContext(ctx)
+	_, err = tokCtx.Peek(0, 10)
+	assert.ErrorIs(t, err, apc.ErrParseErr)
+	assert.Contains(t, err.Error(), "expected valid token in lexer mode stack root > string > interp but got !")
+}
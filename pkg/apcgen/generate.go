@@ -0,0 +1,441 @@
+package apcgen
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// GenerateFunc returns random text matched by a regex or provided parser.
+type GenerateFunc func(r *rand.Rand) string
+
+type GenerateOptionFunc func(opts *GenerateOptions)
+
+// GenerateOptions contains settings that can be passed to the Generate function.
+type GenerateOptions struct {
+	// Source of randomness; seeded for reproducible sentences.
+	Rand *rand.Rand
+	// Maximum number of nested struct types entered below the root type, when possible.
+	MaxDepth int
+	// Maximum number of repetitions beyond the minimum of an unbounded Range.
+	MaxRepeat int
+	// Written between adjacent generated elements.
+	Space string
+	// Generators overriding the default generation of regex(...) terminals, by regex.
+	RegexGenerators map[string]GenerateFunc
+	// Generators of the text of provided parsers, by parser name.
+	ProvidedGenerators map[string]GenerateFunc
+	// Struct types generated for provided parsers, by parser name.
+	ProvidedTypes map[string]reflect.Type
+}
+
+func WithDefaultGenerateOptions(seed int64, generateFuncs ...GenerateOptionFunc) *GenerateOptions {
+	opts := &GenerateOptions{
+		Rand:               rand.New(rand.NewSource(seed)),
+		MaxDepth:           4,
+		MaxRepeat:          3,
+		Space:              " ",
+		RegexGenerators:    make(map[string]GenerateFunc),
+		ProvidedGenerators: make(map[string]GenerateFunc),
+		ProvidedTypes:      make(map[string]reflect.Type),
+	}
+	for _, generateFunc := range generateFuncs {
+		generateFunc(opts)
+	}
+	return opts
+}
+
+func WithLimitsOption(maxDepth int, maxRepeat int) GenerateOptionFunc {
+	return func(opts *GenerateOptions) {
+		opts.MaxDepth = maxDepth
+		opts.MaxRepeat = maxRepeat
+	}
+}
+
+func WithRegexGeneratorOption(regex string, generateFunc GenerateFunc) GenerateOptionFunc {
+	return func(opts *GenerateOptions) {
+		opts.RegexGenerators[regex] = generateFunc
+	}
+}
+
+func WithGeneratorOption(name string, generateFunc GenerateFunc) GenerateOptionFunc {
+	return func(opts *GenerateOptions) {
+		if _, has := opts.ProvidedGenerators[name]; has {
+			panic(fmt.Sprintf("cannot use WithGeneratorOption: name '%v' already specified", name))
+		}
+		opts.ProvidedGenerators[name] = generateFunc
+	}
+}
+
+// The equivalent of WithBuildParserOption: generates RT for the provided parser
+// named after the struct type of RT.
+func WithGenerateTypeOption[RT any]() GenerateOptionFunc {
+	return func(opts *GenerateOptions) {
+		resultStructType := mustGetResultStructTypeFromType(reflectTypeOf[RT]())
+		opts.ProvidedTypes[resultStructType.Name()] = resultStructType
+	}
+}
+
+// CorpusAdder is implemented by *testing.F.
+type CorpusAdder interface {
+	Add(args ...any)
+}
+
+// Returns random text that parses as RT using a parser from BuildParser, with the
+// generated elements separated by opts.Space (so the parser should skip whitespace).
+//
+// Alternatives, optional values and repetitions are chosen randomly, preferring
+// choices that keep the nesting of struct types within opts.MaxDepth. Regex
+// terminals are generated from the regex unless a generator is provided; provided
+// parsers require a generator or a struct type.
+func Generate[RT any](opts *GenerateOptions) (string, error) {
+	g := &generator{
+		opts:     opts,
+		grammars: make(map[reflect.Type]*printGrammar),
+		costs:    make(map[reflect.Type]int),
+		regexps:  make(map[string]*syntax.Regexp),
+		out:      make([]string, 0),
+	}
+	err := g.generateStruct(mustGetResultStructTypeFromType(reflectTypeOf[RT]()), 0)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(g.out, opts.Space), nil
+}
+
+// Adds count sentences from Generate to corpus, such as the seed corpus of a fuzz test.
+func SeedCorpus[RT any](corpus CorpusAdder, count int, opts *GenerateOptions) error {
+	for i := 0; i < count; i++ {
+		sentence, err := Generate[RT](opts)
+		if err != nil {
+			return err
+		}
+		corpus.Add(sentence)
+	}
+	return nil
+}
+
+// Cost of a node that can never be generated within any depth.
+const unboundedCost = math.MaxInt32
+
+type generator struct {
+	opts     *GenerateOptions
+	grammars map[reflect.Type]*printGrammar
+	// Minimum nesting of struct types needed to generate each struct type.
+	costs   map[reflect.Type]int
+	regexps map[string]*syntax.Regexp
+	out     []string
+}
+
+func (g *generator) grammarFor(structType reflect.Type) *printGrammar {
+	if grammar, has := g.grammars[structType]; has {
+		return grammar
+	}
+	subCtx := newBuildSubContextFromType[rune](structType)
+	node, err := parseFull(structType.Name(), subCtx.grammarText, false)
+	if err != nil {
+		panic(fmt.Sprintf("error parsing parser definition for type '%v': %v", structType.Name(), err))
+	}
+	grammar := &printGrammar{
+		subCtx: subCtx,
+		root:   node,
+	}
+	g.grammars[structType] = grammar
+	return grammar
+}
+
+func (g *generator) generateStruct(structType reflect.Type, depth int) error {
+	grammar := g.grammarFor(structType)
+	return g.generateNode(grammar, grammar.root.Child, depth)
+}
+
+func (g *generator) generateNode(grammar *printGrammar, rawNode Node, depth int) error {
+	fits := func(child Node) bool {
+		cost := g.nodeCost(grammar, child)
+		return cost != unboundedCost && depth+cost <= g.opts.MaxDepth
+	}
+
+	switch node := rawNode.(type) {
+	case *matchStringNode:
+		g.emit(node.Value)
+		return nil
+	case *matchRegexNode:
+		if generateFunc, has := g.opts.RegexGenerators[node.Regex]; has {
+			g.emit(generateFunc(g.opts.Rand))
+			return nil
+		}
+		re, err := g.regexpFor(node.Regex)
+		if err != nil {
+			return err
+		}
+		var sb strings.Builder
+		g.generateRegex(&sb, re)
+		g.emit(sb.String())
+		return nil
+	case *providedParserKeyNode:
+		if generateFunc, has := g.opts.ProvidedGenerators[node.Name]; has {
+			g.emit(generateFunc(g.opts.Rand))
+			return nil
+		}
+		if structType, has := g.opts.ProvidedTypes[node.Name]; has {
+			return g.generateStruct(structType, depth+1)
+		}
+		return fmt.Errorf("cannot generate provided parser %v in type '%v': no generator or type provided",
+			node.Name, grammar.subCtx.resultStructType.Name())
+	case *inferNode:
+		return g.generateStruct(g.inferredStructType(grammar, node), depth+1)
+	case *matchTokenNode:
+		return fmt.Errorf("cannot generate token('%v') in type '%v'", node.TokenType, grammar.subCtx.resultStructType.Name())
+	case *captureNode:
+		return g.generateNode(grammar, node.Child, depth)
+	case *seqNode:
+		for _, child := range node.Children {
+			if err := g.generateNode(grammar, child, depth); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *orNode:
+		candidates := make([]Node, 0, len(node.Children))
+		for _, child := range node.Children {
+			if fits(child) {
+				candidates = append(candidates, child)
+			}
+		}
+		if len(candidates) == 0 {
+			candidates = []Node{g.cheapestNode(grammar, node.Children)}
+		}
+		return g.generateNode(grammar, candidates[g.opts.Rand.Intn(len(candidates))], depth)
+	case *rangeNode:
+		count := node.Range.min
+		if fits(node.Child) {
+			limit := node.Range.min + g.opts.MaxRepeat
+			if node.Range.max >= 0 && node.Range.max < limit {
+				limit = node.Range.max
+			}
+			count += g.opts.Rand.Intn(limit - node.Range.min + 1)
+		}
+		for i := 0; i < count; i++ {
+			if err := g.generateNode(grammar, node.Child, depth); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *maybeNode:
+		if fits(node.Child) && g.opts.Rand.Intn(2) == 0 {
+			return g.generateNode(grammar, node.Child, depth)
+		}
+		return nil
+	case *lookNode:
+		return g.generateNode(grammar, node.Child, depth)
+	case *namedNode:
+		return g.generateNode(grammar, node.Child, depth)
+	case *permNode:
+		for _, i := range g.opts.Rand.Perm(len(node.Clauses)) {
+			if err := g.generateNode(grammar, node.Clauses[i].Child, depth); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		panic(fmt.Sprintf("unknown node to process in generateNode: %T", rawNode))
+	}
+}
+
+func (g *generator) emit(text string) {
+	if text != "" {
+		g.out = append(g.out, text)
+	}
+}
+
+// Returns the struct type of the field inferred by node.
+func (g *generator) inferredStructType(grammar *printGrammar, node *inferNode) reflect.Type {
+	fieldName := grammar.subCtx.fieldNameFromCaptureIdx(node.InputIndex)
+	field, ok := grammar.subCtx.resultStructType.FieldByName(fieldName)
+	if !ok {
+		panic(fmt.Sprintf("cannot infer parser: field '%v' not found in type '%v'", fieldName, grammar.subCtx.resultStructType.Name()))
+	}
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Slice {
+		fieldType = fieldType.Elem()
+	}
+	return mustGetResultStructTypeFromType(fieldType)
+}
+
+// Returns the node of nodes with the lowest cost.
+func (g *generator) cheapestNode(grammar *printGrammar, nodes []Node) Node {
+	best := nodes[0]
+	bestCost := g.nodeCost(grammar, best)
+	for _, node := range nodes[1:] {
+		if cost := g.nodeCost(grammar, node); cost < bestCost {
+			best, bestCost = node, cost
+		}
+	}
+	return best
+}
+
+// Returns the minimum nesting of struct types needed to generate structType.
+func (g *generator) typeCost(structType reflect.Type) int {
+	if cost, has := g.costs[structType]; has {
+		return cost
+	}
+	// Recursive references to a type being costed cannot end the recursion
+	g.costs[structType] = unboundedCost
+	grammar := g.grammarFor(structType)
+	cost := g.nodeCost(grammar, grammar.root.Child)
+	g.costs[structType] = cost
+	return cost
+}
+
+// Returns the minimum nesting of struct types needed to generate rawNode.
+func (g *generator) nodeCost(grammar *printGrammar, rawNode Node) int {
+	nestedCost := func(structType reflect.Type) int {
+		cost := g.typeCost(structType)
+		if cost == unboundedCost {
+			return cost
+		}
+		return cost + 1
+	}
+
+	switch node := rawNode.(type) {
+	case *providedParserKeyNode:
+		if _, has := g.opts.ProvidedGenerators[node.Name]; has {
+			return 0
+		}
+		if structType, has := g.opts.ProvidedTypes[node.Name]; has {
+			return nestedCost(structType)
+		}
+		return 0
+	case *inferNode:
+		return nestedCost(g.inferredStructType(grammar, node))
+	case *captureNode:
+		return g.nodeCost(grammar, node.Child)
+	case *seqNode:
+		cost := 0
+		for _, child := range node.Children {
+			if childCost := g.nodeCost(grammar, child); childCost > cost {
+				cost = childCost
+			}
+		}
+		return cost
+	case *orNode:
+		return g.nodeCost(grammar, g.cheapestNode(grammar, node.Children))
+	case *rangeNode:
+		if node.Range.min == 0 {
+			return 0
+		}
+		return g.nodeCost(grammar, node.Child)
+	case *maybeNode:
+		return 0
+	case *lookNode:
+		return g.nodeCost(grammar, node.Child)
+	case *namedNode:
+		return g.nodeCost(grammar, node.Child)
+	case *permNode:
+		cost := 0
+		for _, clause := range node.Clauses {
+			if childCost := g.nodeCost(grammar, clause.Child); childCost > cost {
+				cost = childCost
+			}
+		}
+		return cost
+	default:
+		return 0
+	}
+}
+
+func (g *generator) regexpFor(regex string) (*syntax.Regexp, error) {
+	if re, has := g.regexps[regex]; has {
+		return re, nil
+	}
+	re, err := syntax.Parse(regex, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate regex('%v'): %w", regex, err)
+	}
+	re = re.Simplify()
+	g.regexps[regex] = re
+	return re, nil
+}
+
+// Writes random text matching re to sb.
+func (g *generator) generateRegex(sb *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && g.opts.Rand.Intn(2) == 0 {
+				r = unicode.SimpleFold(r)
+			}
+			sb.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		sb.WriteRune(g.randomRuneInRanges(re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		sb.WriteRune(rune(' ' + g.opts.Rand.Intn('~'-' '+1)))
+	case syntax.OpCapture:
+		g.generateRegex(sb, re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.generateRegex(sb, sub)
+		}
+	case syntax.OpAlternate:
+		g.generateRegex(sb, re.Sub[g.opts.Rand.Intn(len(re.Sub))])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := re.Min, re.Max
+		switch re.Op {
+		case syntax.OpStar:
+			min, max = 0, -1
+		case syntax.OpPlus:
+			min, max = 1, -1
+		case syntax.OpQuest:
+			min, max = 0, 1
+		}
+		if max < 0 || max > min+g.opts.MaxRepeat {
+			max = min + g.opts.MaxRepeat
+		}
+		count := min + g.opts.Rand.Intn(max-min+1)
+		for i := 0; i < count; i++ {
+			g.generateRegex(sb, re.Sub[0])
+		}
+	default:
+		// Empty matches and assertions such as ^, $ and \b generate no text
+	}
+}
+
+// Returns a random rune within the pairs of inclusive ranges, preferring
+// printable ASCII runes when the ranges contain any.
+func (g *generator) randomRuneInRanges(ranges []rune) rune {
+	printable := make([]rune, 0)
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < ' ' {
+			lo = ' '
+		}
+		if hi > '~' {
+			hi = '~'
+		}
+		if lo <= hi {
+			printable = append(printable, lo, hi)
+		}
+	}
+	if len(printable) > 0 {
+		ranges = printable
+	}
+
+	total := 0
+	for i := 0; i+1 < len(ranges); i += 2 {
+		total += int(ranges[i+1]-ranges[i]) + 1
+	}
+	n := g.opts.Rand.Intn(total)
+	for i := 0; i+1 < len(ranges); i += 2 {
+		size := int(ranges[i+1]-ranges[i]) + 1
+		if n < size {
+			return ranges[i] + rune(n)
+		}
+		n -= size
+	}
+	panic("unreachable in randomRuneInRanges")
+}
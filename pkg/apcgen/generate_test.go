@@ -0,0 +1,106 @@
+package apcgen
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+type genTestValue struct {
+	Num  string         `apc:"$regex('-?[0-9]+(\\.[0-9]+)?') |"`
+	Str  string         `apc:"$regex('\"[a-z ]*\"') |"`
+	Bool string         `apc:"$('true' | 'false') |"`
+	Null string         `apc:"$NullParser |"`
+	List *genTestList   `apc:"$. |"`
+	Obj  *genTestObject `apc:"$."`
+}
+
+type genTestList struct {
+	Items []*genTestValue `apc:"'[' ($. (',' $.)*)? ']'"`
+}
+
+type genTestPair struct {
+	Key   string        `apc:"$regex('\"[a-z]+\"')"`
+	Value *genTestValue `apc:"':' $."`
+}
+
+type genTestObject struct {
+	Pairs []*genTestPair `apc:"'{' ($. (',' $.)*)? '}'"`
+}
+
+func newGenTestParser() apc.Parser[rune, *genTestValue] {
+	return BuildParser[*genTestValue](WithDefaultBuildOptions(
+		WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)),
+		WithParserOption("NullParser", apc.CastToAny(apc.ExactStr("null"))),
+	))
+}
+
+func newGenTestOptions(seed int64) *GenerateOptions {
+	return WithDefaultGenerateOptions(seed,
+		WithLimitsOption(3, 3),
+		WithGeneratorOption("NullParser", func(_ *rand.Rand) string { return "null" }),
+	)
+}
+
+func TestGenerateParsesBack(t *testing.T) {
+	parser := newGenTestParser()
+	opts := newGenTestOptions(1)
+	for i := 0; i < 200; i++ {
+		sentence, err := Generate[*genTestValue](opts)
+		assert.NoError(t, err)
+		ctx := apc.NewStringContext(testOriginName, sentence)
+		_, err = apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+		assert.NoError(t, err, sentence)
+	}
+}
+
+func TestGenerateIsSeeded(t *testing.T) {
+	first, err := Generate[*genTestValue](newGenTestOptions(42))
+	assert.NoError(t, err)
+	second, err := Generate[*genTestValue](newGenTestOptions(42))
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestGenerateRespectsMaxDepth(t *testing.T) {
+	opts := newGenTestOptions(7)
+	opts.MaxDepth = 0
+	for i := 0; i < 50; i++ {
+		sentence, err := Generate[*genTestValue](opts)
+		assert.NoError(t, err)
+		assert.NotContains(t, sentence, "[")
+		assert.NotContains(t, sentence, "{")
+	}
+}
+
+func TestGenerateRegexGenerator(t *testing.T) {
+	type Obj struct {
+		Words []string `apc:"$regex('[a-z]+'){2,2}"`
+	}
+	sentence, err := Generate[Obj](WithDefaultGenerateOptions(1,
+		WithRegexGeneratorOption("[a-z]+", func(_ *rand.Rand) string { return "word" }),
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, "word word", sentence)
+
+	type Provided struct {
+		Value string `apc:"$Unknown"`
+	}
+	_, err = Generate[Provided](WithDefaultGenerateOptions(1))
+	assert.EqualError(t, err, "cannot generate provided parser Unknown in type 'Provided': no generator or type provided")
+}
+
+func FuzzGeneratedValues(f *testing.F) {
+	assert.NoError(f, SeedCorpus[*genTestValue](f, 20, newGenTestOptions(3)))
+	parser := newGenTestParser()
+	f.Fuzz(func(t *testing.T, input string) {
+		ctx := apc.NewStringContext(testOriginName, input)
+		_, err := apc.Parse[rune](ctx, parser, apc.DefaultParseConfig)
+		if err != nil && !strings.Contains(err.Error(), "Parse Error") {
+			t.Errorf("unexpected error for %q: %v", input, err)
+		}
+	})
+}
@@ -58,3 +58,13 @@ type maybeNode struct {
 type lookNode struct {
 	Child Node
 }
+
+type permNode struct {
+	Clauses []*permClause
+}
+
+type permClause struct {
+	Child      Node
+	Required   bool
+	InputIndex int
+}
@@ -2,13 +2,14 @@ package apcgen
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/tpillow/apc/pkg/apc"
 )
 
 /*
 parenExpr = '(' expr ')'
-capturableValue = ( ident | '.' | '<str>' | string('') | regex('') | token('type' [, 'val']) | parenExpr )
+capturableValue = ( ident | '.' | '<str>' | string('') | regex('') | token('type' [, 'val']) | perm(value+) | parenExpr )
 valueMaybeCaptured = ( '$'? capturableValue )
 value = valueMaybeCaptured endRangeSpecifier?
 
@@ -22,9 +23,11 @@ root = expr
 */
 
 var (
-	parserInitialized = false
-	realExprParser    apc.Parser[rune, Node]
-	exprParser        = apc.Ref(&realExprParser)
+	parserInitOnce       sync.Once
+	realExprParser       apc.Parser[rune, Node]
+	exprParser           = apc.Ref(&realExprParser)
+	realPermClauseParser apc.Parser[rune, *permClause]
+	permClauseParser     = apc.Ref(&realPermClauseParser)
 
 	parenExprParser = apc.Map(
 		apc.Seq3(
@@ -147,12 +150,27 @@ var (
 		},
 	)
 
+	builtinPermParser = apc.Map(
+		apc.Seq4(
+			apc.ExactStr("perm"),
+			apc.Exact('('),
+			apc.OneOrMore(permClauseParser),
+			apc.Exact(')'),
+		),
+		func(node *apc.Seq4Node[string, rune, []*permClause, rune]) Node {
+			return &permNode{
+				Clauses: node.Result3,
+			}
+		},
+	)
+
 	builtinFuncParser = apc.Any(
 		builtinMatchRegexParser,
 		builtinLookParser,
 		builtinMatchStringParser,
 		builtinMatchTokenParser,
 		builtinNamedParser,
+		builtinPermParser,
 	)
 
 	capturableValueParser = apc.Any(
@@ -327,20 +345,43 @@ var (
 	)
 )
 
-func maybeInitParser() {
-	if parserInitialized {
-		return
-	}
-	parserInitialized = true
-
+func initParser() {
 	realExprParser = apc.Any(
 		orExprParser,
 		seqExprParser,
 	)
+
+	// A clause of perm(...) is optional if it ends with '?'. The InputIndex of the
+	// clause is the column of its first character, after skipping whitespace.
+	realPermClauseParser = func(ctx apc.Context[rune]) (*permClause, error) {
+		err := ctx.RunSkipParsers()
+		if err != nil {
+			return nil, err
+		}
+		inputIndex := ctx.GetCurOrigin().ColNum
+		node, err := valueParser(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		clause := &permClause{
+			Child:      node,
+			Required:   true,
+			InputIndex: inputIndex,
+		}
+		switch cNode := node.(type) {
+		case *maybeNode:
+			clause.Required = false
+		case *captureNode:
+			_, isMaybe := cNode.Child.(*maybeNode)
+			clause.Required = !isMaybe
+		}
+		return clause, nil
+	}
 }
 
 func parseFull(originName string, input string, debugParsers bool) (*rootNode, error) {
-	maybeInitParser()
+	parserInitOnce.Do(initParser)
 	ctx := apc.NewStringContext(originName, input)
 	ctx.DebugParsers = debugParsers
 	return apc.Parse[rune](ctx, rootParser, apc.DefaultParseConfig)
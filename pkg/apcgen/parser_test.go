@@ -200,3 +200,30 @@ func TestGeneric2(t *testing.T) {
 		),
 		node)
 }
+
+func TestPerm(t *testing.T) {
+	node, err := parseFull(testOriginName, `perm( $. 'a'? $'b'? )`, false)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		root1(&permNode{
+			Clauses: []*permClause{
+				{
+					Child:      &captureNode{InputIndex: 7, Child: &inferNode{InputIndex: 8}},
+					Required:   true,
+					InputIndex: 7,
+				},
+				{
+					Child:      &maybeNode{Child: &matchStringNode{Value: "a"}},
+					Required:   false,
+					InputIndex: 10,
+				},
+				{
+					Child:      &captureNode{InputIndex: 15, Child: &maybeNode{Child: &matchStringNode{Value: "b"}}},
+					Required:   false,
+					InputIndex: 15,
+				},
+			},
+		}),
+		node)
+}
@@ -0,0 +1,611 @@
+package apcgen
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// PrintFunc returns the text of a value matched by a provided parser.
+// Returning an error means the value cannot be printed by that parser.
+type PrintFunc func(value any) (string, error)
+
+type PrintOptionFunc func(opts *PrintOptions)
+
+// PrintOptions contains settings that can be passed to the Print function.
+type PrintOptions struct {
+	// Written between adjacent printed elements that are on the same line.
+	Space string
+	// Written once per indentation level at the start of each new line.
+	Indent string
+	// Literals that are followed by a new line.
+	NewlineAfter map[string]bool
+	// Literals that are followed by a new line and increase the indentation level.
+	IndentAfter map[string]bool
+	// Literals that are preceded by a new line and decrease the indentation level.
+	DedentBefore map[string]bool
+	// Functions printing the values of captured provided parsers, by parser name.
+	ProvidedPrinters map[string]PrintFunc
+}
+
+func WithDefaultPrintOptions(printFuncs ...PrintOptionFunc) *PrintOptions {
+	opts := &PrintOptions{
+		Space:            " ",
+		Indent:           "\t",
+		NewlineAfter:     make(map[string]bool),
+		IndentAfter:      make(map[string]bool),
+		DedentBefore:     make(map[string]bool),
+		ProvidedPrinters: make(map[string]PrintFunc),
+	}
+	for _, printFunc := range printFuncs {
+		printFunc(opts)
+	}
+	return opts
+}
+
+func WithSpacingOption(space string, indent string) PrintOptionFunc {
+	return func(opts *PrintOptions) {
+		opts.Space = space
+		opts.Indent = indent
+	}
+}
+
+func WithNewlineAfterOption(literals ...string) PrintOptionFunc {
+	return func(opts *PrintOptions) {
+		for _, literal := range literals {
+			opts.NewlineAfter[literal] = true
+		}
+	}
+}
+
+func WithIndentOption(openLiteral string, closeLiteral string) PrintOptionFunc {
+	return func(opts *PrintOptions) {
+		opts.IndentAfter[openLiteral] = true
+		opts.DedentBefore[closeLiteral] = true
+	}
+}
+
+func WithPrinterOption(name string, printFunc PrintFunc) PrintOptionFunc {
+	return func(opts *PrintOptions) {
+		if _, has := opts.ProvidedPrinters[name]; has {
+			panic(fmt.Sprintf("cannot use WithPrinterOption: name '%v' already specified", name))
+		}
+		opts.ProvidedPrinters[name] = printFunc
+	}
+}
+
+// PrintError is returned by Print when a value cannot be printed using the
+// grammar of its type.
+type PrintError struct {
+	// Name of the struct type being printed.
+	TypeName string
+	// Name of the field that could not be printed, if known.
+	FieldName string
+	Message   string
+	// Number of captures printed before the error occurred.
+	progress int
+	// Whether the error is due to a captured value not being present.
+	missing bool
+}
+
+func (err *PrintError) Error() string {
+	if err.FieldName == "" {
+		return fmt.Sprintf("cannot print type '%v': %v", err.TypeName, err.Message)
+	}
+	return fmt.Sprintf("cannot print field '%v' of type '%v': %v", err.FieldName, err.TypeName, err.Message)
+}
+
+// Writes the text of value to w by walking the apc struct tag grammar of its type,
+// emitting literals and captured field values, such that parsing the text with a
+// parser from BuildParser results in an equal value.
+//
+// Alternatives, optional values and repetitions are chosen by the values of the
+// fields they capture. Uncaptured regexes and provided parsers cannot be printed.
+// Captured provided parsers are printed by the PrintFunc of the same name, or as the
+// nested struct if the name is that of the value's type, or otherwise using fmt.
+//
+// Returns a *PrintError naming the field that could not be printed.
+func Print[T any](value T, w io.Writer, opts *PrintOptions) error {
+	p := &printer{
+		opts:     opts,
+		grammars: make(map[reflect.Type]*printGrammar),
+		regexps:  make(map[string]*regexp.Regexp),
+		out:      make([]string, 0),
+	}
+	err := p.printStruct(reflect.ValueOf(value))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, p.render())
+	return err
+}
+
+// The parsed grammar of a struct type.
+type printGrammar struct {
+	subCtx *buildSubcontext[rune]
+	root   *rootNode
+	// Names of the fields set by captures.
+	capturedFields []string
+}
+
+// The printing state of a struct value.
+type printState struct {
+	grammar *printGrammar
+	value   reflect.Value
+	// Number of printed elements of each slice field.
+	cursors map[string]int
+	// Printed scalar fields.
+	used map[string]bool
+	// Number of captures printed.
+	progress int
+}
+
+type printer struct {
+	opts     *PrintOptions
+	grammars map[reflect.Type]*printGrammar
+	regexps  map[string]*regexp.Regexp
+	out      []string
+}
+
+// A point printing can be restored to.
+type printSnapshot struct {
+	outLen   int
+	cursors  map[string]int
+	used     map[string]bool
+	progress int
+}
+
+func (p *printer) snapshot(st *printState) printSnapshot {
+	snap := printSnapshot{
+		outLen:   len(p.out),
+		cursors:  make(map[string]int, len(st.cursors)),
+		used:     make(map[string]bool, len(st.used)),
+		progress: st.progress,
+	}
+	for k, v := range st.cursors {
+		snap.cursors[k] = v
+	}
+	for k, v := range st.used {
+		snap.used[k] = v
+	}
+	return snap
+}
+
+func (p *printer) restore(st *printState, snap printSnapshot) {
+	p.out = p.out[:snap.outLen]
+	st.cursors = snap.cursors
+	st.used = snap.used
+	st.progress = snap.progress
+}
+
+func (p *printer) errorf(st *printState, fieldName string, format string, formatArgs ...any) *PrintError {
+	return &PrintError{
+		TypeName:  st.grammar.subCtx.resultStructType.Name(),
+		FieldName: fieldName,
+		Message:   fmt.Sprintf(format, formatArgs...),
+		progress:  st.progress,
+	}
+}
+
+func (p *printer) grammarFor(structType reflect.Type) *printGrammar {
+	if grammar, has := p.grammars[structType]; has {
+		return grammar
+	}
+	subCtx := newBuildSubContextFromType[rune](structType)
+	node, err := parseFull(structType.Name(), subCtx.grammarText, false)
+	if err != nil {
+		panic(fmt.Sprintf("error parsing parser definition for type '%v': %v", structType.Name(), err))
+	}
+	grammar := &printGrammar{
+		subCtx: subCtx,
+		root:   node,
+	}
+	walkNodes(node.Child, func(rawNode Node) {
+		if capNode, ok := rawNode.(*captureNode); ok {
+			grammar.capturedFields = append(grammar.capturedFields, subCtx.fieldNameFromCaptureIdx(capNode.InputIndex))
+		}
+	})
+	p.grammars[structType] = grammar
+	return grammar
+}
+
+// Prints the struct or pointer to struct val using the grammar of its type.
+func (p *printer) printStruct(val reflect.Value) error {
+	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return &PrintError{TypeName: val.Type().String(), Message: "cannot print a nil value"}
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("the printed type must be a struct or pointer to a struct; instead got: %v", val.Kind()))
+	}
+
+	st := &printState{
+		grammar: p.grammarFor(val.Type()),
+		value:   val,
+		cursors: make(map[string]int),
+		used:    make(map[string]bool),
+	}
+	err := p.printNode(st, st.grammar.root.Child, false)
+	if err != nil {
+		return err
+	}
+
+	// Every captured value must have been printed for the text to parse back into val
+	for _, fieldName := range st.grammar.capturedFields {
+		field := val.FieldByName(fieldName)
+		if field.Kind() == reflect.Slice {
+			if st.cursors[fieldName] < field.Len() {
+				return p.errorf(st, fieldName, "only %v of %v elements could be printed", st.cursors[fieldName], field.Len())
+			}
+		} else if !st.used[fieldName] && !field.IsZero() {
+			return p.errorf(st, fieldName, "value %v could not be printed", field.Interface())
+		}
+	}
+	return nil
+}
+
+// Prints the uncaptured rawNode. If strict is true, captures of scalar fields
+// with zero values fail, as if the input was not present.
+func (p *printer) printNode(st *printState, rawNode Node, strict bool) error {
+	switch node := rawNode.(type) {
+	case *matchStringNode:
+		p.out = append(p.out, node.Value)
+		return nil
+	case *matchRegexNode:
+		return p.errorf(st, "", "cannot print uncaptured regex('%v')", node.Regex)
+	case *providedParserKeyNode:
+		return p.errorf(st, "", "cannot print uncaptured provided parser %v", node.Name)
+	case *inferNode:
+		return p.errorf(st, st.grammar.subCtx.fieldNameFromCaptureIdx(node.InputIndex), "cannot print uncaptured inferred value")
+	case *matchTokenNode:
+		return p.errorf(st, "", "cannot print token('%v')", node.TokenType)
+	case *captureNode:
+		return p.printCapture(st, node, strict)
+	case *seqNode:
+		for _, child := range node.Children {
+			if err := p.printNode(st, child, strict); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *orNode:
+		return p.printAlternatives(st, node.Children, strict, func(child Node, strict bool) error {
+			return p.printNode(st, child, strict)
+		})
+	case *rangeNode:
+		for i := 0; node.Range.max < 0 || i < node.Range.max; i++ {
+			snap := p.snapshot(st)
+			err := p.printNode(st, node.Child, strict || i >= node.Range.min)
+			if i >= node.Range.min && (err != nil || st.progress == snap.progress) {
+				// Stop repeating once nothing more is captured
+				p.restore(st, snap)
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case *maybeNode:
+		snap := p.snapshot(st)
+		err := p.printNode(st, node.Child, true)
+		if err != nil || st.progress == snap.progress {
+			p.restore(st, snap)
+		}
+		return nil
+	case *lookNode:
+		return p.printNode(st, node.Child, strict)
+	case *namedNode:
+		return p.printNode(st, node.Child, strict)
+	case *permNode:
+		for _, clause := range node.Clauses {
+			if err := p.printNode(st, clause.Child, strict); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		panic(fmt.Sprintf("unknown node to process in printNode: %T", rawNode))
+	}
+}
+
+// Prints the first of nodes that can be printed, preferring nodes whose
+// captured values are present; nodes may print zero values only if no value
+// captured by any of the nodes remains to be printed. If none can be printed,
+// the error of the node that printed the most captures is returned.
+func (p *printer) printAlternatives(st *printState, nodes []Node, strict bool, printFunc func(Node, bool) error) error {
+	var bestErr *PrintError
+	passes := []bool{true}
+	if !strict && !p.anyUnprinted(st, nodes) {
+		passes = append(passes, false)
+	}
+	for _, passStrict := range passes {
+		for _, child := range nodes {
+			snap := p.snapshot(st)
+			err := printFunc(child, passStrict)
+			if err == nil {
+				return nil
+			}
+			p.restore(st, snap)
+			printErr, ok := err.(*PrintError)
+			if !ok {
+				return err
+			}
+			if bestErr == nil || printErr.progress > bestErr.progress ||
+				(printErr.progress == bestErr.progress && bestErr.missing && !printErr.missing) {
+				bestErr = printErr
+			}
+		}
+	}
+	return &PrintError{
+		TypeName:  bestErr.TypeName,
+		FieldName: bestErr.FieldName,
+		Message:   "no alternative matches: " + bestErr.Message,
+		progress:  bestErr.progress,
+		missing:   bestErr.missing,
+	}
+}
+
+// Returns whether any field captured within nodes has a value that remains to be printed.
+func (p *printer) anyUnprinted(st *printState, nodes []Node) bool {
+	unprinted := false
+	for _, node := range nodes {
+		walkNodes(node, func(rawNode Node) {
+			capNode, ok := rawNode.(*captureNode)
+			if !ok {
+				return
+			}
+			fieldName := st.grammar.subCtx.fieldNameFromCaptureIdx(capNode.InputIndex)
+			field := st.value.FieldByName(fieldName)
+			if field.Kind() == reflect.Slice {
+				unprinted = unprinted || st.cursors[fieldName] < field.Len()
+			} else {
+				unprinted = unprinted || (!st.used[fieldName] && !field.IsZero())
+			}
+		})
+	}
+	return unprinted
+}
+
+// Prints the value of the field captured by node.
+func (p *printer) printCapture(st *printState, node *captureNode, strict bool) error {
+	fieldName := st.grammar.subCtx.fieldNameFromCaptureIdx(node.InputIndex)
+	field := st.value.FieldByName(fieldName)
+	if !field.IsValid() {
+		panic(fmt.Sprintf("field '%v' not found on type '%v' via reflection", fieldName, st.grammar.subCtx.resultStructType.Name()))
+	}
+
+	if field.Kind() == reflect.Slice {
+		return p.printSliceCapture(st, fieldName, field, node.Child)
+	}
+
+	if st.used[fieldName] {
+		return p.errorf(st, fieldName, "value already printed")
+	}
+	if mNode, ok := node.Child.(*maybeNode); ok {
+		if !field.IsZero() {
+			if err := p.printCaptured(st, fieldName, mNode, field); err != nil {
+				return err
+			}
+		}
+	} else {
+		if strict && field.IsZero() {
+			err := p.errorf(st, fieldName, "no value to print")
+			err.missing = true
+			return err
+		}
+		if err := p.printCaptured(st, fieldName, node.Child, field); err != nil {
+			return err
+		}
+	}
+	st.used[fieldName] = true
+	st.progress++
+	return nil
+}
+
+// Prints the remaining elements of the slice field captured by a capture of rawNode.
+func (p *printer) printSliceCapture(st *printState, fieldName string, field reflect.Value, rawNode Node) error {
+	nextElem := func(childNode Node) error {
+		cursor := st.cursors[fieldName]
+		if cursor >= field.Len() {
+			return p.errorf(st, fieldName, "no more elements to print")
+		}
+		if err := p.printCaptured(st, fieldName, childNode, field.Index(cursor)); err != nil {
+			return err
+		}
+		st.cursors[fieldName] = cursor + 1
+		st.progress++
+		return nil
+	}
+
+	switch node := rawNode.(type) {
+	case *rangeNode:
+		count := 0
+		for (node.Range.max < 0 || count < node.Range.max) && st.cursors[fieldName] < field.Len() {
+			if err := nextElem(node.Child); err != nil {
+				return err
+			}
+			count++
+		}
+		if count < node.Range.min {
+			return p.errorf(st, fieldName, "expected at least %v elements but got %v", node.Range.min, count)
+		}
+		return nil
+	case *maybeNode:
+		if st.cursors[fieldName] < field.Len() {
+			return nextElem(node.Child)
+		}
+		return nil
+	default:
+		return nextElem(node)
+	}
+}
+
+// Prints val, the captured value of field fieldName, as matched by rawNode.
+func (p *printer) printCaptured(st *printState, fieldName string, rawNode Node, val reflect.Value) error {
+	switch node := rawNode.(type) {
+	case *matchStringNode:
+		if val.Kind() == reflect.String && val.String() != node.Value {
+			return p.errorf(st, fieldName, "value %q does not match '%v'", val.String(), node.Value)
+		}
+		p.out = append(p.out, node.Value)
+		return nil
+	case *matchRegexNode:
+		text := fmt.Sprintf("%v", val.Interface())
+		if !p.regexpFor(node.Regex).MatchString(text) {
+			return p.errorf(st, fieldName, "value %q does not match regex('%v')", text, node.Regex)
+		}
+		p.out = append(p.out, text)
+		return nil
+	case *providedParserKeyNode:
+		if printFunc, has := p.opts.ProvidedPrinters[node.Name]; has {
+			text, err := printFunc(val.Interface())
+			if err != nil {
+				return p.errorf(st, fieldName, "%v", err)
+			}
+			p.out = append(p.out, text)
+			return nil
+		}
+		if structType, ok := structTypeOf(val); ok && structType.Name() == node.Name {
+			return p.printNestedStruct(st, val)
+		}
+		p.out = append(p.out, fmt.Sprintf("%v", val.Interface()))
+		return nil
+	case *inferNode:
+		return p.printNestedStruct(st, val)
+	case *maybeNode:
+		if val.Kind() == reflect.Bool {
+			// The field is set if the child matched, without capturing its value
+			if !val.Bool() {
+				return nil
+			}
+			return p.printNode(st, node.Child, true)
+		}
+		if val.IsZero() {
+			return nil
+		}
+		return p.printCaptured(st, fieldName, node.Child, val)
+	case *rangeNode:
+		return p.printCaptured(st, fieldName, node.Child, val)
+	case *lookNode:
+		return p.printCaptured(st, fieldName, node.Child, val)
+	case *namedNode:
+		return p.printCaptured(st, fieldName, node.Child, val)
+	case *orNode:
+		return p.printAlternatives(st, node.Children, true, func(child Node, _ bool) error {
+			return p.printCaptured(st, fieldName, child, val)
+		})
+	default:
+		return p.errorf(st, fieldName, "cannot print a captured %v", nodeKindName(rawNode))
+	}
+}
+
+// Prints the nested struct val, reporting errors as a failure to print it.
+func (p *printer) printNestedStruct(st *printState, val reflect.Value) error {
+	err := p.printStruct(val)
+	if printErr, ok := err.(*PrintError); ok {
+		// Progress within the nested struct counts towards choosing alternatives
+		printErr.progress += st.progress
+	}
+	return err
+}
+
+func (p *printer) regexpFor(regex string) *regexp.Regexp {
+	if re, has := p.regexps[regex]; has {
+		return re
+	}
+	re := regexp.MustCompile("^(?:" + regex + ")$")
+	p.regexps[regex] = re
+	return re
+}
+
+// Returns the printed elements joined using the spacing and indentation options.
+func (p *printer) render() string {
+	var sb strings.Builder
+	indent := 0
+	newline := false
+	for i, text := range p.out {
+		if p.opts.DedentBefore[text] {
+			if indent > 0 {
+				indent--
+			}
+			newline = true
+		}
+		if newline {
+			sb.WriteString("\n")
+			sb.WriteString(strings.Repeat(p.opts.Indent, indent))
+			newline = false
+		} else if i > 0 {
+			sb.WriteString(p.opts.Space)
+		}
+		sb.WriteString(text)
+		if p.opts.IndentAfter[text] {
+			indent++
+			newline = true
+		}
+		if p.opts.NewlineAfter[text] {
+			newline = true
+		}
+	}
+	if newline {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Returns the struct type of val if it is a struct or pointer to a struct.
+func structTypeOf(val reflect.Value) (reflect.Type, bool) {
+	typ := val.Type()
+	if val.Kind() == reflect.Interface && !val.IsNil() {
+		typ = val.Elem().Type()
+	}
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ, typ.Kind() == reflect.Struct
+}
+
+// Calls visit with rawNode and each of its descendants.
+func walkNodes(rawNode Node, visit func(Node)) {
+	visit(rawNode)
+	switch node := rawNode.(type) {
+	case *captureNode:
+		walkNodes(node.Child, visit)
+	case *seqNode:
+		for _, child := range node.Children {
+			walkNodes(child, visit)
+		}
+	case *orNode:
+		for _, child := range node.Children {
+			walkNodes(child, visit)
+		}
+	case *rangeNode:
+		walkNodes(node.Child, visit)
+	case *maybeNode:
+		walkNodes(node.Child, visit)
+	case *lookNode:
+		walkNodes(node.Child, visit)
+	case *namedNode:
+		walkNodes(node.Child, visit)
+	case *permNode:
+		for _, clause := range node.Clauses {
+			walkNodes(clause.Child, visit)
+		}
+	}
+}
+
+// Returns a readable name of the kind of rawNode.
+func nodeKindName(rawNode Node) string {
+	switch rawNode.(type) {
+	case *seqNode:
+		return "sequence"
+	case *permNode:
+		return "perm"
+	default:
+		return strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", rawNode), "*apcgen."), "Node")
+	}
+}
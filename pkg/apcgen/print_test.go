@@ -0,0 +1,106 @@
+package apcgen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+type printTestValue struct {
+	Object *printTestBlock `apc:"$. |"`
+	Str    string          `apc:"$regex('\"[^\"]*\"') |"`
+	Num    int64           `apc:"$IntParser |"`
+	Ident  string          `apc:"$regex('[a-z]+')"`
+}
+
+type printTestEntry struct {
+	Key      string         `apc:"$regex('[a-z]+')"`
+	Disabled bool           `apc:"$'disabled'?"`
+	Value    printTestValue `apc:"'=' $printTestValue"`
+	Comment  string         `apc:"('#' $regex('[a-z]+'))? ';'"`
+}
+
+type printTestBlock struct {
+	Name    string            `apc:"'block' ($regex('[a-z]+'))? '{'"`
+	Entries []*printTestEntry `apc:"$.* '}'"`
+}
+
+func newPrintTestParser() apc.Parser[rune, *printTestBlock] {
+	return BuildParser[*printTestBlock](WithDefaultBuildOptions(
+		WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)),
+		WithParserOption("IntParser", apc.CastToAny(apc.IntParser)),
+		WithBuildParserOption[printTestValue](),
+	))
+}
+
+func TestPrintRoundTrip(t *testing.T) {
+	value := &printTestBlock{
+		Name: "root",
+		Entries: []*printTestEntry{
+			{Key: "a", Value: printTestValue{Num: 1}},
+			{Key: "b", Value: printTestValue{Str: `"hi"`}, Disabled: true},
+			{Key: "c", Value: printTestValue{Ident: "x"}, Comment: "note"},
+			{Key: "d", Value: printTestValue{Object: &printTestBlock{
+				Entries: []*printTestEntry{{Key: "e", Value: printTestValue{Num: 2}}},
+			}}},
+		},
+	}
+
+	var sb strings.Builder
+	err := Print(value, &sb, WithDefaultPrintOptions(
+		WithIndentOption("{", "}"),
+		WithNewlineAfterOption(";"),
+		WithSpacingOption(" ", "  "),
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, "block root {\n"+
+		"  a = 1 ;\n"+
+		"  b disabled = \"hi\" ;\n"+
+		"  c = x # note ;\n"+
+		"  d = block {\n"+
+		"    e = 2 ;\n"+
+		"  } ;\n"+
+		"}", sb.String())
+
+	ctx := apc.NewStringContext(testOriginName, sb.String())
+	node, err := apc.Parse[rune](ctx, newPrintTestParser(), apc.DefaultParseConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, value, node)
+}
+
+func TestPrintErrors(t *testing.T) {
+	var sb strings.Builder
+	err := Print(&printTestBlock{
+		Entries: []*printTestEntry{{Key: "A", Value: printTestValue{Num: 1}}},
+	}, &sb, WithDefaultPrintOptions())
+	assert.EqualError(t, err, `cannot print field 'Key' of type 'printTestEntry': value "A" does not match regex('[a-z]+')`)
+
+	err = Print(&printTestBlock{
+		Entries: []*printTestEntry{{Key: "a", Value: printTestValue{Str: "bad"}}},
+	}, &sb, WithDefaultPrintOptions())
+	assert.EqualError(t, err, `cannot print field 'Str' of type 'printTestValue': no alternative matches: value "bad" does not match regex('"[^"]*"')`)
+
+	err = Print(&printTestBlock{
+		Entries: []*printTestEntry{{Key: "a", Value: printTestValue{Ident: "1x"}}},
+	}, &sb, WithDefaultPrintOptions())
+	assert.EqualError(t, err, `cannot print field 'Ident' of type 'printTestValue': no alternative matches: value "1x" does not match regex('[a-z]+')`)
+}
+
+func TestPrintProvidedPrinter(t *testing.T) {
+	type Obj struct {
+		Values []float64 `apc:"'[' $FloatParser* ']'"`
+	}
+
+	var sb strings.Builder
+	err := Print(Obj{Values: []float64{1.5, 2}}, &sb, WithDefaultPrintOptions(
+		WithSpacingOption("", ""),
+		WithPrinterOption("FloatParser", func(value any) (string, error) {
+			return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.3f", value), "0"), ".") + " ", nil
+		}),
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, "[1.5 2 ]", sb.String())
+}
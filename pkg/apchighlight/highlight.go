@@ -0,0 +1,141 @@
+// Package apchighlight colorizes source text using the same lexer that parses it,
+// writing ANSI-colored text or HTML spans styled by a Theme.
+package apchighlight
+
+import (
+	"errors"
+
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+// The kind of text in a Span.
+type SpanKind int
+
+const (
+	// Text of a Token produced by the lexer.
+	SpanToken SpanKind = iota
+	// Text between Tokens, skipped by the lexer, such as whitespace and comments.
+	SpanSkipped
+	// Text the lexer could not lex.
+	SpanInvalid
+)
+
+// A piece of highlighted source text.
+type Span struct {
+	Kind SpanKind
+	// The TokenType of the Token, if Kind is SpanToken.
+	Type apc.TokenType
+	Text string
+}
+
+// Splits text into Spans by lexing it with lexer, such as one built by
+// apcgen.BuildSimpleLexer. Concatenating the Text of the Spans reproduces text.
+//
+// Text skipped by the lexer is kept as SpanSkipped. If the lexer fails, the text
+// it failed on is kept as SpanInvalid, and lexing resumes at the next rune.
+func Highlight(originName string, text string, lexer apc.Parser[rune, apc.Token]) []Span {
+	runes := []rune(text)
+	offsets := newOffsetTable(runes)
+	ctx := apc.NewStringContext(originName, text)
+	reader := apc.NewParseReader[rune](ctx, lexer)
+
+	var spans []Span
+	// The offset just past the last highlighted rune
+	lastEnd := 0
+	addSpan := func(kind SpanKind, tokType apc.TokenType, start int, end int) {
+		if end <= start {
+			return
+		}
+		spans = append(spans, Span{Kind: kind, Type: tokType, Text: string(runes[start:end])})
+		lastEnd = end
+	}
+
+	for {
+		if _, err := ctx.Peek(0, 1); errors.Is(err, apc.ErrEOF) {
+			break
+		}
+		tok, org, err := reader.Read()
+		if errors.Is(err, apc.ErrEOF) {
+			// The lexer skipped the rest of the text before reaching the end of input
+			break
+		}
+		if err != nil {
+			// A ParseError is at the rune the lexer failed on, after any text it
+			// skipped itself. Anything consumed before failing is kept as invalid.
+			failed := offsets.offset(org)
+			if perr, ok := err.(*apc.ParseError); ok && perr.Origin.LineNum > 0 {
+				failed = offsets.offset(perr.Origin)
+			}
+			for offsets.curOffset(ctx) <= failed {
+				if _, err := ctx.Consume(1); err != nil {
+					break
+				}
+			}
+			addSpan(SpanSkipped, apc.NilTokenType, lastEnd, failed)
+			addSpan(SpanInvalid, apc.NilTokenType, lastEnd, offsets.curOffset(ctx))
+			continue
+		}
+
+		start, end := offsets.offset(tok.OriginRange.Start), offsets.offset(tok.OriginRange.End)
+		if tok.OriginRange.IsZero() || start < lastEnd || end <= start {
+			// Without a usable range, the Token cannot be placed in the text
+			if offsets.offset(org) >= offsets.curOffset(ctx) {
+				// Nothing was consumed, so skip a rune to make progress
+				if _, err := ctx.Consume(1); err != nil {
+					break
+				}
+			}
+			continue
+		}
+		addSpan(SpanSkipped, apc.NilTokenType, lastEnd, start)
+		addSpan(SpanToken, tok.Type, start, end)
+	}
+	addSpan(SpanSkipped, apc.NilTokenType, lastEnd, len(runes))
+	return spans
+}
+
+// Converts Origins to offsets in a slice of runes.
+type offsetTable struct {
+	// The offset of the first rune of each line.
+	lineStarts []int
+	length     int
+}
+
+func newOffsetTable(runes []rune) *offsetTable {
+	table := &offsetTable{
+		lineStarts: []int{0},
+		length:     len(runes),
+	}
+	for i, r := range runes {
+		if r == '\n' {
+			table.lineStarts = append(table.lineStarts, i+1)
+		}
+	}
+	return table
+}
+
+// Returns the offset of the next unconsumed rune of ctx.
+func (table *offsetTable) curOffset(ctx apc.Context[rune]) int {
+	// At the end of input, GetCurOrigin is that of the last rune
+	if _, err := ctx.Peek(0, 1); errors.Is(err, apc.ErrEOF) {
+		return table.length
+	}
+	return table.offset(ctx.GetCurOrigin())
+}
+
+// Returns the offset of the rune at origin, clamped to the runes. Unknown
+// line numbers are treated as the end of the runes.
+func (table *offsetTable) offset(origin apc.Origin) int {
+	line := origin.LineNum - 1
+	if line < 0 || line >= len(table.lineStarts) {
+		return table.length
+	}
+	offset := table.lineStarts[line] + origin.ColNum - 1
+	if offset < 0 {
+		return 0
+	}
+	if offset > table.length {
+		return table.length
+	}
+	return offset
+}
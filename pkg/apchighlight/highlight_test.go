@@ -0,0 +1,87 @@
+package apchighlight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+	"github.com/tpillow/apc/pkg/apcgen"
+)
+
+func newTestLexer() apc.Parser[rune, apc.Token] {
+	return apcgen.BuildSimpleLexer(apcgen.SimpleLexerBuildOptions{
+		IdentifierTokenType:         "ident",
+		IdentifierParser:            apc.Regex("[a-z]+"),
+		SpecialIdentifierTokenTypes: []apc.TokenType{"let"},
+		ExactMatchTokenTypes:        []apc.TokenType{"=", ";"},
+		ProvidedParsers: []apc.Parser[rune, apc.Token]{
+			apc.Map(apc.Regex("[0-9]+"), func(node string) apc.Token {
+				return apc.Token{Type: "number", Value: node}
+			}),
+		},
+		SkipParsers: []apc.Parser[rune, any]{
+			apc.CastToAny(apc.WhitespaceParser),
+			apc.CastToAny(apc.Regex("#[^\n]*")),
+		},
+	})
+}
+
+func joinSpans(spans []Span) string {
+	var sb strings.Builder
+	for _, span := range spans {
+		sb.WriteString(span.Text)
+	}
+	return sb.String()
+}
+
+func TestHighlightKeepsSkippedText(t *testing.T) {
+	input := "let x = 12; # twelve\n  let y=3;"
+	spans := Highlight("test", input, newTestLexer())
+	assert.Equal(t, input, joinSpans(spans))
+	assert.Equal(t, []Span{
+		{Kind: SpanToken, Type: "let", Text: "let"},
+		{Kind: SpanSkipped, Text: " "},
+		{Kind: SpanToken, Type: "ident", Text: "x"},
+		{Kind: SpanSkipped, Text: " "},
+		{Kind: SpanToken, Type: "=", Text: "="},
+		{Kind: SpanSkipped, Text: " "},
+		{Kind: SpanToken, Type: "number", Text: "12"},
+		{Kind: SpanToken, Type: ";", Text: ";"},
+		{Kind: SpanSkipped, Text: " # twelve\n  "},
+		{Kind: SpanToken, Type: "let", Text: "let"},
+		{Kind: SpanSkipped, Text: " "},
+		{Kind: SpanToken, Type: "ident", Text: "y"},
+		{Kind: SpanToken, Type: "=", Text: "="},
+		{Kind: SpanToken, Type: "number", Text: "3"},
+		{Kind: SpanToken, Type: ";", Text: ";"},
+	}, spans)
+}
+
+func TestHighlightTrailingSkippedText(t *testing.T) {
+	spans := Highlight("test", "x # done\n", newTestLexer())
+	assert.Equal(t, []Span{
+		{Kind: SpanToken, Type: "ident", Text: "x"},
+		{Kind: SpanSkipped, Text: " # done\n"},
+	}, spans)
+	assert.Empty(t, Highlight("test", "", newTestLexer()))
+}
+
+func TestHighlightLexErrors(t *testing.T) {
+	input := "x = @!\n y ü;"
+	spans := Highlight("test", input, newTestLexer())
+	assert.Equal(t, input, joinSpans(spans))
+	assert.Equal(t, []Span{
+		{Kind: SpanToken, Type: "ident", Text: "x"},
+		{Kind: SpanSkipped, Text: " "},
+		{Kind: SpanToken, Type: "=", Text: "="},
+		{Kind: SpanSkipped, Text: " "},
+		{Kind: SpanInvalid, Text: "@"},
+		{Kind: SpanInvalid, Text: "!"},
+		{Kind: SpanSkipped, Text: "\n "},
+		{Kind: SpanToken, Type: "ident", Text: "y"},
+		{Kind: SpanSkipped, Text: " "},
+		{Kind: SpanInvalid, Text: "ü"},
+		{Kind: SpanToken, Type: ";", Text: ";"},
+	}, spans)
+}
@@ -0,0 +1,145 @@
+package apchighlight
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+// How to display a Span.
+type Style struct {
+	// The text color, as "#rrggbb" or "#rgb". Empty for the default color.
+	Color     string
+	Bold      bool
+	Italic    bool
+	Underline bool
+	// The CSS class of HTML spans, if any.
+	Class string
+}
+
+// Returns true if the style is the zero value, displaying text unstyled.
+func (style Style) IsZero() bool {
+	return style == Style{}
+}
+
+// Maps the Spans of highlighted text to Styles.
+type Theme struct {
+	// The style of tokens of each TokenType. Tokens of other types are unstyled.
+	Styles map[apc.TokenType]Style
+	// The style of text skipped by the lexer, such as whitespace and comments.
+	Skipped Style
+	// The style of text the lexer could not lex.
+	Invalid Style
+}
+
+// Returns the style of span.
+func (theme *Theme) StyleOf(span Span) Style {
+	switch span.Kind {
+	case SpanToken:
+		return theme.Styles[span.Type]
+	case SpanSkipped:
+		return theme.Skipped
+	default:
+		return theme.Invalid
+	}
+}
+
+// Returns the red, green and blue components of a "#rrggbb" or "#rgb" color.
+func parseColor(color string) (r uint8, g uint8, b uint8, ok bool) {
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 || !strings.HasPrefix(color, "#") {
+		return 0, 0, 0, false
+	}
+	val, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return uint8(val >> 16), uint8(val >> 8), uint8(val), true
+}
+
+// Returns the ANSI SGR escape sequence selecting style, or "" if it has no
+// effect in a terminal. Colors are written as 24-bit colors.
+func ansiEscape(style Style) string {
+	var params []string
+	if style.Bold {
+		params = append(params, "1")
+	}
+	if style.Italic {
+		params = append(params, "3")
+	}
+	if style.Underline {
+		params = append(params, "4")
+	}
+	if r, g, b, ok := parseColor(style.Color); ok {
+		params = append(params, fmt.Sprintf("38;2;%v;%v;%v", r, g, b))
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// Writes spans to w as text colored with ANSI escape sequences, resetting the
+// style after each styled span.
+func WriteANSI(w io.Writer, spans []Span, theme *Theme) error {
+	for _, span := range spans {
+		escape := ansiEscape(theme.StyleOf(span))
+		text := span.Text
+		if escape != "" {
+			text = escape + text + "\x1b[0m"
+		}
+		if _, err := io.WriteString(w, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the attributes of an HTML span element styled by style, or "" if it is unstyled.
+func htmlAttributes(style Style) string {
+	var css []string
+	if _, _, _, ok := parseColor(style.Color); ok {
+		css = append(css, "color:"+style.Color)
+	}
+	if style.Bold {
+		css = append(css, "font-weight:bold")
+	}
+	if style.Italic {
+		css = append(css, "font-style:italic")
+	}
+	if style.Underline {
+		css = append(css, "text-decoration:underline")
+	}
+
+	var attrs []string
+	if style.Class != "" {
+		attrs = append(attrs, fmt.Sprintf(`class="%v"`, html.EscapeString(style.Class)))
+	}
+	if len(css) > 0 {
+		attrs = append(attrs, fmt.Sprintf(`style="%v"`, strings.Join(css, ";")))
+	}
+	return strings.Join(attrs, " ")
+}
+
+// Writes spans to w as HTML-escaped text, with styled spans wrapped in span
+// elements with inline styles and their class. The caller provides any enclosing
+// element, such as a pre element.
+func WriteHTML(w io.Writer, spans []Span, theme *Theme) error {
+	for _, span := range spans {
+		text := html.EscapeString(span.Text)
+		if attrs := htmlAttributes(theme.StyleOf(span)); attrs != "" {
+			text = "<span " + attrs + ">" + text + "</span>"
+		}
+		if _, err := io.WriteString(w, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package apchighlight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+var testTheme = &Theme{
+	Styles: map[apc.TokenType]Style{
+		"let":    {Color: "#c0f", Bold: true, Class: "kw"},
+		"number": {Color: "#102030"},
+	},
+	Skipped: Style{Italic: true},
+}
+
+var testSpans = []Span{
+	{Kind: SpanToken, Type: "let", Text: "let"},
+	{Kind: SpanSkipped, Text: " "},
+	{Kind: SpanToken, Type: "ident", Text: "a<b"},
+	{Kind: SpanToken, Type: "number", Text: "1"},
+	{Kind: SpanInvalid, Text: "&"},
+}
+
+func TestParseColor(t *testing.T) {
+	r, g, b, ok := parseColor("#102030")
+	assert.True(t, ok)
+	assert.Equal(t, []uint8{0x10, 0x20, 0x30}, []uint8{r, g, b})
+	r, g, b, ok = parseColor("#c0f")
+	assert.True(t, ok)
+	assert.Equal(t, []uint8{0xcc, 0x00, 0xff}, []uint8{r, g, b})
+	for _, color := range []string{"", "102030", "#12345", "#xyzxyz", "red"} {
+		_, _, _, ok = parseColor(color)
+		assert.False(t, ok, color)
+	}
+}
+
+func TestWriteANSI(t *testing.T) {
+	var sb strings.Builder
+	assert.NoError(t, WriteANSI(&sb, testSpans, testTheme))
+	assert.Equal(t,
+		"\x1b[1;38;2;204;0;255mlet\x1b[0m"+
+			"\x1b[3m \x1b[0m"+
+			"a<b"+
+			"\x1b[38;2;16;32;48m1\x1b[0m"+
+			"&",
+		sb.String())
+}
+
+func TestWriteHTML(t *testing.T) {
+	var sb strings.Builder
+	assert.NoError(t, WriteHTML(&sb, testSpans, testTheme))
+	assert.Equal(t,
+		`<span class="kw" style="color:#c0f;font-weight:bold">let</span>`+
+			`<span style="font-style:italic"> </span>`+
+			`a&lt;b`+
+			`<span style="color:#102030">1</span>`+
+			`&amp;`,
+		sb.String())
+}
+
+func TestWriteUnstyled(t *testing.T) {
+	var ansi, html strings.Builder
+	assert.NoError(t, WriteANSI(&ansi, testSpans, &Theme{}))
+	assert.NoError(t, WriteHTML(&html, testSpans, &Theme{}))
+	assert.Equal(t, "let a<b1&", ansi.String())
+	assert.Equal(t, "let a&lt;b1&amp;", html.String())
+}
@@ -0,0 +1,187 @@
+package apclsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Returned by Client methods once the connection to the server has closed.
+var ErrClientClosed = errors.New("client connection closed")
+
+// A notification sent by the server to a Client.
+type Notification struct {
+	Method string
+	Params json.RawMessage
+}
+
+// An in-process LSP client connected to a Server by pipes, for testing servers
+// without an editor.
+type Client struct {
+	w      *io.PipeWriter
+	lock   sync.Mutex
+	nextID int
+	// Channels awaiting the response to each pending request, by ID.
+	pending       map[string]chan *rpcMessage
+	notifications []Notification
+	// Closed and replaced each time a notification is received.
+	notified chan struct{}
+	closed   bool
+	// Receives the result of Serve once the server stops.
+	served chan error
+}
+
+// Returns a Client connected to server, which serves it in a new goroutine.
+func NewClient(server *Server) *Client {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	client := &Client{
+		w:        clientW,
+		pending:  make(map[string]chan *rpcMessage),
+		notified: make(chan struct{}),
+		served:   make(chan error, 1),
+	}
+
+	go func() {
+		err := server.Serve(serverR, serverW)
+		serverW.Close()
+		serverR.Close()
+		client.served <- err
+	}()
+	go client.readLoop(bufio.NewReader(clientR))
+	return client
+}
+
+func (client *Client) readLoop(r *bufio.Reader) {
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			client.lock.Lock()
+			client.closed = true
+			for _, ch := range client.pending {
+				close(ch)
+			}
+			client.pending = nil
+			close(client.notified)
+			client.lock.Unlock()
+			return
+		}
+
+		client.lock.Lock()
+		if msg.ID != nil {
+			if ch, ok := client.pending[string(*msg.ID)]; ok {
+				delete(client.pending, string(*msg.ID))
+				ch <- msg
+			}
+		} else {
+			client.notifications = append(client.notifications, Notification{Method: msg.Method, Params: msg.Params})
+			close(client.notified)
+			client.notified = make(chan struct{})
+		}
+		client.lock.Unlock()
+	}
+}
+
+// Sends a request and waits for its response, decoding its result into result
+// unless result is nil. Returns a *ResponseError if the server responded with an error.
+func (client *Client) Call(method string, params any, result any) error {
+	rawParams, err := marshalRaw(params)
+	if err != nil {
+		return err
+	}
+
+	client.lock.Lock()
+	if client.closed {
+		client.lock.Unlock()
+		return ErrClientClosed
+	}
+	client.nextID++
+	id := json.RawMessage(strconv.Itoa(client.nextID))
+	ch := make(chan *rpcMessage, 1)
+	client.pending[string(id)] = ch
+	client.lock.Unlock()
+
+	if err := client.write(&rpcMessage{ID: &id, Method: method, Params: rawParams}); err != nil {
+		client.lock.Lock()
+		delete(client.pending, string(id))
+		client.lock.Unlock()
+		return err
+	}
+	resp, ok := <-ch
+	if !ok {
+		return ErrClientClosed
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// Sends a notification.
+func (client *Client) Notify(method string, params any) error {
+	rawParams, err := marshalRaw(params)
+	if err != nil {
+		return err
+	}
+	return client.write(&rpcMessage{Method: method, Params: rawParams})
+}
+
+// Writes msg to the server, returning ErrClientClosed if the server has stopped reading.
+func (client *Client) write(msg *rpcMessage) error {
+	err := writeMessage(client.w, msg)
+	if errors.Is(err, io.ErrClosedPipe) {
+		return ErrClientClosed
+	}
+	return err
+}
+
+// Waits for the next notification with the given method not yet waited for, and
+// decodes its params into params unless params is nil. Notifications with other
+// methods received before it are kept for later calls.
+func (client *Client) WaitNotification(ctx context.Context, method string, params any) error {
+	for {
+		client.lock.Lock()
+		for i, notification := range client.notifications {
+			if notification.Method == method {
+				client.notifications = append(client.notifications[:i], client.notifications[i+1:]...)
+				client.lock.Unlock()
+				if params == nil {
+					return nil
+				}
+				return json.Unmarshal(notification.Params, params)
+			}
+		}
+		closed, notified := client.closed, client.notified
+		client.lock.Unlock()
+		if closed {
+			return ErrClientClosed
+		}
+
+		select {
+		case <-notified:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Sends the shutdown request and exit notification, and returns the result of
+// the server's Serve once it stops.
+func (client *Client) Close() error {
+	if err := client.Call("shutdown", nil, nil); err != nil {
+		return err
+	}
+	if err := client.Notify("exit", nil); err != nil {
+		return err
+	}
+	err := <-client.served
+	client.w.Close()
+	return err
+}
@@ -0,0 +1,259 @@
+package apclsp
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+// An open text document and the result of parsing it.
+type document struct {
+	uri   string
+	lines []string
+	// The result of parsing the document, if it parsed without error.
+	result any
+	err    error
+}
+
+func newDocument(uri string, text string) *document {
+	return &document{
+		uri:   uri,
+		lines: strings.Split(text, "\n"),
+	}
+}
+
+// Returns the LSP Position of origin, counting columns in UTF-16 code units as LSP
+// does by default. Positions outside of the document are clamped to it.
+func (doc *document) position(origin apc.Origin) Position {
+	line := origin.LineNum - 1
+	if line < 0 {
+		return Position{}
+	}
+	if line >= len(doc.lines) {
+		line = len(doc.lines) - 1
+		return Position{Line: line, Character: utf16Len([]rune(doc.lines[line]))}
+	}
+	runes := []rune(doc.lines[line])
+	col := origin.ColNum - 1
+	if col < 0 {
+		col = 0
+	} else if col > len(runes) {
+		col = len(runes)
+	}
+	return Position{Line: line, Character: utf16Len(runes[:col])}
+}
+
+func (doc *document) rangeOf(orgRange apc.OriginRange) Range {
+	return Range{
+		Start: doc.position(orgRange.Start),
+		End:   doc.position(orgRange.End),
+	}
+}
+
+// Returns the number of UTF-16 code units needed to encode runes.
+func utf16Len(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// Returns the LSP diagnostics of the parse error of the document, if any.
+func (doc *document) diagnostics(source string) []Diagnostic {
+	diags := []Diagnostic{}
+	if doc.err == nil {
+		return diags
+	}
+
+	adiag := apc.NewDiagnostic(doc.err)
+	diag := Diagnostic{
+		Range:    doc.rangeOf(adiag.OriginRange),
+		Severity: lspSeverity(adiag.Severity),
+		Source:   source,
+		Message:  adiag.Message,
+	}
+	for _, related := range adiag.Related {
+		// Related locations in other documents cannot be converted, and those
+		// without a location are only repeated in the message
+		if related.OriginRange.IsZero() || related.OriginRange.Start.Name != doc.uri {
+			continue
+		}
+		diag.RelatedInformation = append(diag.RelatedInformation, DiagnosticRelatedInformation{
+			Location: Location{URI: doc.uri, Range: doc.rangeOf(related.OriginRange)},
+			Message:  related.Message,
+		})
+	}
+	return append(diags, diag)
+}
+
+func lspSeverity(severity apc.Severity) int {
+	switch severity {
+	case apc.SeverityWarning:
+		return DiagnosticSeverityWarning
+	case apc.SeverityInfo:
+		return DiagnosticSeverityInformation
+	case apc.SeverityHint:
+		return DiagnosticSeverityHint
+	default:
+		return DiagnosticSeverityError
+	}
+}
+
+var (
+	originRangeType = reflect.TypeOf(apc.OriginRange{})
+	apcPkgPath      = originRangeType.PkgPath()
+)
+
+// Returns a DocumentSymbol for each struct of the parse result with a known
+// OriginRange field, named by its type. Structs nested within it are its children.
+func (doc *document) symbols() []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	if doc.result == nil {
+		return symbols
+	}
+	return append(symbols, doc.symbolsOf(reflect.ValueOf(doc.result))...)
+}
+
+func (doc *document) symbolsOf(val reflect.Value) []DocumentSymbol {
+	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		var symbols []DocumentSymbol
+		for i := 0; i < val.Len(); i++ {
+			symbols = append(symbols, doc.symbolsOf(val.Index(i))...)
+		}
+		return symbols
+	case reflect.Struct:
+		// Values such as Tokens and CSTNodes are not symbols of the document
+		if val.Type().PkgPath() == apcPkgPath {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	var children []DocumentSymbol
+	detail := ""
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if detail == "" && field.Type.Kind() == reflect.String {
+			detail = val.Field(i).String()
+		}
+		children = append(children, doc.symbolsOf(val.Field(i))...)
+	}
+
+	orgRangeField, ok := val.Type().FieldByName("OriginRange")
+	if !ok || orgRangeField.Type != originRangeType {
+		return children
+	}
+	orgRange := val.FieldByIndex(orgRangeField.Index).Interface().(apc.OriginRange)
+	if orgRange.IsZero() {
+		return children
+	}
+	name := val.Type().Name()
+	if name == "" {
+		name = "struct"
+	}
+	rng := doc.rangeOf(orgRange)
+	return []DocumentSymbol{{
+		Name:           name,
+		Detail:         detail,
+		Kind:           SymbolKindStruct,
+		Range:          rng,
+		SelectionRange: rng,
+		Children:       children,
+	}}
+}
+
+// Returns a FoldingRange for each document symbol spanning multiple lines,
+// sorted by start line. Of those starting on the same line, only the longest is kept.
+func (doc *document) foldingRanges() []FoldingRange {
+	endLines := make(map[int]int)
+	var visit func(symbols []DocumentSymbol)
+	visit = func(symbols []DocumentSymbol) {
+		for _, sym := range symbols {
+			start, end := sym.Range.Start.Line, sym.Range.End.Line
+			if end > start && end > endLines[start] {
+				endLines[start] = end
+			}
+			visit(sym.Children)
+		}
+	}
+	visit(doc.symbols())
+
+	ranges := make([]FoldingRange, 0, len(endLines))
+	for start, end := range endLines {
+		ranges = append(ranges, FoldingRange{StartLine: start, EndLine: end})
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].StartLine < ranges[j].StartLine
+	})
+	return ranges
+}
+
+// Returns the semantic tokens of the document lexed by lexer, encoded relative to
+// each other as LSP requires. Tokens of types without an index in legendIndexes are
+// omitted, and tokens spanning multiple lines are split into one per line.
+// Lexing stops at the first error, so the tokens before it are still highlighted.
+// A rune is skipped wherever the lexer matches without consuming input.
+func (doc *document) semanticTokens(lexer apc.Parser[rune, apc.Token], legendIndexes map[apc.TokenType]int) SemanticTokens {
+	tokens := SemanticTokens{Data: []int{}}
+	ctx := apc.NewStringContext(doc.uri, strings.Join(doc.lines, "\n"))
+	reader := apc.NewParseReader[rune](ctx, lexer)
+	prev := Position{}
+
+	for {
+		if _, err := ctx.Peek(0, 1); errors.Is(err, apc.ErrEOF) {
+			break
+		}
+		before := ctx.GetCurOrigin()
+		tok, _, err := reader.Read()
+		if err != nil {
+			break
+		}
+		if _, err := ctx.Peek(0, 1); err == nil && ctx.GetCurOrigin() == before {
+			// The lexer matched without consuming input, and would match forever
+			ctx.Consume(1)
+			continue
+		}
+		index, ok := legendIndexes[tok.Type]
+		if !ok || tok.OriginRange.IsZero() {
+			continue
+		}
+
+		rng := doc.rangeOf(tok.OriginRange)
+		for line := rng.Start.Line; line <= rng.End.Line; line++ {
+			start, end := 0, utf16Len([]rune(doc.lines[line]))
+			if line == rng.Start.Line {
+				start = rng.Start.Character
+			}
+			if line == rng.End.Line {
+				end = rng.End.Character
+			}
+			if end <= start {
+				continue
+			}
+			deltaStart := start
+			if line == prev.Line {
+				deltaStart = start - prev.Character
+			}
+			tokens.Data = append(tokens.Data, line-prev.Line, deltaStart, end-start, index, 0)
+			prev = Position{Line: line, Character: start}
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,67 @@
+package apclsp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+func TestDocumentPosition(t *testing.T) {
+	doc := newDocument("doc", "a😀b\nxyz")
+	origin := func(line int, col int) apc.Origin {
+		return apc.Origin{Name: "doc", LineNum: line, ColNum: col}
+	}
+	// The emoji is a single rune but two UTF-16 code units
+	assert.Equal(t, Position{Line: 0, Character: 3}, doc.position(origin(1, 3)))
+	assert.Equal(t, Position{Line: 0, Character: 4}, doc.position(origin(1, 9)))
+	assert.Equal(t, Position{Line: 1, Character: 3}, doc.position(origin(5, 1)))
+	assert.Equal(t, Position{}, doc.position(apc.Origin{}))
+}
+
+func TestDocumentMultilineSemanticTokens(t *testing.T) {
+	lexer := apc.Skip(apc.CastToAny(apc.WhitespaceParser), apc.Any(
+		apc.Map(apc.Regex(`"[^"]*"`), func(node string) apc.Token {
+			return apc.Token{Type: "string", Value: node}
+		}),
+		apc.Map(apc.Regex("[a-z]+"), func(node string) apc.Token {
+			return apc.Token{Type: "ident", Value: node}
+		}),
+	))
+	doc := newDocument("doc", "x \"ab\ncd\" y ?")
+	tokens := doc.semanticTokens(lexer, map[apc.TokenType]int{"string": 0})
+	assert.Equal(t, []int{
+		0, 2, 3, 0, 0, // "ab
+		1, 0, 3, 0, 0, // cd"
+	}, tokens.Data)
+}
+
+func TestDocumentSymbolsSkipNestedApcValues(t *testing.T) {
+	type item struct {
+		OriginRange apc.OriginRange
+		Name        string
+		Token       apc.Token
+	}
+	orgRange := apc.OriginRange{
+		Start: apc.Origin{Name: "doc", LineNum: 1, ColNum: 1},
+		End:   apc.Origin{Name: "doc", LineNum: 1, ColNum: 4},
+	}
+	doc := newDocument("doc", "abc")
+	doc.result = []any{&item{OriginRange: orgRange, Name: "abc", Token: apc.Token{OriginRange: orgRange}}, nil, &item{}}
+	r := Range{End: Position{Character: 3}}
+	assert.Equal(t, []DocumentSymbol{
+		{Name: "item", Detail: "abc", Kind: SymbolKindStruct, Range: r, SelectionRange: r},
+	}, doc.symbols())
+}
+
+func TestDocumentSemanticTokensLexerWithoutProgress(t *testing.T) {
+	lexer := apc.Map(apc.Regex("[a-z]*"), func(node string) apc.Token {
+		return apc.Token{Type: "ident", Value: node}
+	})
+	doc := newDocument("doc", "ab ?c")
+	tokens := doc.semanticTokens(lexer, map[apc.TokenType]int{"ident": 0})
+	assert.Equal(t, []int{
+		0, 0, 2, 0, 0, // ab
+		0, 4, 1, 0, 0, // c
+	}, tokens.Data)
+}
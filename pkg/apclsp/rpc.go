@@ -0,0 +1,94 @@
+// Package apclsp provides a Language Server Protocol server for languages parsed
+// by apcgen-built parsers, offering diagnostics, document symbols, folding ranges
+// and semantic tokens.
+package apclsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// JSON-RPC error codes used by the server.
+const (
+	CodeParseError           = -32700
+	CodeInvalidRequest       = -32600
+	CodeMethodNotFound       = -32601
+	CodeInvalidParams        = -32602
+	CodeInternalError        = -32603
+	CodeServerNotInitialized = -32002
+)
+
+// The error of a JSON-RPC response.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// The error string.
+func (err *ResponseError) Error() string {
+	return fmt.Sprintf("jsonrpc error %v: %v", err.Code, err.Message)
+}
+
+// A JSON-RPC 2.0 request, notification or response. Requests and responses
+// have an ID; notifications do not.
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+// Reads a single message framed by LSP base protocol headers from r.
+// Returns io.EOF if r ends before the headers of a message begin.
+func readMessage(r *bufio.Reader) (*rpcMessage, error) {
+	headers, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(headers) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("cannot read message headers: %w", err)
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(headers.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header '%v'", headers.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("cannot read message body: %w", err)
+	}
+	msg := &rpcMessage{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, &ResponseError{Code: CodeParseError, Message: err.Error()}
+	}
+	return msg, nil
+}
+
+// Writes msg to w, framed by LSP base protocol headers.
+func writeMessage(w io.Writer, msg *rpcMessage) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %v\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// Returns val marshaled to JSON, or JSON null if val is nil.
+func marshalRaw(val any) (json.RawMessage, error) {
+	if val == nil {
+		return json.RawMessage("null"), nil
+	}
+	return json.Marshal(val)
+}
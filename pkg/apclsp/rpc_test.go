@@ -0,0 +1,48 @@
+package apclsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageFraming(t *testing.T) {
+	var buf bytes.Buffer
+	id := json.RawMessage("1")
+	assert.NoError(t, writeMessage(&buf, &rpcMessage{ID: &id, Method: "initialize", Params: json.RawMessage(`{}`)}))
+	assert.Equal(t, "Content-Length: 58\r\n\r\n"+`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`, buf.String())
+
+	r := bufio.NewReader(&buf)
+	msg, err := readMessage(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "initialize", msg.Method)
+	assert.Equal(t, "1", string(*msg.ID))
+	_, err = readMessage(r)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestMessageFramingErrors(t *testing.T) {
+	_, err := readMessage(bufio.NewReader(strings.NewReader("Content-Length: 5\r\n\r\n{nope")))
+	assert.Equal(t, CodeParseError, err.(*ResponseError).Code)
+
+	_, err = readMessage(bufio.NewReader(strings.NewReader("Content-Type: x\r\n\r\n{}")))
+	assert.ErrorContains(t, err, "invalid Content-Length")
+
+	_, err = readMessage(bufio.NewReader(strings.NewReader("Content-Length: 10\r\n\r\n{}")))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestServeInvalidJSON(t *testing.T) {
+	var out bytes.Buffer
+	in := "Content-Length: 5\r\n\r\n{nope"
+	assert.NoError(t, newTestServer().Serve(strings.NewReader(in), &out))
+	assert.Contains(t, out.String(), `"id":null`)
+	msg, err := readMessage(bufio.NewReader(&out))
+	assert.NoError(t, err)
+	assert.Equal(t, CodeParseError, msg.Error.Code)
+}
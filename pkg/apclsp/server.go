@@ -0,0 +1,325 @@
+package apclsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+// Returned by Serve if the client sent the exit notification before a shutdown request.
+var ErrExitWithoutShutdown = errors.New("exit notification received before shutdown request")
+
+type ServerOptionFunc func(opts *ServerOptions)
+
+type ServerOptions struct {
+	// The name of the server, also used as the source of diagnostics.
+	Name        string
+	SkipParsers []apc.Parser[rune, any]
+	ParseConfig apc.ParseConfig
+	// The lexer providing semantic tokens, and the LSP semantic token type
+	// (such as "keyword") of each TokenType to highlight.
+	Lexer      apc.Parser[rune, apc.Token]
+	TokenTypes map[apc.TokenType]string
+}
+
+func WithDefaultServerOptions(optFuncs ...ServerOptionFunc) *ServerOptions {
+	opts := &ServerOptions{
+		Name:        "apclsp",
+		SkipParsers: make([]apc.Parser[rune, any], 0),
+		ParseConfig: apc.DefaultParseConfig,
+		TokenTypes:  make(map[apc.TokenType]string),
+	}
+	for _, optFunc := range optFuncs {
+		optFunc(opts)
+	}
+	return opts
+}
+
+func WithNameOption(name string) ServerOptionFunc {
+	return func(opts *ServerOptions) {
+		opts.Name = name
+	}
+}
+
+func WithSkipParserOption(parser apc.Parser[rune, any]) ServerOptionFunc {
+	return func(opts *ServerOptions) {
+		opts.SkipParsers = append(opts.SkipParsers, parser)
+	}
+}
+
+func WithParseConfigOption(parseConfig apc.ParseConfig) ServerOptionFunc {
+	return func(opts *ServerOptions) {
+		opts.ParseConfig = parseConfig
+	}
+}
+
+// Provides semantic tokens by lexing documents with lexer, giving tokens of each
+// TokenType in tokenTypes the corresponding LSP semantic token type.
+func WithSemanticTokensOption(lexer apc.Parser[rune, apc.Token], tokenTypes map[apc.TokenType]string) ServerOptionFunc {
+	return func(opts *ServerOptions) {
+		opts.Lexer = lexer
+		for tokType, semanticType := range tokenTypes {
+			opts.TokenTypes[tokType] = semanticType
+		}
+	}
+}
+
+// A Language Server Protocol server for documents parsed by a single parser,
+// such as one built by apcgen.BuildParser. Documents are synced in full, and
+// are parsed each time they are opened or changed.
+type Server struct {
+	opts  *ServerOptions
+	parse func(uri string, text string) (any, error)
+	// The semantic token types of the legend, and the index in them of each TokenType.
+	legend        []string
+	legendIndexes map[apc.TokenType]int
+
+	writeLock   sync.Mutex
+	w           io.Writer
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// Returns a Server for documents parsed by parser, whose result is of the root type RT.
+// Document URIs are used as the origin names of parsed input.
+func NewServer[RT any](parser apc.Parser[rune, RT], opts *ServerOptions) *Server {
+	server := &Server{
+		opts: opts,
+		parse: func(uri string, text string) (any, error) {
+			ctx := apc.NewStringContext(uri, text)
+			for _, skipParser := range opts.SkipParsers {
+				ctx.AddSkipParser(skipParser)
+			}
+			return apc.Parse[rune](ctx, parser, opts.ParseConfig)
+		},
+		legendIndexes: make(map[apc.TokenType]int),
+		docs:          make(map[string]*document),
+	}
+
+	for _, semanticType := range opts.TokenTypes {
+		server.legend = append(server.legend, semanticType)
+	}
+	sort.Strings(server.legend)
+	server.legend = dedupSorted(server.legend)
+	for tokType, semanticType := range opts.TokenTypes {
+		server.legendIndexes[tokType] = sort.SearchStrings(server.legend, semanticType)
+	}
+	return server
+}
+
+func dedupSorted(vals []string) []string {
+	result := vals[:0]
+	for i, val := range vals {
+		if i == 0 || val != vals[i-1] {
+			result = append(result, val)
+		}
+	}
+	return result
+}
+
+// Serves the client connected by os.Stdin and os.Stdout. See Serve.
+func (server *Server) ServeStdio() error {
+	return server.Serve(os.Stdin, os.Stdout)
+}
+
+// Serves a single client, reading its messages from r and writing to w, until
+// it sends the exit notification or r ends. Returns ErrExitWithoutShutdown if the
+// client exits without first requesting shutdown.
+func (server *Server) Serve(r io.Reader, w io.Writer) error {
+	server.w = w
+	reader := bufio.NewReader(r)
+	for {
+		msg, err := readMessage(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var respErr *ResponseError
+		if errors.As(err, &respErr) {
+			// The message was framed but not valid JSON, so its ID is unknown
+			if err := server.write(&rpcMessage{ID: nullID(), Error: respErr}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !server.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+		if msg.ID == nil {
+			if err := server.handleNotification(msg); err != nil {
+				return err
+			}
+			continue
+		}
+		result, err := server.handleRequest(msg)
+		resp := &rpcMessage{ID: msg.ID}
+		if err != nil {
+			if !errors.As(err, &respErr) {
+				respErr = &ResponseError{Code: CodeInternalError, Message: err.Error()}
+			}
+			resp.Error = respErr
+		} else if resp.Result, err = marshalRaw(result); err != nil {
+			return err
+		}
+		if err := server.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func nullID() *json.RawMessage {
+	id := json.RawMessage("null")
+	return &id
+}
+
+func (server *Server) write(msg *rpcMessage) error {
+	server.writeLock.Lock()
+	defer server.writeLock.Unlock()
+	return writeMessage(server.w, msg)
+}
+
+func (server *Server) notify(method string, params any) error {
+	raw, err := marshalRaw(params)
+	if err != nil {
+		return err
+	}
+	return server.write(&rpcMessage{Method: method, Params: raw})
+}
+
+func unmarshalParams(msg *rpcMessage, params any) error {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return &ResponseError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (server *Server) handleRequest(msg *rpcMessage) (any, error) {
+	if msg.Method != "initialize" && !server.initialized {
+		return nil, &ResponseError{Code: CodeServerNotInitialized, Message: "server not initialized"}
+	}
+	if server.shutdown {
+		return nil, &ResponseError{Code: CodeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		server.initialized = true
+		return server.initializeResult(), nil
+	case "shutdown":
+		server.shutdown = true
+		return nil, nil
+	case "textDocument/documentSymbol":
+		doc, err := server.requestDocument(msg)
+		if err != nil {
+			return nil, err
+		}
+		return doc.symbols(), nil
+	case "textDocument/foldingRange":
+		doc, err := server.requestDocument(msg)
+		if err != nil {
+			return nil, err
+		}
+		return doc.foldingRanges(), nil
+	case "textDocument/semanticTokens/full":
+		doc, err := server.requestDocument(msg)
+		if err != nil {
+			return nil, err
+		}
+		if server.opts.Lexer == nil {
+			return nil, &ResponseError{Code: CodeMethodNotFound, Message: "semantic tokens are not provided"}
+		}
+		return doc.semanticTokens(server.opts.Lexer, server.legendIndexes), nil
+	default:
+		return nil, &ResponseError{Code: CodeMethodNotFound, Message: fmt.Sprintf("method '%v' not found", msg.Method)}
+	}
+}
+
+func (server *Server) initializeResult() InitializeResult {
+	result := InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:       TextDocumentSyncKindFull,
+			DocumentSymbolProvider: true,
+			FoldingRangeProvider:   true,
+		},
+		ServerInfo: ServerInfo{Name: server.opts.Name},
+	}
+	if server.opts.Lexer != nil {
+		result.Capabilities.SemanticTokensProvider = &SemanticTokensOptions{
+			Legend: SemanticTokensLegend{
+				TokenTypes:     append([]string{}, server.legend...),
+				TokenModifiers: []string{},
+			},
+			Full: true,
+		}
+	}
+	return result
+}
+
+// Returns the open document named by the params of a request.
+func (server *Server) requestDocument(msg *rpcMessage) (*document, error) {
+	var params TextDocumentParams
+	if err := unmarshalParams(msg, &params); err != nil {
+		return nil, err
+	}
+	doc, ok := server.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: fmt.Sprintf("document '%v' is not open", params.TextDocument.URI)}
+	}
+	return doc, nil
+}
+
+// Handles a notification. Notifications have no response, so invalid ones are ignored.
+// Returns an error only if writing to the client fails.
+func (server *Server) handleNotification(msg *rpcMessage) error {
+	if !server.initialized {
+		return nil
+	}
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if unmarshalParams(msg, &params) == nil {
+			return server.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if unmarshalParams(msg, &params) == nil && len(params.ContentChanges) > 0 {
+			// With full document sync, the last change is the full text
+			return server.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if unmarshalParams(msg, &params) == nil {
+			delete(server.docs, params.TextDocument.URI)
+			return server.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+				URI:         params.TextDocument.URI,
+				Diagnostics: []Diagnostic{},
+			})
+		}
+	}
+	return nil
+}
+
+// Parses the new text of a document and publishes its diagnostics.
+func (server *Server) update(uri string, text string) error {
+	doc := newDocument(uri, text)
+	doc.result, doc.err = server.parse(uri, text)
+	server.docs[uri] = doc
+	return server.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: doc.diagnostics(server.opts.Name),
+	})
+}
@@ -0,0 +1,172 @@
+package apclsp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+	"github.com/tpillow/apc/pkg/apcgen"
+)
+
+const testURI = "file:///test.conf"
+
+type lspTestEntry struct {
+	OriginRange apc.OriginRange
+	Key         string `apc:"$regex('[a-z]+') '='"`
+	Value       string `apc:"$regex('[0-9]+') ';'"`
+}
+
+type lspTestBlock struct {
+	OriginRange apc.OriginRange
+	Name        string          `apc:"'block' $regex('[a-z]+') '{'"`
+	Entries     []*lspTestEntry `apc:"$.* '}'"`
+}
+
+type lspTestFile struct {
+	Blocks []*lspTestBlock `apc:"$.*"`
+}
+
+func newTestServer() *Server {
+	parser := apcgen.BuildParser[*lspTestFile](apcgen.WithDefaultBuildOptions(
+		apcgen.WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)),
+	))
+	lexer := apcgen.BuildSimpleLexer(apcgen.SimpleLexerBuildOptions{
+		IdentifierTokenType:         "ident",
+		IdentifierParser:            apc.Regex("[a-z]+"),
+		SpecialIdentifierTokenTypes: []apc.TokenType{"block"},
+		ExactMatchTokenTypes:        []apc.TokenType{"{", "}", "=", ";"},
+		ProvidedParsers: []apc.Parser[rune, apc.Token]{
+			apc.Map(apc.Regex("[0-9]+"), func(node string) apc.Token {
+				return apc.Token{Type: "number", Value: node}
+			}),
+		},
+		SkipParsers: []apc.Parser[rune, any]{apc.CastToAny(apc.WhitespaceParser)},
+	})
+	return NewServer(parser, WithDefaultServerOptions(
+		WithNameOption("testlsp"),
+		WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)),
+		WithSemanticTokensOption(lexer, map[apc.TokenType]string{
+			"block":  "keyword",
+			"ident":  "variable",
+			"number": "number",
+		}),
+	))
+}
+
+func newTestClient(t *testing.T) (*Client, context.Context) {
+	client := NewClient(newTestServer())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	var result InitializeResult
+	assert.NoError(t, client.Call("initialize", map[string]any{}, &result))
+	assert.Equal(t, "testlsp", result.ServerInfo.Name)
+	assert.Equal(t, []string{"keyword", "number", "variable"}, result.Capabilities.SemanticTokensProvider.Legend.TokenTypes)
+	assert.NoError(t, client.Notify("initialized", map[string]any{}))
+	return client, ctx
+}
+
+func openTestDocument(t *testing.T, client *Client, ctx context.Context, text string) PublishDiagnosticsParams {
+	assert.NoError(t, client.Notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, LanguageID: "conf", Version: 1, Text: text},
+	}))
+	var diags PublishDiagnosticsParams
+	assert.NoError(t, client.WaitNotification(ctx, "textDocument/publishDiagnostics", &diags))
+	return diags
+}
+
+func TestServerFeatures(t *testing.T) {
+	client, ctx := newTestClient(t)
+	diags := openTestDocument(t, client, ctx, "block a {\n  x = 1;\n  y = 22;\n}\nblock b { z = 3; }\n")
+	assert.Equal(t, PublishDiagnosticsParams{URI: testURI, Diagnostics: []Diagnostic{}}, diags)
+
+	docParams := TextDocumentParams{TextDocument: TextDocumentIdentifier{URI: testURI}}
+	var symbols []DocumentSymbol
+	assert.NoError(t, client.Call("textDocument/documentSymbol", docParams, &symbols))
+	rng := func(startLine, startChar, endLine, endChar int) Range {
+		return Range{Start: Position{startLine, startChar}, End: Position{endLine, endChar}}
+	}
+	symbol := func(name string, detail string, r Range, children ...DocumentSymbol) DocumentSymbol {
+		return DocumentSymbol{Name: name, Detail: detail, Kind: SymbolKindStruct, Range: r, SelectionRange: r, Children: children}
+	}
+	assert.Equal(t, []DocumentSymbol{
+		symbol("lspTestBlock", "a", rng(0, 0, 3, 1),
+			symbol("lspTestEntry", "x", rng(1, 2, 1, 8)),
+			symbol("lspTestEntry", "y", rng(2, 2, 2, 9)),
+		),
+		symbol("lspTestBlock", "b", rng(4, 0, 4, 18),
+			symbol("lspTestEntry", "z", rng(4, 10, 4, 16)),
+		),
+	}, symbols)
+
+	var folds []FoldingRange
+	assert.NoError(t, client.Call("textDocument/foldingRange", docParams, &folds))
+	assert.Equal(t, []FoldingRange{{StartLine: 0, EndLine: 3}}, folds)
+
+	var tokens SemanticTokens
+	assert.NoError(t, client.Call("textDocument/semanticTokens/full", docParams, &tokens))
+	assert.Equal(t, []int{
+		0, 0, 5, 0, 0, // block
+		0, 6, 1, 2, 0, // a
+		1, 2, 1, 2, 0, // x
+		0, 4, 1, 1, 0, // 1
+		1, 2, 1, 2, 0, // y
+		0, 4, 2, 1, 0, // 22
+		2, 0, 5, 0, 0, // block
+		0, 6, 1, 2, 0, // b
+		0, 4, 1, 2, 0, // z
+		0, 4, 1, 1, 0, // 3
+	}, tokens.Data)
+
+	assert.NoError(t, client.Close())
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	client, ctx := newTestClient(t)
+	openTestDocument(t, client, ctx, "block a { }")
+
+	assert.NoError(t, client.Notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: testURI},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "block a {\n  x = ;\n}"}},
+	}))
+	var diags PublishDiagnosticsParams
+	assert.NoError(t, client.WaitNotification(ctx, "textDocument/publishDiagnostics", &diags))
+	assert.Len(t, diags.Diagnostics, 1)
+	diag := diags.Diagnostics[0]
+	assert.Equal(t, DiagnosticSeverityError, diag.Severity)
+	assert.Equal(t, "testlsp", diag.Source)
+	assert.Equal(t, Range{Start: Position{1, 6}, End: Position{1, 6}}, diag.Range)
+	assert.Contains(t, diag.Message, "but got ;")
+	assert.NotEmpty(t, diag.RelatedInformation)
+
+	// Features of a document that does not parse are empty
+	var symbols []DocumentSymbol
+	docParams := TextDocumentParams{TextDocument: TextDocumentIdentifier{URI: testURI}}
+	assert.NoError(t, client.Call("textDocument/documentSymbol", docParams, &symbols))
+	assert.Empty(t, symbols)
+
+	assert.NoError(t, client.Notify("textDocument/didClose", DidCloseTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+	}))
+	assert.NoError(t, client.WaitNotification(ctx, "textDocument/publishDiagnostics", &diags))
+	assert.Empty(t, diags.Diagnostics)
+	assert.NoError(t, client.Close())
+}
+
+func TestServerErrors(t *testing.T) {
+	client := NewClient(newTestServer())
+	err := client.Call("textDocument/documentSymbol", TextDocumentParams{}, nil)
+	assert.Equal(t, &ResponseError{Code: CodeServerNotInitialized, Message: "server not initialized"}, err)
+
+	assert.NoError(t, client.Call("initialize", map[string]any{}, nil))
+	err = client.Call("textDocument/hover", map[string]any{}, nil)
+	assert.Equal(t, CodeMethodNotFound, err.(*ResponseError).Code)
+	err = client.Call("textDocument/foldingRange", TextDocumentParams{TextDocument: TextDocumentIdentifier{URI: "file:///missing"}}, nil)
+	assert.Equal(t, CodeInvalidParams, err.(*ResponseError).Code)
+
+	assert.NoError(t, client.Notify("exit", nil))
+	assert.ErrorIs(t, <-client.served, ErrExitWithoutShutdown)
+	assert.ErrorIs(t, client.Call("shutdown", nil, nil), ErrClientClosed)
+}
@@ -0,0 +1,151 @@
+// Package apctest provides helpers for testing parsers built with apc and apcgen,
+// such as golden-file tests over a directory of inputs.
+package apctest
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+// Namespaced so that it does not clash with an -update flag of the package under test.
+var update = flag.Bool("apctest.update", false, "update the golden files of apctest.RunGolden with the current results")
+
+// Returns a new Context[CT] over input, with the given origin name.
+type NewContextFunc[CT any] func(originName string, input string) apc.Context[CT]
+
+type TestOptionFunc[CT any] func(opts *TestOptions[CT])
+
+type TestOptions[CT any] struct {
+	NewContext  NewContextFunc[CT]
+	SkipParsers []apc.Parser[CT, any]
+	ParseConfig apc.ParseConfig
+	// The origin name of inputs given to Parse.
+	OriginName string
+	// The file extensions of input and golden files used by RunGolden.
+	InputExt  string
+	GoldenExt string
+	// If true, RunGolden writes the golden files instead of comparing them,
+	// as with the -apctest.update flag.
+	Update bool
+}
+
+// Returns TestOptions that create contexts with newContext, parse to EOF,
+// and read golden tests from ".input" and ".golden" files.
+func WithDefaultTestOptions[CT any](newContext NewContextFunc[CT], optFuncs ...TestOptionFunc[CT]) *TestOptions[CT] {
+	opts := &TestOptions[CT]{
+		NewContext:  newContext,
+		SkipParsers: make([]apc.Parser[CT, any], 0),
+		ParseConfig: apc.DefaultParseConfig,
+		OriginName:  "input",
+		InputExt:    ".input",
+		GoldenExt:   ".golden",
+	}
+	for _, optFunc := range optFuncs {
+		optFunc(opts)
+	}
+	return opts
+}
+
+// A NewContextFunc returning an apc.NewStringContext.
+func StringContext(originName string, input string) apc.Context[rune] {
+	return apc.NewStringContext(originName, input)
+}
+
+func WithSkipParserOption[CT any](parser apc.Parser[CT, any]) TestOptionFunc[CT] {
+	return func(opts *TestOptions[CT]) {
+		opts.SkipParsers = append(opts.SkipParsers, parser)
+	}
+}
+
+func WithParseConfigOption[CT any](parseConfig apc.ParseConfig) TestOptionFunc[CT] {
+	return func(opts *TestOptions[CT]) {
+		opts.ParseConfig = parseConfig
+	}
+}
+
+func WithOriginNameOption[CT any](originName string) TestOptionFunc[CT] {
+	return func(opts *TestOptions[CT]) {
+		opts.OriginName = originName
+	}
+}
+
+func WithFileExtOption[CT any](inputExt string, goldenExt string) TestOptionFunc[CT] {
+	return func(opts *TestOptions[CT]) {
+		opts.InputExt = inputExt
+		opts.GoldenExt = goldenExt
+	}
+}
+
+func WithUpdateOption[CT any](update bool) TestOptionFunc[CT] {
+	return func(opts *TestOptions[CT]) {
+		opts.Update = update
+	}
+}
+
+// Parses input with parser, using a context created and configured by opts.
+func Parse[CT, T any](input string, parser apc.Parser[CT, T], opts *TestOptions[CT]) (T, error) {
+	return parseNamed(opts.OriginName, input, parser, opts)
+}
+
+func parseNamed[CT, T any](originName string, input string, parser apc.Parser[CT, T], opts *TestOptions[CT]) (T, error) {
+	ctx := opts.NewContext(originName, input)
+	for _, skipParser := range opts.SkipParsers {
+		ctx.AddSkipParser(skipParser)
+	}
+	return apc.Parse(ctx, parser, opts.ParseConfig)
+}
+
+// Runs a subtest for each file in dir with the input file extension of opts, in
+// name order. Each parses the file with parser and compares the Dump of its result
+// and error to the file of the same name with the golden file extension.
+//
+// Running tests with the -apctest.update flag (or with opts.Update set) writes the
+// current Dump to the golden files instead.
+// Origins in dumps are named by the input file name, without its directory.
+func RunGolden[CT, T any](t *testing.T, dir string, parser apc.Parser[CT, T], opts *TestOptions[CT]) {
+	t.Helper()
+	inputPaths, err := filepath.Glob(filepath.Join(dir, "*"+opts.InputExt))
+	if err != nil {
+		t.Fatalf("cannot list golden inputs: %v", err)
+	}
+	if len(inputPaths) == 0 {
+		t.Fatalf("no golden inputs matching '*%v' found in '%v'", opts.InputExt, dir)
+	}
+	sort.Strings(inputPaths)
+
+	for _, inputPath := range inputPaths {
+		inputPath := inputPath
+		fileName := filepath.Base(inputPath)
+		goldenPath := strings.TrimSuffix(inputPath, opts.InputExt) + opts.GoldenExt
+		t.Run(strings.TrimSuffix(fileName, opts.InputExt), func(t *testing.T) {
+			input, err := os.ReadFile(inputPath)
+			if err != nil {
+				t.Fatalf("cannot read golden input: %v", err)
+			}
+			node, err := parseNamed(fileName, string(input), parser, opts)
+			dump := Dump(node, err)
+
+			if opts.Update || *update {
+				if err := os.WriteFile(goldenPath, []byte(dump), 0o644); err != nil {
+					t.Fatalf("cannot update golden file: %v", err)
+				}
+				return
+			}
+			golden, err := os.ReadFile(goldenPath)
+			if errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("golden file '%v' does not exist; run the test with -apctest.update to create it", goldenPath)
+			} else if err != nil {
+				t.Fatalf("cannot read golden file: %v", err)
+			}
+			assert.Equal(t, string(golden), dump, "result of '%v' differs from '%v'", inputPath, goldenPath)
+		})
+	}
+}
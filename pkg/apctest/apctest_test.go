@@ -0,0 +1,65 @@
+package apctest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+	"github.com/tpillow/apc/pkg/apcgen"
+)
+
+type testEntry struct {
+	OriginRange apc.OriginRange
+	Key         string `apc:"$regex('[a-z]+') '='"`
+	Value       int64  `apc:"$IntParser"`
+}
+
+type testConfig struct {
+	Entries []*testEntry `apc:"$.*"`
+}
+
+var testParser = apcgen.BuildParser[*testConfig](apcgen.WithDefaultBuildOptions(
+	apcgen.WithParserOption("IntParser", apc.CastToAny(apc.IntParser)),
+))
+
+func testOptions() *TestOptions[rune] {
+	return WithDefaultTestOptions(StringContext, WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)))
+}
+
+func TestRunGolden(t *testing.T) {
+	RunGolden(t, "testdata/golden", testParser, testOptions())
+}
+
+func TestRunGoldenUpdate(t *testing.T) {
+	// The flag of RunGolden does not clash with an -update flag of its users.
+	assert.NotNil(t, flag.Lookup("apctest.update"))
+	assert.Nil(t, flag.Lookup("update"))
+
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "entries.input")
+	goldenPath := filepath.Join(dir, "entries.golden")
+	assert.NoError(t, os.WriteFile(inputPath, []byte("a = 1"), 0o644))
+	RunGolden(t, dir, testParser, WithDefaultTestOptions(StringContext,
+		WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)), WithUpdateOption[rune](true)))
+
+	golden, err := os.ReadFile(goldenPath)
+	assert.NoError(t, err)
+	expected, _ := Parse("a = 1", testParser, WithDefaultTestOptions(StringContext,
+		WithSkipParserOption(apc.CastToAny(apc.WhitespaceParser)), WithOriginNameOption[rune]("entries.input")))
+	assert.Equal(t, Dump(expected, nil), string(golden))
+	RunGolden(t, dir, testParser, testOptions())
+}
+
+func TestParse(t *testing.T) {
+	node, err := Parse("x = 5", testParser, testOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, "input:1:1-1:6", node.Entries[0].OriginRange.String())
+
+	_, err = Parse("x = 5", testParser, WithDefaultTestOptions(StringContext, WithOriginNameOption[rune]("custom")))
+	AssertFailsAt(t, err, 1, 2, "testEntry", "=")
+	origin, _ := ErrorOrigin(err)
+	assert.Equal(t, "custom", origin.Name)
+}
@@ -0,0 +1,86 @@
+package apctest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+// Returns the Origin of the outermost parse error in the chain of wrapped errors of err.
+func ErrorOrigin(err error) (apc.Origin, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch perr := err.(type) {
+		case *apc.ParseError:
+			return perr.Origin, true
+		case *apc.ParseErrorConsumed:
+			return perr.Origin, true
+		}
+	}
+	return apc.Origin{}, false
+}
+
+// Returns the set of things expected by each parse error in the chain of wrapped
+// errors of err: their Expected field, or if empty, the text following "expected"
+// and preceding "but got" in their messages. Returns nil if err has no such parse errors.
+func ExpectedSet(err error) []string {
+	var expected []string
+	seen := make(map[string]bool)
+	for ; err != nil; err = errors.Unwrap(err) {
+		var message, exp string
+		switch perr := err.(type) {
+		case *apc.ParseError:
+			message, exp = perr.Message, perr.Expected
+		case *apc.ParseErrorConsumed:
+			message, exp = perr.Message, perr.Expected
+		default:
+			continue
+		}
+		ok := exp != ""
+		if !ok {
+			exp, ok = expectedOf(message)
+		}
+		if ok && !seen[exp] {
+			seen[exp] = true
+			expected = append(expected, exp)
+		}
+	}
+	return expected
+}
+
+func expectedOf(message string) (string, bool) {
+	if !strings.HasPrefix(message, "expected ") {
+		return "", false
+	}
+	rest := strings.TrimPrefix(message, "expected ")
+	if idx := strings.LastIndex(rest, " but got "); idx != -1 {
+		rest = rest[:idx]
+	}
+	return rest, true
+}
+
+// Asserts that err is a parse error at the given line and column whose ExpectedSet
+// contains exactly the elements of expected, in any order. If no expected elements
+// are given, only the location is asserted.
+func AssertFailsAt(t assert.TestingT, err error, lineNum int, colNum int, expected ...string) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if err == nil {
+		return assert.Fail(t, fmt.Sprintf("expected parse to fail at %v:%v, but it succeeded", lineNum, colNum))
+	}
+	origin, ok := ErrorOrigin(err)
+	if !ok {
+		return assert.Fail(t, fmt.Sprintf("expected a parse error at %v:%v, but got: %v", lineNum, colNum, err))
+	}
+	if origin.LineNum != lineNum || origin.ColNum != colNum {
+		return assert.Fail(t, fmt.Sprintf("expected parse to fail at %v:%v, but it failed at %v:%v", lineNum, colNum,
+			origin.LineNum, origin.ColNum), strings.TrimSuffix(DumpError(err), "\n"))
+	}
+	if len(expected) == 0 {
+		return true
+	}
+	return assert.ElementsMatch(t, expected, ExpectedSet(err), "expected set of parse error at %v:%v", lineNum, colNum)
+}
@@ -0,0 +1,49 @@
+package apctest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+// Records failures instead of failing the test.
+type recordingT struct {
+	failures []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertFailsAt(t *testing.T) {
+	_, err := Parse("a = 1\nb = ;", testParser, testOptions())
+	assert.True(t, AssertFailsAt(t, err, 2, 5))
+	assert.True(t, AssertFailsAt(t, err, 2, 5, "integer", "testEntry"))
+
+	rt := &recordingT{}
+	assert.False(t, AssertFailsAt(rt, err, 1, 5))
+	assert.False(t, AssertFailsAt(rt, err, 2, 5, "integer"))
+	assert.False(t, AssertFailsAt(rt, nil, 2, 5))
+	assert.False(t, AssertFailsAt(rt, fmt.Errorf("oops"), 2, 5))
+	assert.Len(t, rt.failures, 4)
+	assert.Contains(t, rt.failures[0], "expected parse to fail at 1:5, but it failed at 2:5")
+	assert.Contains(t, rt.failures[2], "but it succeeded")
+}
+
+func TestExpectedSet(t *testing.T) {
+	err := &apc.ParseErrorConsumed{
+		Message: "expected a but got b",
+		Err: fmt.Errorf("wrapped: %w", &apc.ParseError{
+			Message: "expected c or d but got e",
+			Err:     &apc.ParseError{Message: "custom message"},
+		}),
+	}
+	assert.Equal(t, []string{"a", "c or d"}, ExpectedSet(err))
+	assert.Nil(t, ExpectedSet(fmt.Errorf("oops")))
+
+	origin, ok := ErrorOrigin(fmt.Errorf("wrapped: %w", &apc.ParseError{Origin: apc.Origin{Name: "x", LineNum: 3, ColNum: 4}}))
+	assert.True(t, ok)
+	assert.Equal(t, "x:3:4", origin.String())
+}
@@ -0,0 +1,192 @@
+package apctest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+// Returns a canonical, human-readable dump of a parse result and error.
+//
+// If err is nil, the dump is "result: " followed by the result, with the exported
+// fields of structs one per line and map entries sorted. Otherwise, it is each
+// error of the chain of wrapped errors on its own line, including its Origin.
+func Dump(result any, err error) string {
+	if err != nil {
+		return DumpError(err)
+	}
+	return "result: " + DumpValue(result) + "\n"
+}
+
+// Returns a canonical dump of err: each error in its chain of wrapped errors on
+// its own line, with parse errors including their Origin, or OriginRange if known.
+func DumpError(err error) string {
+	var sb strings.Builder
+	for prefix := "error"; err != nil; prefix = "caused by" {
+		switch perr := err.(type) {
+		case *apc.ParseError:
+			fmt.Fprintf(&sb, "%v: parse error at %v: %v\n", prefix, errLocation(perr.Origin, perr.OriginRange), perr.Message)
+			err = perr.Err
+		case *apc.ParseErrorConsumed:
+			fmt.Fprintf(&sb, "%v: parse error (cannot backtrack) at %v: %v\n", prefix, errLocation(perr.Origin, perr.OriginRange), perr.Message)
+			err = perr.Err
+		default:
+			// The message of other errors usually includes those they wrap
+			fmt.Fprintf(&sb, "%v: %v\n", prefix, err)
+			err = nil
+		}
+	}
+	return sb.String()
+}
+
+func errLocation(origin apc.Origin, orgRange apc.OriginRange) string {
+	if orgRange.IsZero() {
+		return origin.String()
+	}
+	return orgRange.String()
+}
+
+// Returns a canonical dump of val. Pointers are prefixed with '&', structs list
+// their exported fields one per line, map entries are sorted, and Origins and
+// OriginRanges are written in the same form as in error messages.
+func DumpValue(val any) string {
+	d := &dumper{
+		visiting: make(map[uintptr]bool),
+	}
+	d.dump(reflect.ValueOf(val), 0)
+	return d.sb.String()
+}
+
+type dumper struct {
+	sb       strings.Builder
+	visiting map[uintptr]bool
+}
+
+var (
+	originType      = reflect.TypeOf(apc.Origin{})
+	originRangeType = reflect.TypeOf(apc.OriginRange{})
+)
+
+func (d *dumper) newline(depth int) {
+	d.sb.WriteByte('\n')
+	d.sb.WriteString(strings.Repeat("\t", depth))
+}
+
+func (d *dumper) dump(val reflect.Value, depth int) {
+	if !val.IsValid() {
+		d.sb.WriteString("nil")
+		return
+	}
+
+	switch val.Type() {
+	case originType, originRangeType:
+		d.sb.WriteString(fmt.Sprint(val.Interface()))
+		return
+	}
+	// MaybeValue has no exported fields
+	if val.CanInterface() {
+		if maybe, ok := val.Interface().(interface{ IsNil() bool }); ok && val.Kind() == reflect.Struct {
+			if maybe.IsNil() {
+				d.sb.WriteString("Maybe(nil)")
+				return
+			}
+			d.sb.WriteString("Maybe(")
+			d.dump(val.MethodByName("Value").Call(nil)[0], depth)
+			d.sb.WriteString(")")
+			return
+		}
+	}
+
+	switch val.Kind() {
+	case reflect.Pointer:
+		if val.IsNil() {
+			d.sb.WriteString("nil")
+			return
+		}
+		if d.visiting[val.Pointer()] {
+			d.sb.WriteString("<cycle>")
+			return
+		}
+		d.visiting[val.Pointer()] = true
+		defer delete(d.visiting, val.Pointer())
+		d.sb.WriteByte('&')
+		d.dump(val.Elem(), depth)
+	case reflect.Interface:
+		if val.IsNil() {
+			d.sb.WriteString("nil")
+			return
+		}
+		d.dump(val.Elem(), depth)
+	case reflect.Struct:
+		d.sb.WriteString(val.Type().String())
+		d.sb.WriteByte('{')
+		wroteField := false
+		for i := 0; i < val.NumField(); i++ {
+			field := val.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			d.newline(depth + 1)
+			d.sb.WriteString(field.Name)
+			d.sb.WriteString(": ")
+			d.dump(val.Field(i), depth+1)
+			wroteField = true
+		}
+		if wroteField {
+			d.newline(depth)
+		}
+		d.sb.WriteByte('}')
+	case reflect.Slice, reflect.Array:
+		if val.Len() == 0 {
+			d.sb.WriteString("[]")
+			return
+		}
+		d.sb.WriteByte('[')
+		for i := 0; i < val.Len(); i++ {
+			d.newline(depth + 1)
+			d.dump(val.Index(i), depth+1)
+		}
+		d.newline(depth)
+		d.sb.WriteByte(']')
+	case reflect.Map:
+		if val.Len() == 0 {
+			d.sb.WriteString("{}")
+			return
+		}
+		type entry struct {
+			key string
+			val reflect.Value
+		}
+		entries := make([]entry, 0, val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			entries = append(entries, entry{key: DumpValue(iter.Key().Interface()), val: iter.Value()})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].key < entries[j].key
+		})
+		d.sb.WriteByte('{')
+		for _, entry := range entries {
+			d.newline(depth + 1)
+			d.sb.WriteString(entry.key)
+			d.sb.WriteString(": ")
+			d.dump(entry.val, depth+1)
+		}
+		d.newline(depth)
+		d.sb.WriteByte('}')
+	case reflect.String:
+		d.sb.WriteString(strconv.Quote(val.String()))
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		d.sb.WriteString(val.Type().String())
+	default:
+		if val.CanInterface() {
+			d.sb.WriteString(fmt.Sprint(val.Interface()))
+			return
+		}
+		d.sb.WriteString(fmt.Sprint(val))
+	}
+}
@@ -0,0 +1,69 @@
+package apctest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tpillow/apc/pkg/apc"
+)
+
+func TestDumpValue(t *testing.T) {
+	type node struct {
+		Name     string
+		Children []*node
+		Attrs    map[string]any
+		Maybe    apc.MaybeValue[int]
+		Origin   apc.Origin
+		hidden   int
+	}
+	val := &node{
+		Name:     "root",
+		Children: []*node{{Name: "child", Maybe: apc.NewNilMaybeValue[int]()}},
+		Attrs:    map[string]any{"b": true, "a": 1.5},
+		Maybe:    apc.NewMaybeValue(3),
+		Origin:   apc.Origin{Name: "f", LineNum: 1, ColNum: 2},
+		hidden:   7,
+	}
+	val.Children[0].Children = []*node{val}
+
+	assert.Equal(t, `&apctest.node{
+	Name: "root"
+	Children: [
+		&apctest.node{
+			Name: "child"
+			Children: [
+				<cycle>
+			]
+			Attrs: {}
+			Maybe: Maybe(nil)
+			Origin: :0:0
+		}
+	]
+	Attrs: {
+		"a": 1.5
+		"b": true
+	}
+	Maybe: Maybe(3)
+	Origin: f:1:2
+}`, DumpValue(val))
+	assert.Equal(t, "nil", DumpValue(nil))
+	assert.Equal(t, `[]`, DumpValue([]string{}))
+}
+
+func TestDumpError(t *testing.T) {
+	err := &apc.ParseErrorConsumed{
+		Message: "expected a but got b",
+		Origin:  apc.Origin{Name: "f", LineNum: 1, ColNum: 2},
+		Err: &apc.ParseError{
+			Message:     "expected c but got d",
+			OriginRange: apc.OriginRange{Start: apc.Origin{Name: "f", LineNum: 1, ColNum: 2}, End: apc.Origin{Name: "f", LineNum: 1, ColNum: 4}},
+			Err:         fmt.Errorf("bad input"),
+		},
+	}
+	assert.Equal(t, `error: parse error (cannot backtrack) at f:1:2: expected a but got b
+caused by: parse error at f:1:2-1:4: expected c but got d
+caused by: bad input
+`, Dump(nil, err))
+	assert.Equal(t, "result: \"ok\"\n", Dump("ok", nil))
+}